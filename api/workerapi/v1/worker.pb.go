@@ -25,6 +25,60 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// LogStream is an enum of output streams that log lines can be written to.
+type LogStream int32
+
+const (
+	// LogStreamUnspecified means the output stream is unknown, such as when
+	// the logs were read from Kubernetes, where the streams are combined.
+	LogStreamUnspecified LogStream = 0
+	// LogStreamStdout means the log line was written to standard output.
+	LogStreamStdout LogStream = 1
+	// LogStreamStderr means the log line was written to standard error.
+	LogStreamStderr LogStream = 2
+)
+
+// Enum value maps for LogStream.
+var (
+	LogStream_name = map[int32]string{
+		0: "LOG_STREAM_UNSPECIFIED",
+		1: "LOG_STREAM_STDOUT",
+		2: "LOG_STREAM_STDERR",
+	}
+	LogStream_value = map[string]int32{
+		"LOG_STREAM_UNSPECIFIED": 0,
+		"LOG_STREAM_STDOUT":      1,
+		"LOG_STREAM_STDERR":      2,
+	}
+)
+
+func (x LogStream) Enum() *LogStream {
+	p := new(LogStream)
+	*p = x
+	return p
+}
+
+func (x LogStream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogStream) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_workerapi_v1_worker_proto_enumTypes[0].Descriptor()
+}
+
+func (LogStream) Type() protoreflect.EnumType {
+	return &file_api_workerapi_v1_worker_proto_enumTypes[0]
+}
+
+func (x LogStream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogStream.Descriptor instead.
+func (LogStream) EnumDescriptor() ([]byte, []int) {
+	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{0}
+}
+
 // Status is an enum of different statuses for the build steps.
 type Status int32
 
@@ -52,6 +106,9 @@ const (
 	StatusFailed Status = 6
 	// StatusCancelled means this build was cancelled.
 	StatusCancelled Status = 7
+	// StatusWarning means this build step has failed, but was allowed to fail,
+	// so it does not fail the build.
+	StatusWarning Status = 8
 )
 
 // Enum value maps for Status.
@@ -65,6 +122,7 @@ var (
 		5: "STATUS_SUCCESS",
 		6: "STATUS_FAILED",
 		7: "STATUS_CANCELLED",
+		8: "STATUS_WARNING",
 	}
 	Status_value = map[string]int32{
 		"STATUS_UNSPECIFIED":  0,
@@ -75,6 +133,7 @@ var (
 		"STATUS_SUCCESS":      5,
 		"STATUS_FAILED":       6,
 		"STATUS_CANCELLED":    7,
+		"STATUS_WARNING":      8,
 	}
 )
 
@@ -89,11 +148,11 @@ func (x Status) String() string {
 }
 
 func (Status) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_workerapi_v1_worker_proto_enumTypes[0].Descriptor()
+	return file_api_workerapi_v1_worker_proto_enumTypes[1].Descriptor()
 }
 
 func (Status) Type() protoreflect.EnumType {
-	return &file_api_workerapi_v1_worker_proto_enumTypes[0]
+	return &file_api_workerapi_v1_worker_proto_enumTypes[1]
 }
 
 func (x Status) Number() protoreflect.EnumNumber {
@@ -102,7 +161,7 @@ func (x Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Status.Descriptor instead.
 func (Status) EnumDescriptor() ([]byte, []int) {
-	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{0}
+	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{1}
 }
 
 // Empty messages, but exists for potential future usages
@@ -240,6 +299,11 @@ type StreamLogsResponse struct {
 	Timestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	// Message is the log line text.
 	Message string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	// Container is the name of the container in the build step that outputted
+	// the log line, such as an init container or a sidecar. Empty if unknown.
+	Container string `protobuf:"bytes,5,opt,name=container,proto3" json:"container,omitempty"`
+	// Stream is the output stream that the log line was written to.
+	Stream LogStream `protobuf:"varint,6,opt,name=stream,proto3,enum=wharf.worker.v1.LogStream" json:"stream,omitempty"`
 }
 
 func (x *StreamLogsResponse) Reset() {
@@ -302,6 +366,20 @@ func (x *StreamLogsResponse) GetMessage() string {
 	return ""
 }
 
+func (x *StreamLogsResponse) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *StreamLogsResponse) GetStream() LogStream {
+	if x != nil {
+		return x.Stream
+	}
+	return LogStreamUnspecified
+}
+
 // StreamStatusEventsResponse is a single build step status update.
 type StreamStatusEventsResponse struct {
 	state         protoimpl.MessageState
@@ -314,6 +392,9 @@ type StreamStatusEventsResponse struct {
 	StepID uint64 `protobuf:"varint,2,opt,name=step_id,json=stepId,proto3" json:"step_id,omitempty"`
 	// Status is the step's status.
 	Status Status `protobuf:"varint,3,opt,name=status,proto3,enum=wharf.worker.v1.Status" json:"status,omitempty"`
+	// Termination holds how the step's container or process terminated. Only
+	// set on the final status event of a step that has run.
+	Termination *Termination `protobuf:"bytes,4,opt,name=termination,proto3" json:"termination,omitempty"`
 }
 
 func (x *StreamStatusEventsResponse) Reset() {
@@ -369,6 +450,100 @@ func (x *StreamStatusEventsResponse) GetStatus() Status {
 	return StatusUnspecified
 }
 
+func (x *StreamStatusEventsResponse) GetTermination() *Termination {
+	if x != nil {
+		return x.Termination
+	}
+	return nil
+}
+
+// Termination holds details about how a build step's container or process
+// terminated.
+type Termination struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ExitCode is the exit code of the step's container or process.
+	ExitCode int32 `protobuf:"varint,1,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	// Reason is a short machine-readable reason of the termination, such as
+	// "Completed", "Error", "OOMKilled", or "DeadlineExceeded".
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Message is a human-readable message about the termination, if any.
+	Message string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// StartedAt is when the step's container or process started.
+	StartedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// FinishedAt is when the step's container or process terminated.
+	FinishedAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+}
+
+func (x *Termination) Reset() {
+	*x = Termination{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_workerapi_v1_worker_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Termination) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Termination) ProtoMessage() {}
+
+func (x *Termination) ProtoReflect() protoreflect.Message {
+	mi := &file_api_workerapi_v1_worker_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Termination.ProtoReflect.Descriptor instead.
+func (*Termination) Descriptor() ([]byte, []int) {
+	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Termination) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Termination) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Termination) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Termination) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Termination) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
 // StreamArtifactEventsResponse is a single build artifact event.
 type StreamArtifactEventsResponse struct {
 	state         protoimpl.MessageState
@@ -389,7 +564,7 @@ type StreamArtifactEventsResponse struct {
 func (x *StreamArtifactEventsResponse) Reset() {
 	*x = StreamArtifactEventsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_workerapi_v1_worker_proto_msgTypes[5]
+		mi := &file_api_workerapi_v1_worker_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -402,7 +577,7 @@ func (x *StreamArtifactEventsResponse) String() string {
 func (*StreamArtifactEventsResponse) ProtoMessage() {}
 
 func (x *StreamArtifactEventsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_workerapi_v1_worker_proto_msgTypes[5]
+	mi := &file_api_workerapi_v1_worker_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -415,7 +590,7 @@ func (x *StreamArtifactEventsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamArtifactEventsResponse.ProtoReflect.Descriptor instead.
 func (*StreamArtifactEventsResponse) Descriptor() ([]byte, []int) {
-	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{5}
+	return file_api_workerapi_v1_worker_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *StreamArtifactEventsResponse) GetArtifactID() uint64 {
@@ -453,7 +628,7 @@ var file_api_workerapi_v1_worker_proto_rawDesc = []byte{
 	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75,
 	0x65, 0x73, 0x74, 0x22, 0x1d, 0x0a, 0x1b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74,
 	0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x22, 0x98, 0x01, 0x0a, 0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x67,
+	0x73, 0x74, 0x22, 0xea, 0x01, 0x0a, 0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x67,
 	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6c, 0x6f, 0x67,
 	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6c, 0x6f, 0x67, 0x49, 0x64,
 	0x12, 0x17, 0x0a, 0x07, 0x73, 0x74, 0x65, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
@@ -462,23 +637,51 @@ var file_api_workerapi_v1_worker_proto_rawDesc = []byte{
 	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
 	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
 	0x61, 0x6d, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x81, 0x01,
-	0x0a, 0x1a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x45, 0x76,
-	0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x19, 0x0a, 0x08,
-	0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07,
-	0x65, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x73, 0x74, 0x65, 0x70, 0x5f,
-	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x73, 0x74, 0x65, 0x70, 0x49, 0x64,
-	0x12, 0x2f, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e,
-	0x32, 0x17, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e,
-	0x76, 0x31, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x22, 0x6c, 0x0a, 0x1c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74, 0x69, 0x66,
-	0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x5f, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x61, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74,
-	0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x73, 0x74, 0x65, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x04, 0x52, 0x06, 0x73, 0x74, 0x65, 0x70, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x2a,
-	0xb5, 0x01, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x12, 0x53, 0x54,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x32, 0x0a, 0x06, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x77, 0x68,
+	0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f,
+	0x67, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x22,
+	0xc1, 0x01, 0x0a, 0x1a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x19,
+	0x0a, 0x08, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x73, 0x74, 0x65,
+	0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x73, 0x74, 0x65, 0x70,
+	0x49, 0x64, 0x12, 0x2f, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x17, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65,
+	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x12, 0x3e, 0x0a, 0x0b, 0x74, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66,
+	0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x74, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x22, 0xd4, 0x01, 0x0a, 0x0b, 0x54, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x65, 0x78, 0x69, 0x74, 0x5f, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x65, 0x78, 0x69, 0x74, 0x43, 0x6f, 0x64, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x3b, 0x0a,
+	0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0a,
+	0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x41, 0x74, 0x22, 0x6c, 0x0a, 0x1c, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x72,
+	0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0a, 0x61, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x73,
+	0x74, 0x65, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x73, 0x74,
+	0x65, 0x70, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x2a, 0x55, 0x0a, 0x09, 0x4c, 0x6f, 0x67, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1a, 0x0a, 0x16, 0x4c, 0x4f, 0x47, 0x5f, 0x53, 0x54, 0x52,
+	0x45, 0x41, 0x4d, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x15, 0x0a, 0x11, 0x4c, 0x4f, 0x47, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f,
+	0x53, 0x54, 0x44, 0x4f, 0x55, 0x54, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x4c, 0x4f, 0x47, 0x5f,
+	0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x53, 0x54, 0x44, 0x45, 0x52, 0x52, 0x10, 0x02, 0x2a,
+	0xc9, 0x01, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x12, 0x53, 0x54,
 	0x41, 0x54, 0x55, 0x53, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44,
 	0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x50, 0x45, 0x4e,
 	0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53,
@@ -489,32 +692,33 @@ var file_api_workerapi_v1_worker_proto_rawDesc = []byte{
 	0x41, 0x54, 0x55, 0x53, 0x5f, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x05, 0x12, 0x11,
 	0x0a, 0x0d, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x45, 0x44, 0x10,
 	0x06, 0x12, 0x14, 0x0a, 0x10, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x43, 0x41, 0x4e, 0x43,
-	0x45, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x07, 0x32, 0xc9, 0x02, 0x0a, 0x06, 0x57, 0x6f, 0x72, 0x6b,
-	0x65, 0x72, 0x12, 0x57, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x67, 0x73,
-	0x12, 0x22, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e,
-	0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x67, 0x73, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72,
+	0x45, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x07, 0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41, 0x54, 0x55,
+	0x53, 0x5f, 0x57, 0x41, 0x52, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x08, 0x32, 0xc9, 0x02, 0x0a, 0x06,
+	0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x12, 0x57, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x4c, 0x6f, 0x67, 0x73, 0x12, 0x22, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72,
 	0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x67,
-	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x6f, 0x0a, 0x12, 0x53,
-	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x45, 0x76, 0x65, 0x6e, 0x74,
-	0x73, 0x12, 0x2a, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66,
+	0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x4c, 0x6f, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12,
+	0x6f, 0x0a, 0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x2a, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f,
+	0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x2b, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72,
 	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2b, 0x2e,
-	0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e,
-	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x45, 0x76, 0x65, 0x6e,
-	0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x75, 0x0a, 0x14,
-	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76,
-	0x65, 0x6e, 0x74, 0x73, 0x12, 0x2c, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72,
-	0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74,
-	0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x2d, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65,
-	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74, 0x69, 0x66,
-	0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x30, 0x01, 0x42, 0x3c, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
-	0x6d, 0x2f, 0x69, 0x76, 0x65, 0x72, 0x2d, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2f, 0x77, 0x68, 0x61,
-	0x72, 0x66, 0x2d, 0x63, 0x6d, 0x64, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x77, 0x6f, 0x72, 0x6b, 0x65,
-	0x72, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0xca, 0xb5, 0x03, 0x06, 0x08, 0x01, 0x52, 0x02, 0x49,
-	0x44, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01,
+	0x12, 0x75, 0x0a, 0x14, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x72, 0x74, 0x69, 0x66, 0x61,
+	0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x2c, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66,
+	0x2e, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x41, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2d, 0x2e, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2e, 0x77,
+	0x6f, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41,
+	0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x3c, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x76, 0x65, 0x72, 0x2d, 0x77, 0x68, 0x61, 0x72, 0x66,
+	0x2f, 0x77, 0x68, 0x61, 0x72, 0x66, 0x2d, 0x63, 0x6d, 0x64, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x77,
+	0x6f, 0x72, 0x6b, 0x65, 0x72, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0xca, 0xb5, 0x03, 0x06, 0x08,
+	0x01, 0x52, 0x02, 0x49, 0x44, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -529,32 +733,38 @@ func file_api_workerapi_v1_worker_proto_rawDescGZIP() []byte {
 	return file_api_workerapi_v1_worker_proto_rawDescData
 }
 
-var file_api_workerapi_v1_worker_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_api_workerapi_v1_worker_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_api_workerapi_v1_worker_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_workerapi_v1_worker_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_api_workerapi_v1_worker_proto_goTypes = []interface{}{
-	(Status)(0),                          // 0: wharf.worker.v1.Status
-	(*StreamLogsRequest)(nil),            // 1: wharf.worker.v1.StreamLogsRequest
-	(*StreamStatusEventsRequest)(nil),    // 2: wharf.worker.v1.StreamStatusEventsRequest
-	(*StreamArtifactEventsRequest)(nil),  // 3: wharf.worker.v1.StreamArtifactEventsRequest
-	(*StreamLogsResponse)(nil),           // 4: wharf.worker.v1.StreamLogsResponse
-	(*StreamStatusEventsResponse)(nil),   // 5: wharf.worker.v1.StreamStatusEventsResponse
-	(*StreamArtifactEventsResponse)(nil), // 6: wharf.worker.v1.StreamArtifactEventsResponse
-	(*timestamppb.Timestamp)(nil),        // 7: google.protobuf.Timestamp
+	(LogStream)(0),                       // 0: wharf.worker.v1.LogStream
+	(Status)(0),                          // 1: wharf.worker.v1.Status
+	(*StreamLogsRequest)(nil),            // 2: wharf.worker.v1.StreamLogsRequest
+	(*StreamStatusEventsRequest)(nil),    // 3: wharf.worker.v1.StreamStatusEventsRequest
+	(*StreamArtifactEventsRequest)(nil),  // 4: wharf.worker.v1.StreamArtifactEventsRequest
+	(*StreamLogsResponse)(nil),           // 5: wharf.worker.v1.StreamLogsResponse
+	(*StreamStatusEventsResponse)(nil),   // 6: wharf.worker.v1.StreamStatusEventsResponse
+	(*Termination)(nil),                  // 7: wharf.worker.v1.Termination
+	(*StreamArtifactEventsResponse)(nil), // 8: wharf.worker.v1.StreamArtifactEventsResponse
+	(*timestamppb.Timestamp)(nil),        // 9: google.protobuf.Timestamp
 }
 var file_api_workerapi_v1_worker_proto_depIdxs = []int32{
-	7, // 0: wharf.worker.v1.StreamLogsResponse.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 1: wharf.worker.v1.StreamStatusEventsResponse.status:type_name -> wharf.worker.v1.Status
-	1, // 2: wharf.worker.v1.Worker.StreamLogs:input_type -> wharf.worker.v1.StreamLogsRequest
-	2, // 3: wharf.worker.v1.Worker.StreamStatusEvents:input_type -> wharf.worker.v1.StreamStatusEventsRequest
-	3, // 4: wharf.worker.v1.Worker.StreamArtifactEvents:input_type -> wharf.worker.v1.StreamArtifactEventsRequest
-	4, // 5: wharf.worker.v1.Worker.StreamLogs:output_type -> wharf.worker.v1.StreamLogsResponse
-	5, // 6: wharf.worker.v1.Worker.StreamStatusEvents:output_type -> wharf.worker.v1.StreamStatusEventsResponse
-	6, // 7: wharf.worker.v1.Worker.StreamArtifactEvents:output_type -> wharf.worker.v1.StreamArtifactEventsResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	9, // 0: wharf.worker.v1.StreamLogsResponse.timestamp:type_name -> google.protobuf.Timestamp
+	0, // 1: wharf.worker.v1.StreamLogsResponse.stream:type_name -> wharf.worker.v1.LogStream
+	1, // 2: wharf.worker.v1.StreamStatusEventsResponse.status:type_name -> wharf.worker.v1.Status
+	7, // 3: wharf.worker.v1.StreamStatusEventsResponse.termination:type_name -> wharf.worker.v1.Termination
+	9, // 4: wharf.worker.v1.Termination.started_at:type_name -> google.protobuf.Timestamp
+	9, // 5: wharf.worker.v1.Termination.finished_at:type_name -> google.protobuf.Timestamp
+	2, // 6: wharf.worker.v1.Worker.StreamLogs:input_type -> wharf.worker.v1.StreamLogsRequest
+	3, // 7: wharf.worker.v1.Worker.StreamStatusEvents:input_type -> wharf.worker.v1.StreamStatusEventsRequest
+	4, // 8: wharf.worker.v1.Worker.StreamArtifactEvents:input_type -> wharf.worker.v1.StreamArtifactEventsRequest
+	5, // 9: wharf.worker.v1.Worker.StreamLogs:output_type -> wharf.worker.v1.StreamLogsResponse
+	6, // 10: wharf.worker.v1.Worker.StreamStatusEvents:output_type -> wharf.worker.v1.StreamStatusEventsResponse
+	8, // 11: wharf.worker.v1.Worker.StreamArtifactEvents:output_type -> wharf.worker.v1.StreamArtifactEventsResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_api_workerapi_v1_worker_proto_init() }
@@ -624,6 +834,18 @@ func file_api_workerapi_v1_worker_proto_init() {
 			}
 		}
 		file_api_workerapi_v1_worker_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Termination); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_workerapi_v1_worker_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StreamArtifactEventsResponse); i {
 			case 0:
 				return &v.state
@@ -641,8 +863,8 @@ func file_api_workerapi_v1_worker_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_workerapi_v1_worker_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   6,
+			NumEnums:      2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
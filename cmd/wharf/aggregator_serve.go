@@ -10,6 +10,7 @@ var aggregatorServeCmd = &cobra.Command{
 	Long: `The aggregator tool is used to stream build results from workers to
 the Wharf API through gRPC, killing the worker upon completion.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		defer setupTracing("wharf-cmd-aggregator")()
 		k8sAggregator, err := newAggregator()
 		if err != nil {
 			return err
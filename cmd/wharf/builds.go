@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/lastbuild"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/spf13/cobra"
+)
+
+var buildsFlags = struct {
+	project     string
+	allProjects bool
+	dir         string
+}{}
+
+var buildsCmd = &cobra.Command{
+	Use:   "builds",
+	Short: "Browse the results of previous builds",
+	Long: `Lists and shows the results of previous builds from "wharf run", as
+stored in the result store directory of each build.
+
+Builds are grouped per project, where a project is identified by the URL of
+the Git remote named "origin", or by the path of the repository or directory
+if it has no such remote. By default, only builds of the project in the
+current directory are included. Use --dir to select the project of another
+directory, --project to select a project by its key, or --all-projects to
+include all projects.
+
+The result store directories are located in the OS's temporary directory:
+` + resultstore.BuildsDir(),
+}
+
+func init() {
+	rootCmd.AddCommand(buildsCmd)
+
+	buildsCmd.PersistentFlags().StringVar(&buildsFlags.dir, "dir", ".", "Directory of the project to include builds of")
+	buildsCmd.PersistentFlags().StringVar(&buildsFlags.project, "project", "", "Key of the project to include builds of, as shown by --all-projects")
+	buildsCmd.PersistentFlags().BoolVar(&buildsFlags.allProjects, "all-projects", false, "Include builds of all projects")
+}
+
+// buildsFlagsProjectKey returns the key of the project selected by the flags,
+// or an empty string if all projects are selected.
+func buildsFlagsProjectKey() (string, error) {
+	if buildsFlags.allProjects {
+		if buildsFlags.project != "" {
+			return "", errors.New("flags --project and --all-projects are mutually exclusive")
+		}
+		return "", nil
+	}
+	return parseProjectKey(buildsFlags.project, buildsFlags.dir)
+}
+
+// parseProjectKey returns the project key if set, or else the key of the
+// project of the directory.
+func parseProjectKey(projectKey, dir string) (string, error) {
+	if projectKey != "" {
+		return projectKey, nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return lastbuild.ProjectForDir(absDir).Key, nil
+}
+
+func formatDurationSeconds(seconds float64) string {
+	dur := time.Duration(seconds * float64(time.Second))
+	if dur < time.Second {
+		return dur.Round(time.Millisecond).String()
+	}
+	return dur.Round(time.Second).String()
+}
+
+func formatStartedAt(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// parseTimeFlag parses a flag value as either a duration before now, such
+// as "24h", or as a date or timestamp in local time, such as "2022-06-28" or
+// "2022-06-28T15:04:05".
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if dur, err := time.ParseDuration(value); err == nil {
+		return now.Add(-dur), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, must be a duration such as \"24h\", or a date such as \"2022-06-28\" or \"2022-06-28T15:04:05\"", value)
+}
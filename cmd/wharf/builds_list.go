@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/flagtypes"
+	"github.com/iver-wharf/wharf-cmd/pkg/buildreport"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var buildsListFlags = struct {
+	statuses []string
+	since    string
+	until    string
+	limit    int
+	output   flagtypes.OutputFormat
+}{}
+
+var buildsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists previous builds",
+	Long: `Lists previous builds, with the most recently started build first,
+together with a summary of their status, duration, and the number of stages,
+steps, and artifacts.
+
+The status of a build is based on the statuses of its steps. Builds that
+were force quit may therefore still have the "Running" status.
+
+Use --since and --until to filter on when builds started, using either a
+duration before now, such as "24h", or a date or timestamp in local time,
+such as "2022-06-28" or "2022-06-28T15:04:05".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initLoggingWithWriter(os.Stderr)
+		filter, err := parseBuildsListFilter(time.Now())
+		if err != nil {
+			return err
+		}
+		projectKey, err := buildsFlagsProjectKey()
+		if err != nil {
+			return err
+		}
+		dirs, err := resultstore.ListBuildDirs(projectKey)
+		if err != nil {
+			return err
+		}
+		var items []buildListItem
+		for _, dir := range dirs {
+			report, err := readBuildReport(dir.Path)
+			if err != nil {
+				log.Warn().WithError(err).
+					WithString("path", dir.Path).
+					Message("Failed to read build results. Skipping build.")
+				continue
+			}
+			item := buildListItem{
+				Summary:    report.Summary(),
+				ProjectKey: dir.ProjectKey,
+				Path:       dir.Path,
+			}
+			if item.BuildID == 0 {
+				item.BuildID = dir.BuildID
+			}
+			if filter.match(item.Summary) {
+				items = append(items, item)
+			}
+		}
+		sortBuildListItems(items)
+		if buildsListFlags.limit > 0 && len(items) > buildsListFlags.limit {
+			items = items[:buildsListFlags.limit]
+		}
+		return writeBuildListItems(os.Stdout, items, buildsListFlags.output, projectKey == "")
+	},
+}
+
+type buildListItem struct {
+	buildreport.Summary
+	ProjectKey string `json:"projectKey"`
+	Path       string `json:"path"`
+}
+
+type buildsListFilter struct {
+	statuses map[workermodel.Status]bool
+	since    time.Time
+	until    time.Time
+}
+
+func parseBuildsListFilter(now time.Time) (buildsListFilter, error) {
+	var filter buildsListFilter
+	if len(buildsListFlags.statuses) > 0 {
+		filter.statuses = map[workermodel.Status]bool{}
+		for _, s := range buildsListFlags.statuses {
+			status := workermodel.ParseStatus(s)
+			if status == workermodel.StatusUnknown {
+				return buildsListFilter{}, fmt.Errorf("invalid --status: %q", s)
+			}
+			filter.statuses[status] = true
+		}
+	}
+	var err error
+	if buildsListFlags.since != "" {
+		if filter.since, err = parseTimeFlag(buildsListFlags.since, now); err != nil {
+			return buildsListFilter{}, fmt.Errorf("parse --since: %w", err)
+		}
+	}
+	if buildsListFlags.until != "" {
+		if filter.until, err = parseTimeFlag(buildsListFlags.until, now); err != nil {
+			return buildsListFilter{}, fmt.Errorf("parse --until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+func (f buildsListFilter) match(sum buildreport.Summary) bool {
+	if f.statuses != nil && !f.statuses[sum.Status] {
+		return false
+	}
+	if f.since.IsZero() && f.until.IsZero() {
+		return true
+	}
+	if sum.StartedAt == nil {
+		return false
+	}
+	if !f.since.IsZero() && sum.StartedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && sum.StartedAt.After(f.until) {
+		return false
+	}
+	return true
+}
+
+// sortBuildListItems sorts the most recently started builds first, and
+// builds that have not started last.
+func sortBuildListItems(items []buildListItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].StartedAt, items[j].StartedAt
+		switch {
+		case a == nil && b == nil:
+			return items[i].BuildID > items[j].BuildID
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		default:
+			return a.After(*b)
+		}
+	})
+}
+
+func readBuildReport(dir string) (buildreport.Report, error) {
+	store := resultstore.NewStore(resultstore.NewFS(dir))
+	defer store.Close()
+	if err := store.Freeze(); err != nil {
+		return buildreport.Report{}, err
+	}
+	return buildreport.ReadStore(store)
+}
+
+func writeBuildListItems(w io.Writer, items []buildListItem, format flagtypes.OutputFormat, withProject bool) error {
+	if items == nil {
+		items = []buildListItem{}
+	}
+	switch format {
+	case flagtypes.OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case flagtypes.OutputFormatYAML:
+		b, err := yaml.Marshal(items)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withProject {
+		fmt.Fprint(tw, "PROJECT\t")
+	}
+	fmt.Fprintln(tw, "BUILD\tSTATUS\tSTARTED\tDURATION\tSTAGES\tSTEPS\tARTIFACTS")
+	for _, item := range items {
+		if withProject {
+			fmt.Fprintf(tw, "%s\t", item.ProjectKey)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			item.BuildID, item.Status, formatStartedAt(item.StartedAt),
+			formatDurationSeconds(item.DurationSeconds),
+			item.Stages, item.Steps, item.Artifacts)
+	}
+	return tw.Flush()
+}
+
+func completeBuildStatus(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	var statuses []string
+	for _, s := range []workermodel.Status{
+		workermodel.StatusSuccess,
+		workermodel.StatusFailed,
+		workermodel.StatusCancelled,
+		workermodel.StatusRunning,
+		workermodel.StatusNone,
+	} {
+		statuses = append(statuses, s.String())
+	}
+	return statuses, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	buildsCmd.AddCommand(buildsListCmd)
+
+	buildsListCmd.Flags().StringSliceVar(&buildsListFlags.statuses, "status", nil, "Only include builds with any of these statuses, such as Success or Failed")
+	buildsListCmd.RegisterFlagCompletionFunc("status", completeBuildStatus)
+	buildsListCmd.Flags().StringVar(&buildsListFlags.since, "since", "", "Only include builds started after this time, such as \"24h\" or \"2022-06-28\"")
+	buildsListCmd.Flags().StringVar(&buildsListFlags.until, "until", "", "Only include builds started before this time, such as \"24h\" or \"2022-06-28\"")
+	buildsListCmd.Flags().IntVar(&buildsListFlags.limit, "limit", 0, "Only include this many of the most recent builds. Zero means no limit")
+	buildsListCmd.Flags().VarP(&buildsListFlags.output, "output", "o", `Output format. Must be one of "yaml" or "json". Prints a table if not set`)
+	buildsListCmd.RegisterFlagCompletionFunc("output", flagtypes.CompleteOutputFormat)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iver-wharf/wharf-cmd/internal/flagtypes"
+	"github.com/iver-wharf/wharf-cmd/pkg/buildreport"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var buildsShowFlags = struct {
+	output flagtypes.OutputFormat
+}{}
+
+var buildsShowCmd = &cobra.Command{
+	Use:   "show <build-id>",
+	Short: "Shows the results of a previous build",
+	Long: `Shows the status and duration of a previous build, together with the
+status, duration, and artifacts of each of its stages and steps.
+
+If multiple result store directories exist for the same build ID, such as
+from dry-runs, then the most recently modified one is used.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initLoggingWithWriter(os.Stderr)
+		buildID, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return fmt.Errorf("parse build ID: %w", err)
+		}
+		projectKey, err := buildsFlagsProjectKey()
+		if err != nil {
+			return err
+		}
+		if projectKey == "" {
+			return fmt.Errorf("flag --all-projects is not supported, use --project instead")
+		}
+		store, err := resultstore.OpenStoreForBuildID(projectKey, uint(buildID))
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		report, err := buildreport.ReadStore(store)
+		if err != nil {
+			return err
+		}
+		if report.BuildID == 0 {
+			report.BuildID = uint(buildID)
+		}
+		return writeBuildShowReport(os.Stdout, report, store.Path(), buildsShowFlags.output)
+	},
+}
+
+func writeBuildShowReport(w io.Writer, report buildreport.Report, path string, format flagtypes.OutputFormat) error {
+	switch format {
+	case flagtypes.OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case flagtypes.OutputFormatYAML:
+		b, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Build:\t%d\n", report.BuildID)
+	if report.Project != "" {
+		fmt.Fprintf(tw, "Project:\t%s\n", report.Project)
+	}
+	if report.RerunOfBuildID != 0 {
+		fmt.Fprintf(tw, "Re-run of:\t%d\n", report.RerunOfBuildID)
+	}
+	fmt.Fprintf(tw, "Status:\t%s\n", report.Status)
+	fmt.Fprintf(tw, "Started:\t%s\n", formatStartedAt(report.StartedAt))
+	fmt.Fprintf(tw, "Duration:\t%s\n", formatDurationSeconds(report.DurationSeconds))
+	fmt.Fprintf(tw, "Path:\t%s\n", path)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "STAGE/STEP\tTYPE\tSTATUS\tDURATION\tARTIFACTS")
+	var stepErrors []string
+	for _, stage := range report.Stages {
+		fmt.Fprintf(tw, "%s\t\t%s\t%s\n", stage.Name, stage.Status,
+			formatDurationSeconds(stage.DurationSeconds))
+		for _, step := range stage.Steps {
+			var artifacts []string
+			for _, a := range step.Artifacts {
+				artifacts = append(artifacts, a.Name)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", step.Name, step.Type,
+				step.Status, formatDurationSeconds(step.DurationSeconds),
+				strings.Join(artifacts, ", "))
+			if step.Error != "" {
+				stepErrors = append(stepErrors, fmt.Sprintf("  %s/%s: %s", stage.Name, step.Name, step.Error))
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(stepErrors) > 0 {
+		fmt.Fprintf(w, "\nErrors:\n%s\n", strings.Join(stepErrors, "\n"))
+	}
+	return nil
+}
+
+func init() {
+	buildsCmd.AddCommand(buildsShowCmd)
+
+	buildsShowCmd.Flags().VarP(&buildsShowFlags.output, "output", "o", `Output format. Must be one of "yaml" or "json". Prints a table if not set`)
+	buildsShowCmd.RegisterFlagCompletionFunc("output", flagtypes.CompleteOutputFormat)
+}
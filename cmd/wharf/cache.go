@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the tarball cache used by wharf run",
+	Long: `Manages the persistent cache of repository tarballs, that "wharf run"
+and "wharf files" reuse when the files of a step have not changed since a
+previous run.
+
+The cache is configured via the worker.tarballCache settings in the config,
+and is by default stored in "iver-wharf/wharf-cmd/tarstore" inside the user's
+cache directory, such as ~/.cache on Linux.`,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func tarballCacheFromConfig() (tarstore.Cache, error) {
+	conf := rootConfig.Worker.TarballCache
+	maxSize, err := conf.MaxSizeBytes()
+	if err != nil {
+		return tarstore.Cache{}, fmt.Errorf("parse max size: %w", err)
+	}
+	dir := conf.Dir
+	if dir == "" {
+		dir, err = tarstore.DefaultCacheDir()
+		if err != nil {
+			return tarstore.Cache{}, fmt.Errorf("get default cache dir: %w", err)
+		}
+	}
+	return tarstore.Cache{Dir: dir, MaxSize: maxSize}, nil
+}
+
+// newTarStore creates a tarball store that uses the tarball cache, if it is
+// enabled. Falls back to a store without a cache if the cache directory
+// cannot be used.
+func newTarStore(currentDir string) (tarstore.Store, error) {
+	if !rootConfig.Worker.TarballCache.Enabled {
+		return tarstore.New(currentDir)
+	}
+	cache, err := tarballCacheFromConfig()
+	if err == nil {
+		var store tarstore.Store
+		store, err = tarstore.NewWithCache(currentDir, cache)
+		if err == nil {
+			return store, nil
+		}
+	}
+	log.Warn().
+		WithError(err).
+		Message("Failed to use tarball cache. Continuing without it.")
+	return tarstore.New(currentDir)
+}
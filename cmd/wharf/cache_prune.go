@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var cachePruneFlags = struct {
+	maxSize string
+}{}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Removes tarballs from the tarball cache",
+	Long: `Removes tarballs from the persistent tarball cache, starting with the
+least recently used ones.
+
+By default, all tarballs are removed. Use --max-size to instead only remove
+tarballs until the cache is at most the given size, such as "500Mi".
+
+Temporary directories left behind by runs that were force quit more than a
+day ago are also removed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var maxSize int64
+		if cachePruneFlags.maxSize != "" {
+			q, err := resource.ParseQuantity(cachePruneFlags.maxSize)
+			if err != nil {
+				return fmt.Errorf("parse --max-size: %w", err)
+			}
+			if q.Sign() < 0 {
+				return fmt.Errorf("invalid --max-size: %s, must not be negative", cachePruneFlags.maxSize)
+			}
+			maxSize = q.Value()
+		}
+		cache, err := tarballCacheFromConfig()
+		if err != nil {
+			return err
+		}
+		removed, err := cache.Prune(maxSize)
+		var removedSize int64
+		for _, e := range removed {
+			removedSize += e.Size
+			log.Debug().
+				WithString("key", e.Key).
+				WithString("size", formatByteSize(e.Size)).
+				Message("Removed tarball.")
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().
+			WithString("dir", cache.Dir).
+			WithInt("count", len(removed)).
+			WithString("size", formatByteSize(removedSize)).
+			Message("Pruned tarball cache.")
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cachePruneCmd.Flags().StringVar(&cachePruneFlags.maxSize, "max-size", "", "Only remove tarballs until the cache is at most this size, such as 500Mi")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/internal/flagtypes"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/spf13/cobra"
+	"gopkg.in/typ.v4/slices"
+)
+
+var filesFlags = struct {
+	step        string
+	noGitIgnore bool
+	env         string
+	inputs      flagtypes.KeyValueArray
+	varSubFlags commonVarSubFlags
+}{}
+
+var filesCmd = &cobra.Command{
+	Use:   "files [path]",
+	Short: "Lists the files that would be transferred to a step",
+	Long: `Lists the files of the repository that "wharf run" would transfer to a
+step, together with the size of the resulting tarball.
+
+Use the optional "path" argument to specify a .wharf-ci.yml file or a
+directory containing a .wharf-ci.yml file. Defaults to current directory ("./")
+
+Files are filtered in the following ways:
+
+- Files ignored by .gitignore files, unless --no-gitignore is set.
+- Files ignored by .wharfignore files, which use the same syntax as
+  .gitignore files, and can be placed in any directory.
+- Files ignored by the "ignore" list of the step in the .wharf-ci.yml file.
+- For helm and kubectl steps, all files except the ones they reference.
+
+Use --step to select the step, either by its name or via "stage/step" syntax.
+Without --step, only the .gitignore and .wharfignore files are applied, which
+is what all steps without their own filters share.
+
+The file paths are written to stdout, one per line, while the summary is
+logged to stderr, so the output can be piped to other tools.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"yml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initLoggingWithWriter(os.Stderr)
+		currentDir, err := parseCurrentDir(slices.SafeGet(args, 0))
+		if err != nil {
+			return err
+		}
+
+		def, err := parseBuildDefinition(currentDir, wharfyml.Args{
+			Env:       filesFlags.env,
+			Inputs:    parseInputArgs(filesFlags.inputs),
+			VarSource: filesFlags.varSubFlags.varSource(),
+		})
+		if err != nil {
+			return err
+		}
+
+		var step wharfyml.Step
+		if filesFlags.step != "" {
+			step, err = findStepByName(def, filesFlags.step)
+			if err != nil {
+				return err
+			}
+		}
+
+		tarStore, err := newTarStore(currentDir)
+		if err != nil {
+			return err
+		}
+		defer tarStore.Close()
+		closeBeforeForceQuit(tarStore)
+
+		tarball, err := worker.PrepareStepRepo(rootContext, step, 1, worker.StepRepoOptions{
+			TarStore:      tarStore,
+			VarSource:     def.VarSource,
+			SkipGitIgnore: filesFlags.noGitIgnore,
+			CurrentDir:    currentDir,
+		})
+		if err != nil {
+			return err
+		}
+		fileCount, filesSize, err := writeTarballFileNames(os.Stdout, tarball)
+		if err != nil {
+			return fmt.Errorf("read tarball: %w", err)
+		}
+		stat, err := os.Stat(string(tarball))
+		if err != nil {
+			return err
+		}
+		log.Info().
+			WithInt("files", fileCount).
+			WithString("filesSize", formatByteSize(filesSize)).
+			WithString("tarballSize", formatByteSize(stat.Size())).
+			Message("Done listing files.")
+		return nil
+	},
+}
+
+// findStepByName returns the step with the given name, or with the given
+// "stage/step" name.
+func findStepByName(def wharfyml.Definition, name string) (wharfyml.Step, error) {
+	stageName, stepName, hasStage := strings.Cut(name, "/")
+	if !hasStage {
+		stepName = name
+	}
+	var found []wharfyml.Step
+	for _, stage := range def.Stages {
+		if hasStage && stage.Name != stageName {
+			continue
+		}
+		for _, step := range stage.Steps {
+			if step.Name == stepName {
+				found = append(found, step)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return wharfyml.Step{}, fmt.Errorf("step not found: %q", name)
+	case 1:
+		return found[0], nil
+	default:
+		return wharfyml.Step{}, fmt.Errorf("step name is ambiguous, use \"stage/step\" syntax instead: %q", name)
+	}
+}
+
+func writeTarballFileNames(w io.Writer, tarball tarstore.Tarball) (int, int64, error) {
+	file, err := tarball.Open()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	var count int
+	var size int64
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return count, size, nil
+		}
+		if err != nil {
+			return count, size, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		count++
+		size += header.Size
+		fmt.Fprintln(w, header.Name)
+	}
+}
+
+func formatByteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(filesCmd)
+
+	filesCmd.Flags().StringVar(&filesFlags.step, "step", "", "Step to list the files of, supports \"stage/step\" syntax")
+	filesCmd.RegisterFlagCompletionFunc("step", completeWharfYmlStep)
+	filesCmd.Flags().BoolVar(&filesFlags.noGitIgnore, "no-gitignore", false, "Don't respect .gitignore files")
+	addCommonVarSubFlags(filesCmd.Flags(), &filesFlags.varSubFlags)
+	addWharfYmlEnvFlag(filesCmd, filesCmd.Flags(), &filesFlags.env)
+	addWharfYmlInputsFlag(filesCmd, filesCmd.Flags(), &filesFlags.inputs)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workerclient"
+	"github.com/spf13/cobra"
+)
+
+var logsFlags = struct {
+	project     string
+	dir         string
+	step        string
+	follow      bool
+	since       string
+	grep        string
+	invertMatch bool
+	ignoreCase  bool
+	timestamps  bool
+}{}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <build-id>",
+	Short: "Prints the logs of a previous or running build",
+	Long: `Prints the logs of all steps of a build, or of a single step via --step,
+as read from the build's result store directory. Each line is prefixed with
+the stage and step name, and the lines of all steps are sorted by time.
+
+Use --follow to keep printing new log lines of a running build. This
+requires the build to have been started via "wharf run --serve", as the log
+lines are then streamed from its worker server. If no worker server is
+running for the build, then only the log lines written so far are printed.
+
+Builds are selected in the same way as for "wharf builds", where --dir or
+--project selects the project. Use --since to only print log lines written
+after a given time, using either a duration before now, such as "10m", or a
+date or timestamp in local time, such as "2022-06-28T15:04:05".
+
+Use --grep to only print log lines matching a regular expression, in the
+same syntax as used by Go: https://golang.org/s/re2syntax`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initLoggingWithWriter(os.Stderr)
+		buildID, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return fmt.Errorf("parse build ID: %w", err)
+		}
+		filter, err := parseLogLineFilter(time.Now())
+		if err != nil {
+			return err
+		}
+		projectKey, err := parseProjectKey(logsFlags.project, logsFlags.dir)
+		if err != nil {
+			return err
+		}
+		store, err := resultstore.OpenStoreForBuildID(projectKey, uint(buildID))
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		printer := logLinePrinter{
+			w:          os.Stdout,
+			filter:     filter,
+			stepNames:  newStepNameResolver(store),
+			timestamps: logsFlags.timestamps,
+		}
+
+		if logsFlags.follow {
+			client, ok := connectToBuildWorkerServer(rootContext, store)
+			if ok {
+				defer client.Close()
+				return followLogs(rootContext, client, printer)
+			}
+			log.Warn().
+				WithUint64("buildId", buildID).
+				Message("No worker server is serving the build, which requires the build to be running and started via \"wharf run --serve\". Printing the logs written so far.")
+		}
+
+		lines, err := readAllLogLines(store)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			printer.print(line)
+		}
+		return nil
+	},
+}
+
+type logLineFilter struct {
+	step        string
+	since       time.Time
+	grep        *regexp.Regexp
+	invertMatch bool
+}
+
+func parseLogLineFilter(now time.Time) (logLineFilter, error) {
+	filter := logLineFilter{
+		step:        logsFlags.step,
+		invertMatch: logsFlags.invertMatch,
+	}
+	if logsFlags.since != "" {
+		since, err := parseTimeFlag(logsFlags.since, now)
+		if err != nil {
+			return logLineFilter{}, fmt.Errorf("parse --since: %w", err)
+		}
+		filter.since = since
+	}
+	if logsFlags.grep != "" {
+		pattern := logsFlags.grep
+		if logsFlags.ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return logLineFilter{}, fmt.Errorf("parse --grep: %w", err)
+		}
+		filter.grep = re
+	}
+	return filter, nil
+}
+
+// match returns true if the log line, written by the step with the given
+// "stage/step" name, passes the filter. The step filter matches either the
+// step name or the full "stage/step" name.
+func (f logLineFilter) match(line resultstore.LogLine, stepName string) bool {
+	if f.step != "" && stepName != f.step && !strings.HasSuffix(stepName, "/"+f.step) {
+		return false
+	}
+	if !f.since.IsZero() && line.Timestamp.Before(f.since) {
+		return false
+	}
+	if f.grep != nil && f.grep.MatchString(line.Message) == f.invertMatch {
+		return false
+	}
+	return true
+}
+
+// stepNameResolver looks up the "stage/step" names of steps by their IDs.
+// Steps that are not yet known are looked up again, as new steps may start
+// while following the logs of a running build.
+type stepNameResolver struct {
+	store resultstore.Store
+	names map[uint64]string
+}
+
+func newStepNameResolver(store resultstore.Store) stepNameResolver {
+	return stepNameResolver{store: store, names: map[uint64]string{}}
+}
+
+func (r stepNameResolver) name(stepID uint64) string {
+	if name, ok := r.names[stepID]; ok {
+		return name
+	}
+	meta, err := r.store.ReadStepMeta(stepID)
+	if err != nil {
+		return fmt.Sprintf("step-%d", stepID)
+	}
+	name := meta.StageName + "/" + meta.StepName
+	r.names[stepID] = name
+	return name
+}
+
+type logLinePrinter struct {
+	w          io.Writer
+	filter     logLineFilter
+	stepNames  stepNameResolver
+	timestamps bool
+}
+
+func (p logLinePrinter) print(line resultstore.LogLine) {
+	stepName := p.stepNames.name(line.StepID)
+	if !p.filter.match(line, stepName) {
+		return
+	}
+	if p.timestamps {
+		fmt.Fprintf(p.w, "%s ", line.Timestamp.Local().Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(p.w, "[%s] %s\n", stepName, line.Message)
+}
+
+// readAllLogLines reads the log lines of all steps, including steps without
+// metadata, sorted by time.
+func readAllLogLines(store resultstore.Store) ([]resultstore.LogLine, error) {
+	stepIDs, err := store.ListStepIDs()
+	if err != nil {
+		return nil, err
+	}
+	var lines []resultstore.LogLine
+	for _, stepID := range stepIDs {
+		stepLines, err := readStepLogLines(store, stepID)
+		if err != nil {
+			return nil, fmt.Errorf("read logs of step %d: %w", stepID, err)
+		}
+		lines = append(lines, stepLines...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+	return lines, nil
+}
+
+func readStepLogLines(store resultstore.Store, stepID uint64) ([]resultstore.LogLine, error) {
+	reader, err := store.OpenLogReader(stepID)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var lines []resultstore.LogLine
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+// connectToBuildWorkerServer connects to the worker server of the build, as
+// started via "wharf run --serve". Returns false if the build was not served,
+// if its worker server is no longer running, or if the server at the same
+// address is serving another build.
+func connectToBuildWorkerServer(ctx context.Context, store resultstore.Store) (workerclient.Client, bool) {
+	meta, err := store.ReadBuildMeta()
+	if err != nil || meta.WorkerServerURL == "" {
+		return workerclient.Client{}, false
+	}
+	client, err := workerclient.New(meta.WorkerServerURL, workerclient.Options{
+		// Only connecting to a worker server on the same machine.
+		InsecureSkipVerify: true,
+		BuildID:            meta.BuildID,
+	})
+	if err != nil {
+		return workerclient.Client{}, false
+	}
+	getCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	served, err := client.GetBuild(getCtx)
+	if err != nil {
+		log.Debug().WithError(err).
+			WithString("url", meta.WorkerServerURL).
+			Message("Failed to get build from worker server.")
+		client.Close()
+		return workerclient.Client{}, false
+	}
+	if filepath.Clean(served.ResultStorePath) != filepath.Clean(store.Path()) {
+		log.Debug().
+			WithString("url", meta.WorkerServerURL).
+			WithUint("servedBuildId", served.BuildID).
+			WithString("servedPath", served.ResultStorePath).
+			Message("Worker server is serving another build.")
+		client.Close()
+		return workerclient.Client{}, false
+	}
+	log.Debug().
+		WithString("url", meta.WorkerServerURL).
+		Message("Following logs from worker server of build.")
+	return client, true
+}
+
+// followLogs prints all log lines streamed from the worker server, which
+// starts with the lines written so far, until the server closes the stream
+// or the context is cancelled.
+func followLogs(ctx context.Context, client workerclient.Client, printer logLinePrinter) error {
+	stream, err := client.StreamLogs(ctx, &workerclient.LogsRequest{})
+	if err != nil {
+		return fmt.Errorf("open logs stream from worker server: %w", err)
+	}
+	for {
+		line, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read logs stream from worker server: %w", err)
+		}
+		printer.print(resultstore.LogLine{
+			StepID:    line.StepID,
+			LogID:     line.LogID,
+			Message:   line.Message,
+			Timestamp: line.Timestamp.AsTime(),
+			Container: line.Container,
+		})
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().StringVar(&logsFlags.dir, "dir", ".", "Directory of the project of the build")
+	logsCmd.Flags().StringVar(&logsFlags.project, "project", "", "Key of the project of the build, as shown by \"wharf builds list --all-projects\"")
+	logsCmd.Flags().StringVar(&logsFlags.step, "step", "", "Only print logs of this step, supports \"stage/step\" syntax")
+	logsCmd.Flags().BoolVarP(&logsFlags.follow, "follow", "f", false, "Keep printing new log lines of a running build")
+	logsCmd.Flags().StringVar(&logsFlags.since, "since", "", "Only print log lines written after this time, such as \"10m\" or \"2022-06-28T15:04:05\"")
+	logsCmd.Flags().StringVar(&logsFlags.grep, "grep", "", "Only print log lines matching this regular expression")
+	logsCmd.Flags().BoolVar(&logsFlags.invertMatch, "invert-match", false, "Only print log lines not matching --grep")
+	logsCmd.Flags().BoolVar(&logsFlags.ignoreCase, "ignore-case", false, "Ignore casing when matching --grep")
+	logsCmd.Flags().BoolVarP(&logsFlags.timestamps, "timestamps", "t", false, "Prefix each log line with its timestamp")
+}
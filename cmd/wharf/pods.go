@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+var podsFlags = struct {
+	buildID   uint
+	projectID uint
+	stageID   uint64
+	stepID    uint64
+}{}
+
+var podsCmd = &cobra.Command{
+	Use:   "pods",
+	Short: "Manage step pods created by wharf run",
+	Long: `Lists and cleans up the Kubernetes pods of build steps, created by
+"wharf run" or by workers started via "wharf provisioner".
+
+Each step pod is labeled with the ID of its build, project, stage, and step:
+
+  wharf.iver.com/build-ref
+  wharf.iver.com/project-id
+  wharf.iver.com/stage-id
+  wharf.iver.com/step-id
+
+The pods can be filtered on these labels via the --build-id, --project-id,
+--stage-id, and --step-id flags. Only pods of the current Wharf instance, as
+set via --instance, are included.`,
+}
+
+func init() {
+	rootCmd.AddCommand(podsCmd)
+
+	podsCmd.PersistentFlags().UintVar(&podsFlags.buildID, "build-id", 0, "Only include pods of this build")
+	podsCmd.PersistentFlags().UintVar(&podsFlags.projectID, "project-id", 0, "Only include pods of this project")
+	podsCmd.PersistentFlags().Uint64Var(&podsFlags.stageID, "stage-id", 0, "Only include pods of this stage")
+	podsCmd.PersistentFlags().Uint64Var(&podsFlags.stepID, "step-id", 0, "Only include pods of this step")
+	addKubernetesFlags(podsCmd.PersistentFlags())
+}
+
+func newStepPodsClient() (corev1.PodInterface, error) {
+	restConfig, err := loadKubeconfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return clientset.CoreV1().Pods(rootConfig.K8s.Namespace), nil
+}
+
+func podsFlagsSelector() worker.K8sStepPodSelector {
+	return worker.K8sStepPodSelector{
+		InstanceID: rootConfig.InstanceID,
+		BuildID:    podsFlags.buildID,
+		ProjectID:  podsFlags.projectID,
+		StageID:    podsFlags.stageID,
+		StepID:     podsFlags.stepID,
+	}
+}
+
+func podsFlagsHasFilter() bool {
+	return podsFlags.buildID != 0 || podsFlags.projectID != 0 ||
+		podsFlags.stageID != 0 || podsFlags.stepID != 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+var podsCleanFlags = struct {
+	all bool
+}{}
+
+var podsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Deletes step pods inside Kubernetes",
+	Long: `Deletes the Kubernetes pods of build steps, such as pods left behind
+by "wharf run --keep-failed-pods" or by builds that were force quit.
+
+At least one of the --build-id, --project-id, --stage-id, or --step-id flags
+must be set. Use --all to instead delete all step pods of the current Wharf
+instance.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !podsCleanFlags.all && !podsFlagsHasFilter() {
+			return errors.New("missing filter flag, or --all to delete all step pods")
+		}
+		pods, err := newStepPodsClient()
+		if err != nil {
+			return err
+		}
+
+		sel := podsFlagsSelector()
+		deleted, err := worker.DeleteK8sStepPods(rootContext, pods, sel)
+		for _, name := range deleted {
+			log.Info().WithString("name", name).Message("Deleted step pod.")
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().
+			WithInt("count", len(deleted)).
+			WithString("selector", sel.LabelSelector()).
+			Message("Deleted step pods with matching labels.")
+		return nil
+	},
+}
+
+func init() {
+	podsCmd.AddCommand(podsCleanCmd)
+
+	podsCleanCmd.Flags().BoolVar(&podsCleanFlags.all, "all", false, "Delete all step pods, regardless of build")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+var podsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists step pods inside Kubernetes",
+	Long: `Lists the Kubernetes pods of build steps, together with the IDs of
+the build, project, stage, and step that created them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pods, err := newStepPodsClient()
+		if err != nil {
+			return err
+		}
+
+		sel := podsFlagsSelector()
+		list, err := worker.ListK8sStepPods(rootContext, pods, sel)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			WithInt("count", len(list)).
+			WithString("selector", sel.LabelSelector()).
+			Message("Fetched step pods with matching labels.")
+		for _, pod := range list {
+			log.Info().
+				WithString("name", pod.Name).
+				WithString("phase", string(pod.Status.Phase)).
+				WithDuration("age", time.Since(pod.CreationTimestamp.Time).Truncate(time.Second)).
+				WithString("buildId", pod.Labels[worker.LabelBuildRef]).
+				WithString("projectId", pod.Labels[worker.LabelProjectID]).
+				WithString("stageId", pod.Labels[worker.LabelStageID]).
+				WithString("stepId", pod.Labels[worker.LabelStepID]).
+				WithString("stage", pod.Annotations[worker.AnnotationStageName]).
+				WithString("step", pod.Annotations[worker.AnnotationStepName]).
+				Message("")
+		}
+		return nil
+	},
+}
+
+func init() {
+	podsCmd.AddCommand(podsListCmd)
+}
@@ -38,6 +38,7 @@ The <repo> argument is used by Git to clone the repository, such as:
 			return err
 		}
 
+		defer setupTracing("wharf-cmd-provisioner")()
 		p, err := newProvisioner()
 		if err != nil {
 			return err
@@ -18,6 +18,7 @@ the following URL path on a running wharf provisioner server:
 	/api/swagger/index.html
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		defer setupTracing("wharf-cmd-provisioner")()
 		p, err := newProvisioner()
 		if err != nil {
 			return err
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/iver-wharf/wharf-cmd/internal/flagtypes"
+	"github.com/iver-wharf/wharf-cmd/internal/lastbuild"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/spf13/cobra"
+	"gopkg.in/typ.v4/slices"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+var renderFlags = struct {
+	stage       string
+	steps       []string
+	skipSteps   []string
+	env         string
+	inputs      flagtypes.KeyValueArray
+	output      flagtypes.OutputFormat
+	varSubFlags commonVarSubFlags
+}{
+	output: flagtypes.OutputFormatYAML,
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render [path]",
+	Short: "Prints the Kubernetes pods of a .wharf-ci.yml file",
+	Long: `Parses a .wharf-ci.yml file and prints the complete Kubernetes
+Pod manifests that "wharf run" would create for each step, including labels,
+annotations, owner references, and volumes, without contacting Kubernetes.
+
+Use the optional "path" argument to specify a .wharf-ci.yml file or a
+directory containing a .wharf-ci.yml file. Defaults to current directory ("./")
+
+The pods are only given a generated name prefix, as their full names are set
+by Kubernetes on creation. The namespace is read from the kubeconfig, if any,
+or from the --k8s-namespace flag.
+
+With -o yaml (default), the pods are printed as a multi-document YAML stream.
+With -o json, the pods are printed as a single JSON list object. Logs are
+written to stderr, so the output can be piped to other tools.
+
+The stages and steps can be filtered in the same way as for "wharf run", via
+the --stage, --step, and --skip-step flags.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"yml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Keep stdout clean for the manifests, so they can be piped.
+		initLoggingWithWriter(os.Stderr)
+		currentDir, err := parseCurrentDir(slices.SafeGet(args, 0))
+		if err != nil {
+			return err
+		}
+
+		if renderFlags.varSubFlags.buildID == 0 {
+			renderFlags.varSubFlags.buildID, err = lastbuild.GuessNext(lastbuild.ProjectForDir(currentDir))
+			if err != nil {
+				return fmt.Errorf("get default for --build-id flag: %w", err)
+			}
+		}
+
+		def, err := parseBuildDefinition(currentDir, wharfyml.Args{
+			Env:       renderFlags.env,
+			Inputs:    parseInputArgs(renderFlags.inputs),
+			VarSource: renderFlags.varSubFlags.varSource(),
+		})
+		if err != nil {
+			return err
+		}
+
+		loadKubeNamespace()
+		pods, err := worker.RenderK8sPods(rootContext, def, worker.K8sRunnerOptions{
+			BuildOptions: worker.BuildOptions{
+				StageFilter:    renderFlags.stage,
+				StepFilter:     renderFlags.steps,
+				SkipStepFilter: renderFlags.skipSteps,
+			},
+			Config:    &rootConfig,
+			BuildID:   renderFlags.varSubFlags.buildID,
+			ProjectID: renderFlags.varSubFlags.projectID,
+		})
+		if err != nil {
+			return err
+		}
+		return writeRenderedPods(os.Stdout, pods, renderFlags.output)
+	},
+}
+
+// loadKubeNamespace sets the namespace from the kubeconfig and --namespace
+// flag, without requiring a valid kubeconfig to be present.
+func loadKubeNamespace() {
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	clientConf := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, &k8sOverridesFlags)
+	namespace, _, err := clientConf.Namespace()
+	if err != nil {
+		log.Debug().WithError(err).
+			WithString("namespace", rootConfig.K8s.Namespace).
+			Message("Failed to get namespace from kubeconfig. Using namespace from config.")
+		return
+	}
+	rootConfig.K8s.Namespace = namespace
+}
+
+func writeRenderedPods(w io.Writer, pods []v1.Pod, format flagtypes.OutputFormat) error {
+	switch format {
+	case flagtypes.OutputFormatJSON:
+		list := v1.PodList{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"},
+			Items:    pods,
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	default:
+		for i, pod := range pods {
+			b, err := yaml.Marshal(pod)
+			if err != nil {
+				return fmt.Errorf("marshal pod %d: %w", i, err)
+			}
+			if i > 0 {
+				io.WriteString(w, "---\n")
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().VarP(&renderFlags.output, "output", "o", `Output format. Must be one of "yaml" or "json"`)
+	renderCmd.RegisterFlagCompletionFunc("output", flagtypes.CompleteOutputFormat)
+
+	addCommonVarSubFlags(renderCmd.Flags(), &renderFlags.varSubFlags)
+	addWharfYmlStageFlag(renderCmd, renderCmd.Flags(), &renderFlags.stage)
+	addWharfYmlStepFlags(renderCmd, renderCmd.Flags(), &renderFlags.steps, &renderFlags.skipSteps)
+	addWharfYmlEnvFlag(renderCmd, renderCmd.Flags(), &renderFlags.env)
+	addWharfYmlInputsFlag(renderCmd, renderCmd.Flags(), &renderFlags.inputs)
+	addKubernetesFlags(renderCmd.Flags())
+}
@@ -38,9 +38,12 @@ var (
 	k8sOverridesFlags clientcmd.ConfigOverrides
 
 	rootConfig     config.Config
+	rootVersion    app.Version
 	runAfterConfig []func()
 
 	toCloseBeforeForceQuit []io.Closer
+
+	cancelSignalChan = newCancelSignalChan()
 )
 
 var rootFlags = struct {
@@ -101,6 +104,7 @@ func execute(version app.Version) {
 		f()
 	}
 
+	rootVersion = version
 	rootCmd.Version = versionString(version)
 	if err := rootCmd.Execute(); err != nil {
 		initLoggingIfNeeded()
@@ -146,7 +150,22 @@ func initLoggingIfNeeded() {
 }
 
 func initLogging() {
+	initLoggingWithWriter(nil)
+}
+
+// initLoggingWithWriter replaces all logging outputs with one that writes to
+// the given writer, or stdout if nil.
+func initLoggingWithWriter(w io.Writer) {
+	logger.ClearOutputs()
+	logger.AddOutput(rootFlags.loglevel.Level(), newConsoleLogSink(w))
+	isLoggingInitialized = true
+}
+
+// newConsoleLogSink returns a logging output that writes to the given writer,
+// or stdout if nil, using the same format for all commands.
+func newConsoleLogSink(w io.Writer) logger.Sink {
 	logConfig := consolepretty.DefaultConfig
+	logConfig.Writer = w
 	if rootFlags.loglevel.Level() != logger.LevelDebug {
 		logConfig.DisableCaller = true
 		logConfig.DisableDate = true
@@ -154,12 +173,11 @@ func initLogging() {
 	} else {
 		logConfig.ScopeMaxLength = 16
 	}
-	logger.AddOutput(rootFlags.loglevel.Level(), consolepretty.New(logConfig))
-	isLoggingInitialized = true
+	return consolepretty.New(logConfig)
 }
 
 func handleCancelSignals(cancel context.CancelFunc) {
-	ch := newCancelSignalChan()
+	ch := cancelSignalChan
 	<-ch
 	log.Info().WithDuration("gracePeriod", cancelGracePeriod).
 		Message("Cancelling build. Press ^C again to force quit.")
@@ -186,7 +204,17 @@ func closeBeforeForceQuit(closer io.Closer) {
 	toCloseBeforeForceQuit = append(toCloseBeforeForceQuit, closer)
 }
 
-func newCancelSignalChan() <-chan os.Signal {
+// sendCancelSignal behaves the same as if the process received an interrupt
+// signal. Used when the terminal is in raw mode, where Ctrl+C does not produce
+// an interrupt signal.
+func sendCancelSignal() {
+	select {
+	case cancelSignalChan <- os.Interrupt:
+	default:
+	}
+}
+
+func newCancelSignalChan() chan os.Signal {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	return ch
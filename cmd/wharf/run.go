@@ -4,30 +4,52 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/iver-wharf/wharf-cmd/internal/buildtui"
 	"github.com/iver-wharf/wharf-cmd/internal/flagtypes"
+	"github.com/iver-wharf/wharf-cmd/internal/gitutil"
 	"github.com/iver-wharf/wharf-cmd/internal/lastbuild"
+	"github.com/iver-wharf/wharf-cmd/pkg/buildreport"
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine"
+	"github.com/iver-wharf/wharf-cmd/pkg/notifier"
 	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
-	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
 	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workerserver"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
 	"github.com/spf13/cobra"
+	"gopkg.in/typ.v4"
 	"gopkg.in/typ.v4/slices"
+	"k8s.io/client-go/rest"
 )
 
 var runFlags = struct {
 	stage       string
+	steps       []string
+	skipSteps   []string
 	env         string
 	serve       bool
 	noGitIgnore bool
+	rerunFailed uint
+	keepFailed  bool
+	debugFailed bool
+	noTUI       bool
+	report      flagtypes.ReportFormat
+	reportFile  string
+	runner      flagtypes.Runner
+	engineHost  string
 	inputs      flagtypes.KeyValueArray
 	dryRun      flagtypes.DryRun
 	varSubFlags commonVarSubFlags
 }{
 	dryRun: flagtypes.DryRunNone,
+	report: flagtypes.ReportFormatNone,
+	runner: flagtypes.RunnerK8s,
 }
 
 var runCmd = &cobra.Command{
@@ -36,14 +58,77 @@ var runCmd = &cobra.Command{
 	Long: `Runs a new build in a Kubernetes cluster using pods
 based on a .wharf-ci.yml file.
 
+Use --runner local to instead run the commands of each container step directly
+on the host, inside a temporary copy of the repository, without contacting
+Kubernetes. The container image of each step is ignored, and other step types
+than "container" are not supported.
+
+Use --runner docker or --runner podman to instead run each step as a container
+via the Docker Engine API, or Podman's Docker-compatible API, without
+contacting Kubernetes. The repository is copied into each container before it
+is started. Kubernetes-specific features such as secrets and config maps are
+not supported, and are skipped with a warning. The API address is set via
+--engine-host, and defaults to the DOCKER_HOST environment variable or the
+engine's default socket.
+
 Use the optional "path" argument to specify a .wharf-ci.yml file or a
 directory containing a .wharf-ci.yml file. Defaults to current directory ("./")
 
 If no stage is specified via --stage then wharf will run all stages
 in sequence, based on their order of declaration in the .wharf-ci.yml file.
 
+Steps can be filtered across all stages via --step and --skip-step, which
+accept glob patterns such as "docker-*" or "build/*". Stages where all steps
+are filtered out are skipped, and it is an error if all steps are filtered out.
+
+Use --rerun-failed to only run the steps that did not succeed in a previous
+build, as well as all steps in the stages after it. The previous build's
+results are read from its result store directory. The new build gets a new
+build ID, and its results refer back to the original build. Steps that failed
+but were allowed to fail, with the "Warning" status, are not re-run. It is an
+error if all steps of the previous build succeeded.
+
+Use --keep-failed-pods to not delete the pods of failed steps. A debug pod,
+based on the failed step's pod but that only sleeps instead of running the
+step, is started next to it with the repository transferred to it. The
+kubectl commands to inspect, open a shell in, and delete the pods are logged.
+
+Use --debug-on-failure to instead directly open an interactive shell in the
+debug pod when a step fails. Both pods are deleted when the shell exits.
+
+When the k8s.buildNamespace.enabled setting of the wharf-cmd-config.yml file
+is set, a new Kubernetes namespace is created for the build, with the
+ResourceQuota, LimitRange, and NetworkPolicy from the config, and all step pods
+are run inside it. The namespace is deleted when the build finishes or is
+cancelled, unless --keep-failed-pods is set and the build did not succeed. No
+namespace is created when using --dry-run.
+
 All steps in each stage will be run in parallel for each stage.
 
+When run in a terminal, a live tree of all stages and steps is shown, together
+with the logs of the selected step. Use the arrow keys to select a step, and
+PgUp/PgDn to scroll its logs. Press "q" to close the UI and continue the build
+with plain log output, or Ctrl+C to cancel the build. The plain log output is
+used instead when stdout is not a terminal, or when --no-tui,
+--keep-failed-pods, or --debug-on-failure is set. Warnings and errors logged
+while the UI is shown are printed again after the UI is closed.
+
+Use --report to write a machine-readable report of the build results, with
+the status, duration, error, and artifacts of each step, as either "json",
+"junit", or "markdown". The report is written to stdout, or to the file set
+via --report-file. Logs are written to stderr when the report is written to
+stdout. The report is also written when the build fails.
+
+Notifications about the build starting, stages finishing, steps failing, and
+the build finishing are sent to the webhooks configured in the
+notifications.webhooks setting of the wharf-cmd-config.yml file, using either
+a generic, Slack-compatible, or Microsoft Teams-compatible JSON payload.
+
+OpenTelemetry spans of the build, its stages and steps, and of preparing and
+transferring the repository to each step are exported via OTLP when the
+tracing.enabled setting is set in the wharf-cmd-config.yml file. The trace is
+continued from the TRACEPARENT environment variable, if set.
+
 Read more about the .wharf-ci.yml file here:
 https://iver-wharf.github.io/#/usage-wharfyml/`,
 	Args: cobra.MaximumNArgs(1),
@@ -51,20 +136,28 @@ https://iver-wharf.github.io/#/usage-wharfyml/`,
 		return []string{"yml"}, cobra.ShellCompDirectiveFilterFileExt
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		kubeconfig, err := loadKubeconfig()
-		if err != nil {
-			return err
+		if w := runLogWriter(); w != nil {
+			initLoggingWithWriter(w)
+		}
+		if runFlags.runner != flagtypes.RunnerK8s {
+			if runFlags.dryRun != flagtypes.DryRunNone {
+				return fmt.Errorf("--dry-run is not supported with --runner %s", runFlags.runner)
+			}
+			if runFlags.keepFailed || runFlags.debugFailed {
+				return fmt.Errorf("--keep-failed-pods and --debug-on-failure are not supported with --runner %s", runFlags.runner)
+			}
 		}
 		currentDir, err := parseCurrentDir(slices.SafeGet(args, 0))
 		if err != nil {
 			return err
 		}
 
+		project := lastbuild.ProjectForDir(currentDir)
 		if runFlags.varSubFlags.buildID == 0 {
 			if runFlags.dryRun == flagtypes.DryRunNone {
-				runFlags.varSubFlags.buildID, err = lastbuild.Next()
+				runFlags.varSubFlags.buildID, err = lastbuild.Next(project)
 			} else {
-				runFlags.varSubFlags.buildID, err = lastbuild.GuessNext()
+				runFlags.varSubFlags.buildID, err = lastbuild.GuessNext(project)
 			}
 			if err != nil {
 				return fmt.Errorf("get default for --build-id flag: %w", err)
@@ -80,7 +173,24 @@ https://iver-wharf.github.io/#/usage-wharfyml/`,
 			return err
 		}
 
-		store, err := resultstore.NewStoreForBuildID(runFlags.varSubFlags.buildID)
+		var rerunSucceededSteps map[string]bool
+		if runFlags.rerunFailed != 0 {
+			rerunSucceededSteps, err = readSucceededStepsOfBuild(project, runFlags.rerunFailed)
+			if err != nil {
+				return fmt.Errorf("read results of build to re-run: %w", err)
+			}
+			log.Info().
+				WithUint("rerunOfBuildId", runFlags.rerunFailed).
+				WithInt("succeededSteps", len(rerunSucceededSteps)).
+				Message("Re-running failed steps of previous build.")
+		}
+
+		defer setupTracing("wharf-cmd-worker")()
+		buildCtx, buildSpan := startBuildSpan(rootContext)
+		defer buildSpan.End()
+
+		migrateLegacyResultStores()
+		store, err := resultstore.NewStoreForBuildID(project.Key, runFlags.varSubFlags.buildID)
 		if err != nil {
 			return err
 		}
@@ -88,45 +198,159 @@ https://iver-wharf.github.io/#/usage-wharfyml/`,
 		closeBeforeForceQuit(store)
 		log.Debug().WithString("path", store.Path()).
 			Message("Created result store.")
+		if err := store.SetBuildMeta(resultstore.BuildMeta{
+			BuildID:         runFlags.varSubFlags.buildID,
+			Project:         project.Name,
+			RerunOfBuildID:  runFlags.rerunFailed,
+			WorkerServerURL: typ.Tern(runFlags.serve, workerServerURL, ""),
+		}); err != nil {
+			return fmt.Errorf("write build metadata: %w", err)
+		}
 
-		tarStore, err := tarstore.New(currentDir)
+		tarStore, err := newTarStore(currentDir)
 		if err != nil {
 			return err
 		}
 		defer tarStore.Close()
 		closeBeforeForceQuit(tarStore)
 
-		b, err := worker.NewK8s(rootContext, def,
-			worker.K8sRunnerOptions{
-				BuildOptions: worker.BuildOptions{
-					StageFilter: runFlags.stage,
-				},
-				Config:        &rootConfig,
-				CurrentDir:    currentDir,
-				RestConfig:    kubeconfig,
-				ResultStore:   store,
-				SkipGitIgnore: runFlags.noGitIgnore,
-				TarStore:      tarStore,
-				VarSource:     def.VarSource,
-				DryRun:        convDryRunFlag(runFlags.dryRun),
-			})
+		buildOpts := worker.BuildOptions{
+			StageFilter:         runFlags.stage,
+			StepFilter:          runFlags.steps,
+			SkipStepFilter:      runFlags.skipSteps,
+			RerunSucceededSteps: rerunSucceededSteps,
+		}
+		var b worker.Builder
+		// buildSucceeded is set after the build, and decides if the build
+		// namespace is kept when using --keep-failed-pods.
+		var buildSucceeded bool
+		switch runFlags.runner {
+		case flagtypes.RunnerLocal:
+			b, err = worker.NewLocal(buildCtx, def,
+				worker.LocalRunnerOptions{
+					BuildOptions:  buildOpts,
+					Config:        &rootConfig,
+					CurrentDir:    currentDir,
+					ResultStore:   store,
+					SkipGitIgnore: runFlags.noGitIgnore,
+					TarStore:      tarStore,
+					VarSource:     def.VarSource,
+				})
+		case flagtypes.RunnerDocker, flagtypes.RunnerPodman:
+			client, clientErr := newEngineClient(runFlags.runner, runFlags.engineHost)
+			if clientErr != nil {
+				return clientErr
+			}
+			b, err = worker.NewDocker(buildCtx, def,
+				worker.DockerRunnerOptions{
+					BuildOptions:  buildOpts,
+					Config:        &rootConfig,
+					Client:        client,
+					CurrentDir:    currentDir,
+					ResultStore:   store,
+					SkipGitIgnore: runFlags.noGitIgnore,
+					TarStore:      tarStore,
+					VarSource:     def.VarSource,
+					BuildID:       runFlags.varSubFlags.buildID,
+					ProjectID:     runFlags.varSubFlags.projectID,
+				})
+		default:
+			kubeconfig, kubeErr := loadKubeconfig()
+			if kubeErr != nil {
+				return kubeErr
+			}
+			k8sConfig := rootConfig
+			if rootConfig.K8s.BuildNamespace.Enabled {
+				ns, nsErr := newBuildNamespace(buildCtx, kubeconfig)
+				if nsErr != nil {
+					return nsErr
+				}
+				if ns != nil {
+					defer func() { closeBuildNamespace(ns, buildSucceeded) }()
+					if !runFlags.keepFailed {
+						closeBeforeForceQuit(ns)
+					}
+					k8sConfig.K8s.Namespace = ns.Name
+				}
+			}
+			b, err = worker.NewK8s(buildCtx, def,
+				worker.K8sRunnerOptions{
+					BuildOptions:   buildOpts,
+					Config:         &k8sConfig,
+					CurrentDir:     currentDir,
+					RestConfig:     kubeconfig,
+					ResultStore:    store,
+					SkipGitIgnore:  runFlags.noGitIgnore,
+					TarStore:       tarStore,
+					VarSource:      def.VarSource,
+					DryRun:         convDryRunFlag(runFlags.dryRun),
+					BuildID:        runFlags.varSubFlags.buildID,
+					ProjectID:      runFlags.varSubFlags.projectID,
+					KeepFailedPods: runFlags.keepFailed,
+					DebugOnFailure: runFlags.debugFailed,
+				})
+		}
 		if err != nil {
 			return err
 		}
 
-		ctx := rootContext
+		ctx := buildCtx
 		if runFlags.serve {
 			var server workerserver.Server
-			ctx, server = startWorkerServerWithCancel(rootContext, store)
+			ctx, server = startWorkerServerWithCancel(buildCtx, store)
 			defer server.Close()
 		}
 
 		log.Debug().Message("Successfully created builder.")
 		log.Info().WithUint("buildId", runFlags.varSubFlags.buildID).
 			Message("Starting build.")
-		res, err := b.Build(ctx)
-		if err != nil {
-			return err
+		stopTUI := func() {}
+		if !runFlags.noTUI && !runFlags.keepFailed && !runFlags.debugFailed && buildtui.IsSupported() {
+			stopTUI, err = startRunTUI(store, runFlags.varSubFlags.buildID)
+			if err != nil {
+				return err
+			}
+		}
+		buildNotifier := notifier.New(rootConfig.Notifications.Webhooks, notifier.Options{
+			InstanceID: rootConfig.InstanceID,
+			BuildID:    runFlags.varSubFlags.buildID,
+			ProjectID:  runFlags.varSubFlags.projectID,
+		})
+		defer buildNotifier.Close()
+		buildNotifier.BuildStarted()
+		if err := buildNotifier.WatchStatusUpdates(store); err != nil {
+			log.Warn().WithError(err).Message("Failed to watch status updates. No stage or step notifications will be sent.")
+		}
+		startedAt := time.Now()
+		res, buildErr := b.Build(ctx)
+		stopTUI()
+		endBuildSpan(buildSpan, res, buildErr)
+		buildSucceeded = buildErr == nil && res.Status == workermodel.StatusSuccess
+		if runFlags.serve {
+			clearBuildWorkerServerURL(store)
+		}
+		if buildErr != nil {
+			// Still notify and report, as failed builds are the ones that
+			// matter the most to get notified about.
+			if res.Status != workermodel.StatusCancelled {
+				res.Status = workermodel.StatusFailed
+			}
+			if res.Duration == 0 {
+				res.Duration = time.Since(startedAt)
+			}
+		}
+		buildNotifier.BuildFinished(res)
+
+		if runFlags.report != flagtypes.ReportFormatNone {
+			if err := writeBuildReport(res, store, currentDir, startedAt); err != nil {
+				if buildErr == nil {
+					return fmt.Errorf("write build report: %w", err)
+				}
+				log.Warn().WithError(err).Message("Failed to write build report.")
+			}
+		}
+		if buildErr != nil {
+			return buildErr
 		}
 
 		if res.Status != workermodel.StatusSuccess && res.Status != workermodel.StatusCancelled {
@@ -148,6 +372,63 @@ https://iver-wharf.github.io/#/usage-wharfyml/`,
 	},
 }
 
+// newBuildNamespace creates a dedicated Kubernetes namespace for the build.
+// Returns nil when doing a dry run, as no pods are created then. When keeping
+// failed pods, the namespace is still created, but is not deleted afterwards
+// by closeBuildNamespace if the build did not succeed.
+func newBuildNamespace(ctx context.Context, kubeconfig *rest.Config) (*worker.K8sBuildNamespace, error) {
+	if runFlags.dryRun != flagtypes.DryRunNone {
+		log.Info().Message("Skipping creating build namespace because of dry-run.")
+		return nil, nil
+	}
+	ns, err := worker.NewK8sBuildNamespace(ctx, worker.K8sBuildNamespaceOptions{
+		Config:     rootConfig.K8s.BuildNamespace,
+		RestConfig: kubeconfig,
+		InstanceID: rootConfig.InstanceID,
+		BuildID:    runFlags.varSubFlags.buildID,
+		ProjectID:  runFlags.varSubFlags.projectID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create build namespace: %w", err)
+	}
+	return ns, nil
+}
+
+// closeBuildNamespace deletes the build namespace, unless failed pods are kept
+// and the build did not succeed, as the kept pods are inside the namespace.
+func closeBuildNamespace(ns *worker.K8sBuildNamespace, buildSucceeded bool) {
+	if runFlags.keepFailed && !buildSucceeded {
+		log.Info().
+			WithString("namespace", ns.Name).
+			Message("Keeping build namespace because of --keep-failed-pods. Delete it manually when done.")
+		return
+	}
+	if err := ns.Close(); err != nil {
+		log.Warn().WithError(err).Message("Failed to delete build namespace.")
+	}
+}
+
+const (
+	workerServerBindAddress = "0.0.0.0:5010"
+	// workerServerURL is the URL that the worker server can be reached on
+	// from the same machine, such as by "wharf logs --follow".
+	workerServerURL = "http://localhost:5010"
+)
+
+// clearBuildWorkerServerURL removes the worker server URL from the build
+// metadata, as the logs of a finished build are read from disk instead.
+func clearBuildWorkerServerURL(store resultstore.Store) {
+	meta, err := store.ReadBuildMeta()
+	if err == nil {
+		meta.WorkerServerURL = ""
+		err = store.SetBuildMeta(meta)
+	}
+	if err != nil {
+		log.Warn().WithError(err).
+			Message("Failed to clear worker server URL from build metadata.")
+	}
+}
+
 func startWorkerServerWithCancel(ctx context.Context, store resultstore.Store) (context.Context, workerserver.Server) {
 	ctx, cancel := context.WithCancel(ctx)
 	server := workerserver.New(store, nil)
@@ -158,7 +439,7 @@ func startWorkerServerWithCancel(ctx context.Context, store resultstore.Store) (
 	}()
 
 	go func() {
-		const address = "0.0.0.0:5010"
+		const address = workerServerBindAddress
 		log.Info().WithString("address", address).
 			Message("Serving build results via REST & gRPC.")
 		defer cancel()
@@ -170,21 +451,135 @@ func startWorkerServerWithCancel(ctx context.Context, store resultstore.Store) (
 	return ctx, server
 }
 
+func startRunTUI(store resultstore.Store, buildID uint) (func(), error) {
+	var stop func()
+	ui := buildtui.New(store, buildtui.Options{
+		BuildID:     buildID,
+		OnInterrupt: sendCancelSignal,
+		OnQuit: func() {
+			stop()
+			log.Info().Message("Closed the terminal UI. Continuing the build with plain log output.")
+		},
+	})
+	if err := ui.Start(); err != nil {
+		return nil, fmt.Errorf("start terminal UI: %w", err)
+	}
+	closeBeforeForceQuit(ui)
+	initLoggingWithWriter(ui.LogWriter())
+	// Warnings and errors are also printed after the UI is closed, so they
+	// are not lost together with the UI.
+	replayLevel := rootFlags.loglevel.Level()
+	if replayLevel < logger.LevelWarn {
+		replayLevel = logger.LevelWarn
+	}
+	logger.AddOutput(replayLevel, newConsoleLogSink(ui.ReplayWriter()))
+	var stopOnce sync.Once
+	stop = func() {
+		stopOnce.Do(func() {
+			initLoggingWithWriter(runLogWriter())
+			if err := ui.Close(); err != nil {
+				log.Warn().WithError(err).Message("Failed to restore terminal.")
+			}
+		})
+	}
+	return stop, nil
+}
+
+// runLogWriter returns the writer to use for logging, or nil to use stdout.
+// Logs are written to stderr when the build report is written to stdout, so
+// the report can be parsed.
+func runLogWriter() io.Writer {
+	if runFlags.report != flagtypes.ReportFormatNone && runFlags.reportFile == "" {
+		return os.Stderr
+	}
+	return nil
+}
+
+func writeBuildReport(res worker.Result, store resultstore.Store, currentDir string, startedAt time.Time) error {
+	report, err := buildreport.New(res, store)
+	if err != nil {
+		return err
+	}
+	report.StartedAt = &startedAt
+	if gitStats, err := gitutil.StatsFromExec(currentDir); err == nil {
+		report.Git = buildreport.NewGit(gitStats)
+	}
+	if runFlags.reportFile == "" {
+		return buildreport.Write(os.Stdout, buildreport.Format(runFlags.report), report)
+	}
+	file, err := os.Create(runFlags.reportFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := buildreport.Write(file, buildreport.Format(runFlags.report), report); err != nil {
+		return err
+	}
+	log.Info().WithString("path", runFlags.reportFile).
+		WithString("format", string(runFlags.report)).
+		Message("Wrote build report.")
+	return file.Close()
+}
+
+func readSucceededStepsOfBuild(project lastbuild.Project, buildID uint) (map[string]bool, error) {
+	store, err := resultstore.OpenStoreForBuildID(project.Key, buildID)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	log.Debug().WithString("path", store.Path()).
+		Message("Opened result store of build to re-run.")
+	return worker.ReadRerunSucceededSteps(store)
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 
 	runCmd.Flags().BoolVar(&runFlags.serve, "serve", false, "Serves build results over REST & gRPC and waits until terminated (e.g via SIGTERM)")
 	runCmd.Flags().BoolVar(&runFlags.noGitIgnore, "no-gitignore", false, "Don't respect .gitignore files")
+	runCmd.Flags().UintVar(&runFlags.rerunFailed, "rerun-failed", 0, "Build ID of a previous build to only re-run the failed steps and later stages of")
+	runCmd.Flags().BoolVar(&runFlags.keepFailed, "keep-failed-pods", false, "Don't delete pods of failed steps, and start a debug pod for each with the repository transferred to it")
+	runCmd.Flags().BoolVar(&runFlags.debugFailed, "debug-on-failure", false, "Open an interactive shell in a debug pod when a step fails")
+	runCmd.Flags().BoolVar(&runFlags.noTUI, "no-tui", false, "Print plain log output instead of showing the interactive terminal UI")
+	runCmd.Flags().Var(&runFlags.report, "report", `Write a build report. Must be one of "json", "junit", or "markdown"`)
+	runCmd.RegisterFlagCompletionFunc("report", flagtypes.CompleteReportFormat)
+	runCmd.Flags().StringVar(&runFlags.reportFile, "report-file", "", "File to write the build report to. Defaults to stdout")
+	runCmd.Flags().Var(&runFlags.runner, "runner", `Where to run the steps. Must be one of "k8s", "local", "docker", or "podman"`)
+	runCmd.RegisterFlagCompletionFunc("runner", flagtypes.CompleteRunner)
+	runCmd.Flags().StringVar(&runFlags.engineHost, "engine-host", "", "Docker Engine or Podman API address, such as unix:///var/run/docker.sock. Defaults to $DOCKER_HOST")
 	runCmd.Flags().Var(&runFlags.dryRun, "dry-run", `Must be one of "none", "client", or "server"`)
 	runCmd.RegisterFlagCompletionFunc("dry-run", flagtypes.CompleteDryRun)
 
 	addCommonVarSubFlags(runCmd.Flags(), &runFlags.varSubFlags)
 	addWharfYmlStageFlag(runCmd, runCmd.Flags(), &runFlags.stage)
+	addWharfYmlStepFlags(runCmd, runCmd.Flags(), &runFlags.steps, &runFlags.skipSteps)
 	addWharfYmlEnvFlag(runCmd, runCmd.Flags(), &runFlags.env)
 	addWharfYmlInputsFlag(runCmd, runCmd.Flags(), &runFlags.inputs)
 	addKubernetesFlags(runCmd.Flags())
 }
 
+func newEngineClient(runner flagtypes.Runner, host string) (*dockerengine.Client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		if runner == flagtypes.RunnerPodman {
+			host = dockerengine.DefaultPodmanHostForUser()
+		} else {
+			host = dockerengine.DefaultDockerHost
+		}
+	}
+	client, err := dockerengine.NewClient(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(rootContext); err != nil {
+		return nil, fmt.Errorf("connect to engine API at %s: %w", host, err)
+	}
+	log.Debug().WithString("host", host).Message("Connected to engine API.")
+	return client, nil
+}
+
 func convDryRunFlag(dryRun flagtypes.DryRun) worker.DryRun {
 	switch dryRun {
 	case flagtypes.DryRunClient:
@@ -195,3 +590,34 @@ func convDryRunFlag(dryRun flagtypes.DryRun) worker.DryRun {
 		return worker.DryRunNone
 	}
 }
+
+// migrateLegacyResultStores moves the result stores of builds from before
+// they were grouped per project into the legacy project, as it is unknown
+// which projects they belong to. This is only done once, unless it fails.
+func migrateLegacyResultStores() {
+	migrated, err := lastbuild.IsLegacyMigrated()
+	if err != nil {
+		log.Warn().WithError(err).
+			Message("Failed to check if result stores of previous builds are migrated.")
+		return
+	}
+	if migrated {
+		return
+	}
+	moved, err := resultstore.MigrateLegacyStores(lastbuild.LegacyProjectKey)
+	if len(moved) > 0 {
+		log.Info().
+			WithInt("count", len(moved)).
+			WithString("dir", resultstore.ProjectBuildsDir(lastbuild.LegacyProjectKey)).
+			Message("Moved result stores of previous builds, from before they were grouped per project.")
+	}
+	if err != nil {
+		log.Warn().WithError(err).
+			Message("Failed to migrate result stores of previous builds.")
+		return
+	}
+	if err := lastbuild.SetLegacyMigrated(); err != nil {
+		log.Warn().WithError(err).
+			Message("Failed to mark result stores of previous builds as migrated.")
+	}
+}
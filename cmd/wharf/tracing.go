@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracingShutdownTimeout = 5 * time.Second
+
+var tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/cmd/wharf")
+
+// setupTracing sets up OpenTelemetry tracing, if enabled in the config, and
+// returns a function that flushes any remaining spans. Errors are only logged,
+// as tracing is not essential to any command.
+func setupTracing(serviceName string) func() {
+	shutdown, err := tracing.Setup(rootConfig.Tracing, serviceName, rootVersion.Version)
+	if err != nil {
+		log.Warn().WithError(err).Message("Failed to set up tracing. No spans will be exported.")
+		return func() {}
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Warn().WithError(err).Message("Failed to flush traces.")
+		}
+	}
+}
+
+// startBuildSpan starts the root span of a build, which continues the trace
+// of the wharf-cmd-provisioner when run inside a worker pod, as read from the
+// TRACEPARENT environment variable.
+func startBuildSpan(ctx context.Context) (context.Context, trace.Span) {
+	ctx = tracing.ContextFromEnv(ctx)
+	return tracer.Start(ctx, "build", trace.WithAttributes(
+		attribute.Int64("wharf.build.id", int64(runFlags.varSubFlags.buildID)),
+		attribute.Int64("wharf.project.id", int64(runFlags.varSubFlags.projectID)),
+		attribute.String("wharf.instance", rootConfig.InstanceID),
+		attribute.String("wharf.runner", runFlags.runner.String()),
+	))
+}
+
+func endBuildSpan(span trace.Span, res worker.Result, err error) {
+	span.SetAttributes(attribute.Stringer("wharf.status", res.Status))
+	tracing.RecordError(span, err)
+	span.End()
+}
@@ -176,6 +176,13 @@ func addWharfYmlStageFlag(cmd *cobra.Command, flags *pflag.FlagSet, value *strin
 	cmd.RegisterFlagCompletionFunc("stage", completeWharfYmlStage)
 }
 
+func addWharfYmlStepFlags(cmd *cobra.Command, flags *pflag.FlagSet, steps, skipSteps *[]string) {
+	flags.StringArrayVar(steps, "step", nil, "Step to run, supports glob patterns and \"stage/step\" syntax (will run all steps if unset), can be set multiple times")
+	flags.StringArrayVar(skipSteps, "skip-step", nil, "Step to not run, supports glob patterns and \"stage/step\" syntax, can be set multiple times")
+	cmd.RegisterFlagCompletionFunc("step", completeWharfYmlStep)
+	cmd.RegisterFlagCompletionFunc("skip-step", completeWharfYmlStep)
+}
+
 func addWharfYmlEnvFlag(cmd *cobra.Command, flags *pflag.FlagSet, value *string) {
 	flags.StringVarP(value, "environment", "e", "", "Environment selection")
 	cmd.RegisterFlagCompletionFunc("environment", completeWharfYmlEnv)
@@ -198,6 +205,20 @@ func completeWharfYmlStage(cmd *cobra.Command, args []string, _ string) ([]strin
 	return stages, cobra.ShellCompDirectiveNoFileComp
 }
 
+func completeWharfYmlStep(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	def, err := parseWharfYmlForCompletions(cmd, args)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var steps []string
+	for _, stage := range def.Stages {
+		for _, step := range stage.Steps {
+			steps = append(steps, step.Name)
+		}
+	}
+	return steps, cobra.ShellCompDirectiveNoFileComp
+}
+
 func completeWharfYmlEnv(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 	def, err := parseWharfYmlForCompletions(cmd, args)
 	if err != nil {
@@ -253,9 +274,9 @@ func (flags commonVarSubFlags) varSource() varsub.Source {
 	m := make(varsub.SourceMap)
 	if flags.buildID != 0 {
 		sourceName := "flag --build-id"
-		if path, err := lastbuild.Path(); err == nil {
+		if dir, err := lastbuild.ProjectsDir(); err == nil {
 			sourceName = fmt.Sprintf(
-				"%s, or next ID from %s", sourceName, util.ShorthandHome(path))
+				"%s, or next ID of the project from %s", sourceName, util.ShorthandHome(dir))
 		}
 		m["BUILD_REF"] = varsub.Val{
 			Value:  flags.buildID,
@@ -275,10 +296,8 @@ func addCommonVarSubFlags(flags *pflag.FlagSet, varFlags *commonVarSubFlags) {
 	flags.UintVar(&varFlags.projectID, "project-id", 0, "Overrides PROJECT_ID variable")
 
 	buildIDHelp := "Overrides BUILD_REF variable"
-	if path, err := lastbuild.Path(); err == nil {
-		if nextGuess, err := lastbuild.GuessNext(); err == nil {
-			buildIDHelp = fmt.Sprintf("%s (default %d, via %q)", buildIDHelp, nextGuess, path)
-		}
+	if dir, err := lastbuild.ProjectsDir(); err == nil {
+		buildIDHelp = fmt.Sprintf("%s (default next build ID of the project, via %q)", buildIDHelp, dir)
 	}
 	flags.UintVar(&varFlags.buildID, "build-id", 0, buildIDHelp)
 }
@@ -22,14 +22,6 @@ var varsCmd = &cobra.Command{
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Intentionally not calling parent PersistentPreRunE
 		// to disable the SIGTERM signal hooks from rootCmd
-
-		if varsFlags.varSubFlags.buildID == 0 {
-			buildID, err := lastbuild.GuessNext()
-			if err != nil {
-				return fmt.Errorf("get default for --build-id flag: %w", err)
-			}
-			varsFlags.varSubFlags.buildID = buildID
-		}
 		return nil
 	},
 }
@@ -48,6 +40,14 @@ func varsCmdParseBuildDef(args []string) (wharfyml.Definition, error) {
 		return wharfyml.Definition{}, err
 	}
 
+	if varsFlags.varSubFlags.buildID == 0 {
+		buildID, err := lastbuild.GuessNext(lastbuild.ProjectForDir(currentDir))
+		if err != nil {
+			return wharfyml.Definition{}, fmt.Errorf("get default for --build-id flag: %w", err)
+		}
+		varsFlags.varSubFlags.buildID = buildID
+	}
+
 	return parseBuildDefinition(currentDir, wharfyml.Args{
 		Env:       varsFlags.env,
 		Inputs:    parseInputArgs(varsFlags.inputs),
@@ -0,0 +1,18 @@
+package buildtui
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// waitForInput waits until the console has input events to read, or until
+// the timeout. Returns false if the timeout was reached first.
+func waitForInput(f *os.File, timeout time.Duration) (bool, error) {
+	event, err := windows.WaitForSingleObject(windows.Handle(f.Fd()), uint32(timeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return event == windows.WAIT_OBJECT_0, nil
+}
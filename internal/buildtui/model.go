@@ -0,0 +1,437 @@
+package buildtui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+const (
+	// maxLogLinesPerStep limits how many log lines are kept in memory per
+	// step. The full logs are still available in the result store.
+	maxLogLinesPerStep = 10000
+	treePaneWidth      = 40
+	buildLogName       = "Build log"
+)
+
+var (
+	ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+	tabReplacer     = strings.NewReplacer("\t", "    ")
+
+	colorHeader   = color.New(color.Bold)
+	colorSelected = color.New(color.ReverseVideo)
+	colorStage    = color.New(color.Bold)
+	colorDim      = color.New(color.Faint)
+)
+
+type stepState struct {
+	id        uint64
+	name      string
+	stageName string
+	status    workermodel.Status
+	started   time.Time
+	finished  time.Time
+	logs      []string
+}
+
+func (s *stepState) duration(now time.Time) time.Duration {
+	if s.started.IsZero() {
+		return 0
+	}
+	if !s.finished.IsZero() {
+		return s.finished.Sub(s.started)
+	}
+	return now.Sub(s.started)
+}
+
+type stageState struct {
+	name  string
+	steps []*stepState
+}
+
+// model is the state of the terminal UI. It is not safe for concurrent use.
+type model struct {
+	buildID uint
+	started time.Time
+
+	buildLog *stepState
+	stages   []*stageState
+	steps    map[uint64]*stepState
+
+	// selected is the index of the selected log in the list returned by
+	// selectables, where 0 is the build log.
+	selected int
+	// scroll is the number of lines scrolled up from the bottom of the
+	// selected log. Zero means the log pane follows new log lines.
+	scroll int
+}
+
+func newModel(buildID uint, started time.Time) *model {
+	return &model{
+		buildID:  buildID,
+		started:  started,
+		buildLog: &stepState{name: buildLogName},
+		steps:    make(map[uint64]*stepState),
+	}
+}
+
+// hasStep returns true if the step has been added via addStep.
+func (m *model) hasStep(stepID uint64) bool {
+	_, ok := m.steps[stepID]
+	return ok
+}
+
+// addStep adds a step to the tree, grouped by its stage. Stages and steps are
+// shown in the order they are added.
+func (m *model) addStep(meta resultstore.StepMeta) {
+	if m.hasStep(meta.StepID) {
+		return
+	}
+	step := &stepState{
+		id:        meta.StepID,
+		name:      meta.StepName,
+		stageName: meta.StageName,
+	}
+	m.steps[meta.StepID] = step
+	for _, stage := range m.stages {
+		if stage.name == meta.StageName {
+			stage.steps = append(stage.steps, step)
+			return
+		}
+	}
+	m.stages = append(m.stages, &stageState{
+		name:  meta.StageName,
+		steps: []*stepState{step},
+	})
+}
+
+func (m *model) applyStatusUpdate(update resultstore.StatusUpdate) {
+	step, ok := m.steps[update.StepID]
+	if !ok {
+		return
+	}
+	step.status = update.Status
+	if step.started.IsZero() {
+		step.started = update.Timestamp
+	}
+	if isDoneStatus(update.Status) {
+		step.finished = update.Timestamp
+	}
+}
+
+func (m *model) applyLogLine(line resultstore.LogLine) {
+	step, ok := m.steps[line.StepID]
+	if !ok {
+		return
+	}
+	step.appendLog(line.Message)
+}
+
+func (m *model) addBuildLog(line string) {
+	m.buildLog.appendLog(line)
+}
+
+func (s *stepState) appendLog(line string) {
+	s.logs = append(s.logs, sanitizeLine(line))
+	if len(s.logs) > maxLogLinesPerStep {
+		s.logs = s.logs[len(s.logs)-maxLogLinesPerStep:]
+	}
+}
+
+// selectables returns the build log followed by all steps, in the order they
+// are shown in the tree.
+func (m *model) selectables() []*stepState {
+	list := []*stepState{m.buildLog}
+	for _, stage := range m.stages {
+		list = append(list, stage.steps...)
+	}
+	return list
+}
+
+func (m *model) selectedStep() *stepState {
+	list := m.selectables()
+	if m.selected >= len(list) {
+		m.selected = len(list) - 1
+	}
+	return list[m.selected]
+}
+
+func (m *model) moveSelection(delta int) {
+	list := m.selectables()
+	m.selected += delta
+	if m.selected < 0 {
+		m.selected = 0
+	}
+	if m.selected >= len(list) {
+		m.selected = len(list) - 1
+	}
+	m.scroll = 0
+}
+
+func (m *model) scrollLogs(delta int) {
+	m.scroll += delta
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+	if max := len(m.selectedStep().logs); m.scroll > max {
+		m.scroll = max
+	}
+}
+
+func (m *model) scrollToTop() {
+	m.scroll = len(m.selectedStep().logs)
+}
+
+func (m *model) scrollToBottom() {
+	m.scroll = 0
+}
+
+func (m *model) buildStatus() workermodel.Status {
+	status := workermodel.StatusNone
+	for _, step := range m.steps {
+		switch step.status {
+		case workermodel.StatusFailed:
+			return workermodel.StatusFailed
+		case workermodel.StatusScheduling, workermodel.StatusInitializing, workermodel.StatusRunning:
+			status = workermodel.StatusRunning
+		case workermodel.StatusCancelled:
+			if status != workermodel.StatusRunning {
+				status = workermodel.StatusCancelled
+			}
+		}
+	}
+	return status
+}
+
+// render returns the full screen content, one string per line. Each line is
+// at most width runes wide, not counting color escape codes.
+func (m *model) render(width, height int, now time.Time) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	lines := make([]string, 0, height)
+	header := fmt.Sprintf(" wharf run  build #%d  %s  %s", m.buildID,
+		m.buildStatus(), formatDuration(now.Sub(m.started)))
+	lines = append(lines, colorHeader.Sprint(padRight(header, width)))
+	if height == 1 {
+		return lines
+	}
+
+	bodyHeight := height - 2
+	treeWidth := treePaneWidth
+	if treeWidth > width/2 {
+		treeWidth = width / 2
+	}
+	logWidth := width - treeWidth - 1
+	tree := m.renderTree(treeWidth, bodyHeight, now)
+	logs := m.renderLogs(logWidth, bodyHeight)
+	for i := 0; i < bodyHeight; i++ {
+		lines = append(lines, tree[i]+colorDim.Sprint("│")+logs[i])
+	}
+
+	footer := " ↑/↓ select  PgUp/PgDn scroll  Home/End top/follow  q quit  ^C cancel"
+	lines = append(lines, colorDim.Sprint(padRight(footer, width)))
+	return lines
+}
+
+func (m *model) renderTree(width, height int, now time.Time) []string {
+	type row struct {
+		text     string
+		col      *color.Color
+		selected bool
+	}
+	rows := []row{{
+		text:     " " + buildLogName,
+		selected: m.selected == 0,
+	}}
+	selectedRow := 0
+	index := 1
+	for _, stage := range m.stages {
+		rows = append(rows, row{text: " " + stage.name, col: colorStage})
+		for _, step := range stage.steps {
+			durStr := ""
+			if !step.started.IsZero() {
+				durStr = formatDuration(step.duration(now))
+			}
+			prefix := fmt.Sprintf("   %s ", statusSymbol(step.status))
+			name := truncate(step.name, width-len([]rune(prefix))-len(durStr)-1)
+			text := prefix + padRight(name, width-len([]rune(prefix))-len(durStr)-1) + " " + durStr
+			if m.selected == index {
+				selectedRow = len(rows)
+			}
+			rows = append(rows, row{
+				text:     text,
+				col:      statusColor(step.status),
+				selected: m.selected == index,
+			})
+			index++
+		}
+	}
+
+	// Scroll the tree so the selected row is always visible.
+	offset := 0
+	if selectedRow >= height {
+		offset = selectedRow - height + 1
+	}
+	lines := make([]string, height)
+	for i := range lines {
+		idx := offset + i
+		if idx >= len(rows) {
+			lines[i] = strings.Repeat(" ", width)
+			continue
+		}
+		r := rows[idx]
+		text := padRight(truncate(r.text, width), width)
+		switch {
+		case r.selected:
+			lines[i] = colorSelected.Sprint(text)
+		case r.col != nil:
+			lines[i] = r.col.Sprint(text)
+		default:
+			lines[i] = text
+		}
+	}
+	return lines
+}
+
+func (m *model) renderLogs(width, height int) []string {
+	step := m.selectedStep()
+	lines := make([]string, height)
+	if height == 0 {
+		return lines
+	}
+	title := " " + step.name
+	if step != m.buildLog {
+		title = fmt.Sprintf(" %s/%s  %s", step.stageName, step.name, step.status)
+	}
+	if m.scroll > 0 {
+		title += fmt.Sprintf("  (scrolled %d lines up)", m.scroll)
+	}
+	lines[0] = colorHeader.Sprint(padRight(truncate(title, width), width))
+
+	logHeight := height - 1
+	end := len(step.logs) - m.scroll
+	start := end - logHeight
+	if start < 0 {
+		start = 0
+	}
+	for i := 1; i < height; i++ {
+		idx := start + i - 1
+		if idx >= end {
+			lines[i] = strings.Repeat(" ", width)
+			continue
+		}
+		lines[i] = padRight(truncate(" "+step.logs[idx], width), width)
+	}
+	return lines
+}
+
+// renderSummary returns the final state of all steps, meant to be printed
+// after the terminal UI has closed.
+func (m *model) renderSummary(now time.Time) []string {
+	var lines []string
+	for _, stage := range m.stages {
+		lines = append(lines, colorStage.Sprint(stage.name))
+		for _, step := range stage.steps {
+			lines = append(lines, statusColor(step.status).Sprintf("  %s %-30s %-12s %s",
+				statusSymbol(step.status), step.name, step.status,
+				formatDuration(step.duration(now))))
+		}
+	}
+	return lines
+}
+
+func isDoneStatus(status workermodel.Status) bool {
+	switch status {
+	case workermodel.StatusSuccess, workermodel.StatusFailed,
+		workermodel.StatusCancelled, workermodel.StatusWarning:
+		return true
+	default:
+		return false
+	}
+}
+
+func statusSymbol(status workermodel.Status) string {
+	switch status {
+	case workermodel.StatusScheduling:
+		return "◌"
+	case workermodel.StatusInitializing:
+		return "◐"
+	case workermodel.StatusRunning:
+		return "●"
+	case workermodel.StatusSuccess:
+		return "✓"
+	case workermodel.StatusFailed:
+		return "✗"
+	case workermodel.StatusCancelled:
+		return "⊘"
+	case workermodel.StatusWarning:
+		return "!"
+	default:
+		return "○"
+	}
+}
+
+func statusColor(status workermodel.Status) *color.Color {
+	switch status {
+	case workermodel.StatusScheduling, workermodel.StatusInitializing, workermodel.StatusRunning:
+		return color.New(color.FgCyan)
+	case workermodel.StatusSuccess:
+		return color.New(color.FgGreen)
+	case workermodel.StatusFailed:
+		return color.New(color.FgRed)
+	case workermodel.StatusWarning:
+		return color.New(color.FgYellow)
+	default:
+		return colorDim
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d < time.Hour {
+		return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// sanitizeLine removes color codes and control characters that would
+// otherwise break the layout of the terminal UI.
+func sanitizeLine(line string) string {
+	line = ansiEscapeRegex.ReplaceAllString(line, "")
+	line = tabReplacer.Replace(line)
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
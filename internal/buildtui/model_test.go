@@ -0,0 +1,105 @@
+package buildtui
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	color.NoColor = true
+}
+
+func newTestModel(t *testing.T) (*model, time.Time) {
+	start := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	m := newModel(42, start)
+	m.addStep(resultstore.StepMeta{StepID: 1, StageName: "build", StepName: "compile"})
+	m.addStep(resultstore.StepMeta{StepID: 2, StageName: "test", StepName: "unit"})
+	m.addStep(resultstore.StepMeta{StepID: 3, StageName: "build", StepName: "lint"})
+	return m, start
+}
+
+func TestModel_addStepGroupsByStage(t *testing.T) {
+	m, _ := newTestModel(t)
+	require.Len(t, m.stages, 2)
+	assert.Equal(t, "build", m.stages[0].name)
+	assert.Equal(t, "test", m.stages[1].name)
+
+	var names []string
+	for _, step := range m.selectables() {
+		names = append(names, step.name)
+	}
+	assert.Equal(t, []string{buildLogName, "compile", "lint", "unit"}, names)
+}
+
+func TestModel_applyStatusUpdateDuration(t *testing.T) {
+	m, start := newTestModel(t)
+	m.applyStatusUpdate(resultstore.StatusUpdate{StepID: 1, Status: workermodel.StatusScheduling, Timestamp: start})
+	m.applyStatusUpdate(resultstore.StatusUpdate{StepID: 1, Status: workermodel.StatusRunning, Timestamp: start.Add(time.Second)})
+	now := start.Add(10 * time.Second)
+	assert.Equal(t, 10*time.Second, m.steps[1].duration(now))
+	assert.Equal(t, workermodel.StatusRunning, m.buildStatus())
+
+	m.applyStatusUpdate(resultstore.StatusUpdate{StepID: 1, Status: workermodel.StatusFailed, Timestamp: start.Add(5 * time.Second)})
+	assert.Equal(t, 5*time.Second, m.steps[1].duration(now))
+	assert.Equal(t, workermodel.StatusFailed, m.buildStatus())
+}
+
+func TestModel_scrollLogs(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.moveSelection(1)
+	for i := 0; i < 5; i++ {
+		m.applyLogLine(resultstore.LogLine{StepID: 1, Message: "line"})
+	}
+	m.scrollLogs(3)
+	assert.Equal(t, 3, m.scroll)
+	m.scrollLogs(10)
+	assert.Equal(t, 5, m.scroll, "clamped to number of lines")
+	m.scrollLogs(-10)
+	assert.Equal(t, 0, m.scroll)
+
+	m.scrollToTop()
+	m.moveSelection(1)
+	assert.Equal(t, 0, m.scroll, "reset when changing selection")
+	m.moveSelection(100)
+	assert.Equal(t, 3, m.selected, "clamped to last step")
+}
+
+func TestModel_renderShowsSelectedLogs(t *testing.T) {
+	m, start := newTestModel(t)
+	m.applyStatusUpdate(resultstore.StatusUpdate{StepID: 2, Status: workermodel.StatusRunning, Timestamp: start})
+	m.applyLogLine(resultstore.LogLine{StepID: 2, Message: "\x1b[32mok\x1b[0m\tpkg/foo"})
+	m.applyLogLine(resultstore.LogLine{StepID: 1, Message: "from other step"})
+	m.moveSelection(3)
+
+	const width, height = 80, 10
+	lines := m.render(width, height, start.Add(65*time.Second))
+	require.Len(t, lines, height)
+	for i, line := range lines {
+		assert.Equalf(t, width, utf8.RuneCountInString(line), "line %d width: %q", i, line)
+	}
+	screen := strings.Join(lines, "\n")
+	assert.Contains(t, lines[0], "build #42")
+	assert.Contains(t, screen, "● unit")
+	assert.Contains(t, screen, "01:05")
+	assert.Contains(t, screen, "test/unit  Running")
+	assert.Contains(t, screen, "ok    pkg/foo")
+	assert.NotContains(t, screen, "from other step")
+}
+
+func TestSanitizeLine(t *testing.T) {
+	assert.Equal(t, "red text    tab", sanitizeLine("\x1b[31mred\x1b[0m text\ttab\r"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab…", truncate("abcd", 3))
+	assert.Equal(t, "", truncate("abcd", 0))
+}
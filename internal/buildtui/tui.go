@@ -0,0 +1,334 @@
+// Package buildtui contains an interactive terminal UI that shows the live
+// status and logs of all steps in a build, based on the updates published by
+// a result store.
+package buildtui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"golang.org/x/term"
+)
+
+const (
+	subBufferSize  = 100
+	redrawInterval = 100 * time.Millisecond
+	// inputPollInterval is how often to check if the UI is closed while
+	// waiting for input, so reading input can stop without consuming any
+	// keystrokes meant for the shell after the build.
+	inputPollInterval = 100 * time.Millisecond
+
+	escEnterAltScreen = "\x1b[?1049h"
+	escExitAltScreen  = "\x1b[?1049l"
+	escHideCursor     = "\x1b[?25l"
+	escShowCursor     = "\x1b[?25h"
+	escClearLine      = "\x1b[K"
+)
+
+// ErrNotTerminal is returned by Start when stdin or stdout is not a terminal.
+var ErrNotTerminal = errors.New("stdin or stdout is not a terminal")
+
+// Options holds settings for the terminal UI.
+type Options struct {
+	// BuildID is shown in the header of the UI.
+	BuildID uint
+	// OnInterrupt is called when the user presses Ctrl+C, as the terminal is
+	// in raw mode and will not produce an interrupt signal on its own.
+	OnInterrupt func()
+	// OnQuit is called when the user presses "q" to close the UI without
+	// cancelling the build. It is called in a new goroutine, so it may call
+	// Close.
+	OnQuit func()
+}
+
+// TUI is an interactive terminal UI of a running build.
+type TUI struct {
+	store resultstore.Store
+	opts  Options
+	in    *os.File
+	out   *os.File
+
+	mutex     sync.Mutex
+	model     *model
+	dirty     bool
+	logBuf    bytes.Buffer
+	replayBuf bytes.Buffer
+	oldState  *term.State
+
+	statusCh <-chan resultstore.StatusUpdate
+	logCh    <-chan resultstore.LogLine
+	done     chan struct{}
+	wg       sync.WaitGroup
+	closeErr error
+	closed   sync.Once
+}
+
+// IsSupported returns true if both stdin and stdout are terminals, which is
+// required to show the terminal UI.
+func IsSupported() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// New creates a new terminal UI that reads from the given result store. The
+// UI is not shown until Start is called.
+func New(store resultstore.Store, opts Options) *TUI {
+	return &TUI{
+		store: store,
+		opts:  opts,
+		in:    os.Stdin,
+		out:   os.Stdout,
+		model: newModel(opts.BuildID, time.Now()),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start switches the terminal over to the UI, and starts listening on status
+// updates and log lines from the result store.
+func (t *TUI) Start() error {
+	if !IsSupported() {
+		return ErrNotTerminal
+	}
+	statusCh, err := t.store.SubAllStatusUpdates(subBufferSize)
+	if err != nil {
+		return fmt.Errorf("subscribe to status updates: %w", err)
+	}
+	logCh, err := t.store.SubAllLogLines(subBufferSize)
+	if err != nil {
+		t.store.UnsubAllStatusUpdates(statusCh)
+		return fmt.Errorf("subscribe to log lines: %w", err)
+	}
+	oldState, err := term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		t.store.UnsubAllStatusUpdates(statusCh)
+		t.store.UnsubAllLogLines(logCh)
+		return fmt.Errorf("set terminal to raw mode: %w", err)
+	}
+	t.oldState = oldState
+	t.statusCh = statusCh
+	t.logCh = logCh
+	io.WriteString(t.out, escEnterAltScreen+escHideCursor)
+
+	t.wg.Add(4)
+	go t.readStatusUpdates()
+	go t.readLogLines()
+	go t.redrawLoop()
+	go t.readInput()
+	return nil
+}
+
+// Close stops the UI, restores the terminal, and prints a summary of all
+// steps to stdout. Calling Close multiple times has no further effect.
+func (t *TUI) Close() error {
+	t.closed.Do(func() {
+		t.closeErr = t.close()
+	})
+	return t.closeErr
+}
+
+func (t *TUI) close() error {
+	t.store.UnsubAllStatusUpdates(t.statusCh)
+	t.store.UnsubAllLogLines(t.logCh)
+	close(t.done)
+	t.wg.Wait()
+
+	io.WriteString(t.out, escShowCursor+escExitAltScreen)
+	err := term.Restore(int(t.in.Fd()), t.oldState)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, line := range t.model.renderSummary(time.Now()) {
+		fmt.Fprintln(t.out, line)
+	}
+	if t.replayBuf.Len() > 0 {
+		fmt.Fprintln(t.out)
+		fmt.Fprintln(t.out, "Warnings and errors:")
+		t.out.Write(t.replayBuf.Bytes())
+	}
+	return err
+}
+
+// LogWriter returns a writer that adds each written line to the build log in
+// the UI. Meant to be used as output of the logger while the UI is shown.
+func (t *TUI) LogWriter() io.Writer {
+	return logWriter{t}
+}
+
+// ReplayWriter returns a writer of which all written data is printed after the
+// summary when the UI is closed. Meant to be used as output of the logger for
+// warnings and errors, so they are still shown after the UI is closed.
+func (t *TUI) ReplayWriter() io.Writer {
+	return replayWriter{t}
+}
+
+type replayWriter struct {
+	t *TUI
+}
+
+func (w replayWriter) Write(p []byte) (int, error) {
+	w.t.mutex.Lock()
+	defer w.t.mutex.Unlock()
+	return w.t.replayBuf.Write(p)
+}
+
+type logWriter struct {
+	t *TUI
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.t.mutex.Lock()
+	defer w.t.mutex.Unlock()
+	w.t.logBuf.Write(p)
+	for {
+		line, err := w.t.logBuf.ReadString('\n')
+		if err != nil {
+			// Put back the incomplete line until the rest is written.
+			w.t.logBuf.WriteString(line)
+			break
+		}
+		w.t.model.addBuildLog(strings.TrimSuffix(line, "\n"))
+	}
+	w.t.dirty = true
+	return len(p), nil
+}
+
+func (t *TUI) readStatusUpdates() {
+	defer t.wg.Done()
+	for update := range t.statusCh {
+		t.mutex.Lock()
+		t.addStepIfMissing(update.StepID)
+		t.model.applyStatusUpdate(update)
+		t.dirty = true
+		t.mutex.Unlock()
+	}
+}
+
+func (t *TUI) readLogLines() {
+	defer t.wg.Done()
+	for line := range t.logCh {
+		t.mutex.Lock()
+		t.addStepIfMissing(line.StepID)
+		t.model.applyLogLine(line)
+		t.dirty = true
+		t.mutex.Unlock()
+	}
+}
+
+// addStepIfMissing must be called while holding the mutex.
+func (t *TUI) addStepIfMissing(stepID uint64) {
+	if t.model.hasStep(stepID) {
+		return
+	}
+	meta, err := t.store.ReadStepMeta(stepID)
+	if err != nil {
+		meta = resultstore.StepMeta{
+			StageName: "unknown",
+			StepName:  fmt.Sprintf("step #%d", stepID),
+		}
+	}
+	meta.StepID = stepID
+	t.model.addStep(meta)
+}
+
+func (t *TUI) redrawLoop() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+	lastFullRedraw := time.Time{}
+	for {
+		select {
+		case <-t.done:
+			return
+		case now := <-ticker.C:
+			t.mutex.Lock()
+			// Durations change every second, even without any new updates.
+			if t.dirty || now.Sub(lastFullRedraw) >= time.Second {
+				t.draw(now)
+				t.dirty = false
+				lastFullRedraw = now
+			}
+			t.mutex.Unlock()
+		}
+	}
+}
+
+// draw must be called while holding the mutex.
+func (t *TUI) draw(now time.Time) {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	for i, line := range t.model.render(width, height, now) {
+		fmt.Fprintf(&buf, "\x1b[%d;1H%s%s", i+1, line, escClearLine)
+	}
+	t.out.Write(buf.Bytes())
+}
+
+func (t *TUI) readInput() {
+	defer t.wg.Done()
+	buf := make([]byte, 16)
+	for {
+		ready, err := waitForInput(t.in, inputPollInterval)
+		if err != nil {
+			return
+		}
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+		if !ready {
+			continue
+		}
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+		t.handleKey(string(buf[:n]))
+	}
+}
+
+func (t *TUI) handleKey(key string) {
+	switch key {
+	case "\x03":
+		if t.opts.OnInterrupt != nil {
+			t.opts.OnInterrupt()
+		}
+		return
+	case "q":
+		if t.opts.OnQuit != nil {
+			go t.opts.OnQuit()
+		}
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pageSize := 10
+	if _, height, err := term.GetSize(int(t.out.Fd())); err == nil && height > 4 {
+		pageSize = height - 4
+	}
+	switch key {
+	case "\x1b[A", "k":
+		t.model.moveSelection(-1)
+	case "\x1b[B", "j":
+		t.model.moveSelection(1)
+	case "\x1b[5~", "\x02":
+		t.model.scrollLogs(pageSize)
+	case "\x1b[6~", "\x06":
+		t.model.scrollLogs(-pageSize)
+	case "\x1b[H", "\x1b[1~", "g":
+		t.model.scrollToTop()
+	case "\x1b[F", "\x1b[4~", "G":
+		t.model.scrollToBottom()
+	default:
+		return
+	}
+	t.dirty = true
+}
@@ -22,10 +22,22 @@ func (ioCopier) Copy(dst io.Writer, src io.Reader) error {
 	return err
 }
 
+func (ioCopier) CacheKey() string {
+	return "io"
+}
+
 // IOCopier is a copier implementation that uses the built-in io.Copy()
 // to copy the content in a loop.
 var IOCopier Copier = ioCopier{}
 
+// CacheKeyer is an optional interface for a Copier whose output depends only
+// on its input and on the returned key. Files copied with copiers that do
+// not implement this interface are never cached, such as when the output
+// depends on variables that may change between runs.
+type CacheKeyer interface {
+	CacheKey() string
+}
+
 // CopyFile takes a destination and source path and copies the content via the
 // copier implementation.
 func CopyFile(dst, src string, copier Copier) error {
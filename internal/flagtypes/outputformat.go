@@ -0,0 +1,66 @@
+package flagtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ensure they conform to the interfaces.
+var outputFormat = OutputFormatYAML
+var _ pflag.Value = &outputFormat
+
+// OutputFormat is an enum flag for setting the format of printed objects.
+type OutputFormat string
+
+const (
+	// OutputFormatYAML prints objects as YAML.
+	OutputFormatYAML OutputFormat = "yaml"
+	// OutputFormatJSON prints objects as JSON.
+	OutputFormatJSON OutputFormat = "json"
+)
+
+// String implements the pflag.Value and fmt.Stringer interfaces.
+// This returns a human-readable representation of the output format flag.
+func (f *OutputFormat) String() string {
+	return fmt.Sprintf(`"%s"`, string(*f))
+}
+
+// Set implements the pflag.Value interface.
+// This parses the output format string and updates the output format variable.
+func (f *OutputFormat) Set(value string) error {
+	format, err := parseOutputFormat(value)
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+func parseOutputFormat(value string) (OutputFormat, error) {
+	switch strings.ToLower(value) {
+	case "yaml", "yml":
+		return OutputFormatYAML, nil
+	case "json":
+		return OutputFormatJSON, nil
+	default:
+		return "", errors.New(`must be one of "yaml" or "json"`)
+	}
+}
+
+// Type implements the pflag.Value interface.
+// The value is only used in help text.
+func (f *OutputFormat) Type() string {
+	return "format"
+}
+
+// CompleteOutputFormat returns completions for the OutputFormat type.
+func CompleteOutputFormat(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		string(OutputFormatYAML) + "\tYAML",
+		string(OutputFormatJSON) + "\tJSON",
+	}, cobra.ShellCompDirectiveNoFileComp
+}
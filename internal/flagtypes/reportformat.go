@@ -0,0 +1,73 @@
+package flagtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ensure they conform to the interfaces.
+var reportFormat = ReportFormatNone
+var _ pflag.Value = &reportFormat
+
+// ReportFormat is an enum flag for setting the format of a build report.
+type ReportFormat string
+
+const (
+	// ReportFormatNone disables writing a build report.
+	ReportFormatNone ReportFormat = ""
+	// ReportFormatJSON writes the build report as JSON.
+	ReportFormatJSON ReportFormat = "json"
+	// ReportFormatJUnit writes the build report as JUnit XML.
+	ReportFormatJUnit ReportFormat = "junit"
+	// ReportFormatMarkdown writes the build report as Markdown.
+	ReportFormatMarkdown ReportFormat = "markdown"
+)
+
+// String implements the pflag.Value and fmt.Stringer interfaces.
+// This returns a human-readable representation of the report format flag.
+func (f *ReportFormat) String() string {
+	return fmt.Sprintf(`"%s"`, string(*f))
+}
+
+// Set implements the pflag.Value interface.
+// This parses the report format string and updates the report format variable.
+func (f *ReportFormat) Set(value string) error {
+	format, err := parseReportFormat(value)
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+func parseReportFormat(value string) (ReportFormat, error) {
+	switch strings.ToLower(value) {
+	case "json":
+		return ReportFormatJSON, nil
+	case "junit":
+		return ReportFormatJUnit, nil
+	case "markdown", "md":
+		return ReportFormatMarkdown, nil
+	default:
+		return "", errors.New(`must be one of "json", "junit", or "markdown"`)
+	}
+}
+
+// Type implements the pflag.Value interface.
+// The value is only used in help text.
+func (f *ReportFormat) Type() string {
+	return "format"
+}
+
+// CompleteReportFormat returns completions for the ReportFormat type.
+func CompleteReportFormat(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		string(ReportFormatJSON) + "\tFull result tree as JSON",
+		string(ReportFormatJUnit) + "\tJUnit XML, with stages as test suites and steps as test cases",
+		string(ReportFormatMarkdown) + "\tHuman-readable Markdown summary",
+	}, cobra.ShellCompDirectiveNoFileComp
+}
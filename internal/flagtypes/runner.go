@@ -0,0 +1,77 @@
+package flagtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ensure they conform to the interfaces.
+var runner = RunnerK8s
+var _ pflag.Value = &runner
+
+// Runner is an enum flag for setting where build steps are executed.
+type Runner string
+
+const (
+	// RunnerK8s runs each step as a pod in Kubernetes.
+	RunnerK8s Runner = "k8s"
+	// RunnerLocal runs the commands of each step directly on the host.
+	RunnerLocal Runner = "local"
+	// RunnerDocker runs each step as a container via the Docker Engine API.
+	RunnerDocker Runner = "docker"
+	// RunnerPodman runs each step as a container via Podman's
+	// Docker-compatible API.
+	RunnerPodman Runner = "podman"
+)
+
+// String implements the pflag.Value and fmt.Stringer interfaces.
+// This returns a human-readable representation of the runner flag.
+func (r *Runner) String() string {
+	return fmt.Sprintf(`"%s"`, string(*r))
+}
+
+// Set implements the pflag.Value interface.
+// This parses the runner string and updates the runner variable.
+func (r *Runner) Set(value string) error {
+	parsed, err := parseRunner(value)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func parseRunner(value string) (Runner, error) {
+	switch strings.ToLower(value) {
+	case "k8s", "kubernetes":
+		return RunnerK8s, nil
+	case "local":
+		return RunnerLocal, nil
+	case "docker":
+		return RunnerDocker, nil
+	case "podman":
+		return RunnerPodman, nil
+	default:
+		return "", errors.New(`must be one of "k8s", "local", "docker", or "podman"`)
+	}
+}
+
+// Type implements the pflag.Value interface.
+// The value is only used in help text.
+func (r *Runner) Type() string {
+	return "runner"
+}
+
+// CompleteRunner returns completions for the Runner type.
+func CompleteRunner(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		string(RunnerK8s) + "\tRuns each step as a pod in Kubernetes",
+		string(RunnerLocal) + "\tRuns the commands of container steps directly on the host",
+		string(RunnerDocker) + "\tRuns each step as a container via Docker",
+		string(RunnerPodman) + "\tRuns each step as a container via Podman",
+	}, cobra.ShellCompDirectiveNoFileComp
+}
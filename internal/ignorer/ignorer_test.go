@@ -1,6 +1,7 @@
 package ignorer
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -40,3 +41,59 @@ func assertIgnore(t *testing.T, i Ignorer, want bool, path string) {
 		t.Errorf("want Ignore(%q) = %t, got %t", path, want, got)
 	}
 }
+
+func TestWharfIgnorer(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".wharfignore":          "*.log\n/build/\n",
+		"sub/.wharfignore":      "secret.txt\n!keep.log\n",
+		"app.log":               "",
+		"main.go":               "",
+		"build/out.bin":         "",
+		"sub/secret.txt":        "",
+		"sub/keep.log":          "",
+		"sub/build/out.bin":     "",
+		"other/secret.txt":      "",
+		"other/nested/info.log": "",
+	})
+	i, err := NewWharfIgnorer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIgnore(t, i, true, "app.log")
+	assertIgnore(t, i, false, "main.go")
+	assertIgnore(t, i, true, "build")
+	assertIgnore(t, i, true, "sub/secret.txt")
+	assertIgnore(t, i, false, "sub/keep.log")
+	assertIgnore(t, i, false, "sub/build/out.bin")
+	assertIgnore(t, i, false, "other/secret.txt")
+	assertIgnore(t, i, true, "other/nested/info.log")
+}
+
+func TestPatternIgnorer(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"docs/readme.md": "",
+		"main.go":        "",
+		"main_test.go":   "",
+	})
+	i, err := NewPatternIgnorer(dir, []string{"docs/", "*_test.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIgnore(t, i, true, "docs")
+	assertIgnore(t, i, false, "main.go")
+	assertIgnore(t, i, true, "main_test.go")
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
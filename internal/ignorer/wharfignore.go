@@ -0,0 +1,59 @@
+package ignorer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/denormal/go-gitignore"
+)
+
+// WharfIgnoreFileName is the name of the files that list patterns, in
+// .gitignore syntax, of files to not transfer to the steps of a build.
+const WharfIgnoreFileName = ".wharfignore"
+
+// NewWharfIgnorer creates an Ignorer that ignores files based on the
+// .wharfignore files found in the directory and its subdirectories. Patterns
+// in nested .wharfignore files are relative to the directory the file is in,
+// in the same way as for .gitignore files.
+func NewWharfIgnorer(dir string) (Ignorer, error) {
+	repo, err := gitignore.NewRepositoryWithFile(dir, WharfIgnoreFileName)
+	if err != nil {
+		return nil, err
+	}
+	return matchIgnorer{dir, repo}, nil
+}
+
+// NewPatternIgnorer creates an Ignorer that ignores files matching any of the
+// patterns, in .gitignore syntax, relative to the given directory.
+func NewPatternIgnorer(dir string, patterns []string) (Ignorer, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var parseErr error
+	ign := gitignore.New(strings.NewReader(strings.Join(patterns, "\n")), absDir,
+		func(e gitignore.Error) bool {
+			if parseErr == nil {
+				parseErr = fmt.Errorf("invalid ignore pattern on line %d: %w", e.Position().Line, e.Underlying())
+			}
+			return true
+		})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return matchIgnorer{absDir, ign}, nil
+}
+
+type matchIgnorer struct {
+	dir     string
+	matcher gitignore.GitIgnore
+}
+
+func (i matchIgnorer) Ignore(relPath string) bool {
+	match := i.matcher.Match(filepath.Join(i.dir, relPath))
+	if match == nil {
+		return false
+	}
+	return match.Ignore()
+}
@@ -12,22 +12,23 @@ import (
 	"github.com/rogpeppe/go-internal/lockedfile"
 )
 
-// GuessNext returns the approximated next build ID to use. It calculates this
-// by reading the build ID file, bumping it by +1, then releasing the file lock.
+// GuessNext returns the approximated next build ID to use for a project. It
+// calculates this by reading the project's build ID file, bumping it by +1,
+// then releasing the file lock.
 //
 // This function does not mutate the next value.
 //
 // This means the guess from this function is not guaranteed to be the same
 // value as returned by Next, so the return from this function should only be
 // used to give approximations to the user.
-func GuessNext() (uint, error) {
-	file, err := editFile()
+func GuessNext(project Project) (uint, error) {
+	file, err := editFile(project)
 	if err != nil {
 		return 0, err
 	}
 	defer file.Close()
 
-	last, err := readLast(file)
+	last, err := readLastOrLegacy(file)
 	if err != nil {
 		return 0, err
 	}
@@ -36,17 +37,17 @@ func GuessNext() (uint, error) {
 	return next, nil
 }
 
-// Next returns the next build ID to use. It calculates this by locking the
-// build ID file, reading its current value, bumping it by +1, then writing
-// the new value, and then releasing the file lock.
-func Next() (uint, error) {
-	file, err := editFile()
+// Next returns the next build ID to use for a project. It calculates this by
+// locking the project's build ID file, reading its current value, bumping it
+// by +1, then writing the new value, and then releasing the file lock.
+func Next(project Project) (uint, error) {
+	file, err := editFile(project)
 	if err != nil {
 		return 0, err
 	}
 	defer file.Close()
 
-	last, err := readLast(file)
+	last, err := readLastOrLegacy(file)
 	if err != nil {
 		return 0, err
 	}
@@ -61,8 +62,8 @@ func Next() (uint, error) {
 	return next, nil
 }
 
-func editFile() (*lockedfile.File, error) {
-	path, err := Path()
+func editFile(project Project) (*lockedfile.File, error) {
+	path, err := Path(project)
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +85,33 @@ func createFile(path string) (*lockedfile.File, error) {
 	return lockedfile.Create(path)
 }
 
+// readLastOrLegacy reads the last build ID of a project. New projects
+// continue from the last build ID of the global build ID file used before
+// build IDs were counted per project, so build IDs do not restart at 1.
+func readLastOrLegacy(reader io.Reader) (uint, error) {
+	last, err := readLast(reader)
+	if err != nil || last != 0 {
+		return last, err
+	}
+	return readLegacyLast()
+}
+
+func readLegacyLast() (uint, error) {
+	path, err := LegacyPath()
+	if err != nil {
+		return 0, err
+	}
+	file, err := lockedfile.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return readLast(file)
+}
+
 func readLast(reader io.Reader) (uint, error) {
 	scanner := bufio.NewScanner(reader)
 	scanner.Scan()
@@ -106,11 +134,77 @@ func writeNext(writer io.Writer, next uint) error {
 	return err
 }
 
-// Path returns the path to the file that contains the last build ID.
-func Path() (string, error) {
-	cacheDir, err := os.UserCacheDir()
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iver-wharf", "wharf-cmd"), nil
+}
+
+// ProjectsDir returns the path to the directory that contains the build ID
+// files of all projects.
+func ProjectsDir() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "projects"), nil
+}
+
+// LegacyPath returns the path to the global file that contained the last
+// build ID before build IDs were counted per project. It is only read from,
+// to give new projects their first build ID.
+func LegacyPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "last-build-id.txt"), nil
+}
+
+// IsLegacyMigrated returns true if the builds from before build IDs were
+// counted per project have already been migrated, as marked by
+// SetLegacyMigrated.
+func IsLegacyMigrated() (bool, error) {
+	path, err := legacyMigratedPath()
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetLegacyMigrated marks that the builds from before build IDs were counted
+// per project have been migrated, so the migration is only done once.
+func SetLegacyMigrated() error {
+	path, err := legacyMigratedPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		return err
+	}
+	return os.WriteFile(path, nil, 0664)
+}
+
+func legacyMigratedPath() (string, error) {
+	dir, err := ProjectsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".legacy-migrated"), nil
+}
+
+// Path returns the path to the file that contains the last build ID of a
+// project.
+func Path(project Project) (string, error) {
+	projectsDir, err := ProjectsDir()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
-	return filepath.Join(cacheDir, "iver-wharf", "wharf-cmd", "last-build-id.txt"), nil
+	return filepath.Join(projectsDir, project.Key, "last-build-id.txt"), nil
 }
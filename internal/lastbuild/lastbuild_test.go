@@ -0,0 +1,56 @@
+package lastbuild
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext_continuesFromLegacy(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	legacyPath, err := LegacyPath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(legacyPath), 0775))
+	require.NoError(t, os.WriteFile(legacyPath, []byte("41\n"), 0664))
+
+	project := Project{Key: "my-project"}
+	guess, err := GuessNext(project)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), guess)
+
+	next, err := Next(project)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), next)
+	next, err = Next(project)
+	require.NoError(t, err)
+	assert.Equal(t, uint(43), next)
+
+	b, err := os.ReadFile(legacyPath)
+	require.NoError(t, err)
+	assert.Equal(t, "41\n", string(b), "legacy file should be left as-is")
+}
+
+func TestNext_noLegacy(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	next, err := Next(Project{Key: "my-project"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), next)
+}
+
+func TestSetLegacyMigrated(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	migrated, err := IsLegacyMigrated()
+	require.NoError(t, err)
+	assert.False(t, migrated)
+
+	require.NoError(t, SetLegacyMigrated())
+	migrated, err = IsLegacyMigrated()
+	require.NoError(t, err)
+	assert.True(t, migrated)
+}
@@ -0,0 +1,106 @@
+package lastbuild
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/internal/gitutil"
+)
+
+// LegacyProjectKey is the key of the project that builds are grouped under
+// when it is unknown which project they belong to, such as builds from
+// versions of wharf-cmd before build IDs were counted per project.
+const LegacyProjectKey = "legacy"
+
+var regexUnsafeKeyChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// Project is a group of builds that share the same build ID counter, such as
+// all builds of the same Git repository.
+type Project struct {
+	// Key uniquely identifies the project, and is safe to use in file
+	// names, e.g "iver-wharf-wharf-cmd-1a2b3c4d".
+	Key string
+	// Name is a human readable name of the project, e.g
+	// "iver-wharf/wharf-cmd".
+	Name string
+}
+
+// ProjectForDir returns the project of a directory. Repositories with an
+// "origin" Git remote are identified by the remote's URL, so that all clones
+// of the same repository share the same build IDs. Other directories are
+// identified by the path of their Git repository root, or by their own path
+// if not inside a Git repository.
+func ProjectForDir(dir string) Project {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+	if stats, err := gitutil.StatsFromExec(absDir); err == nil {
+		origin := stats.Remotes["origin"]
+		url := origin.FetchURL
+		if url == "" {
+			url = origin.PushURL
+		}
+		if url != "" {
+			return projectFromOrigin(url, stats.EstimatedRepoGroup, stats.EstimatedRepoName)
+		}
+	}
+	if root, err := gitutil.GitRepoRoot(absDir); err == nil {
+		absDir = root
+	}
+	return projectFromDir(absDir)
+}
+
+func projectFromOrigin(url, group, name string) Project {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(url), ".git")
+	}
+	if group != "" {
+		name = group + "/" + name
+	}
+	return Project{
+		Key:  projectKey(name, normalizeRemoteURL(url)),
+		Name: name,
+	}
+}
+
+// normalizeRemoteURL removes the scheme, user, and ".git" suffix from a Git
+// remote URL, so that HTTPS and SSH clones of the same repository get the
+// same project key. E.g "git@github.com:iver-wharf/wharf-cmd.git" and
+// "https://github.com/iver-wharf/wharf-cmd" both become
+// "github.com/iver-wharf/wharf-cmd".
+func normalizeRemoteURL(url string) string {
+	if _, afterScheme, ok := strings.Cut(url, "://"); ok {
+		url = afterScheme
+	} else {
+		// SCP-like syntax, e.g "git@github.com:iver-wharf/wharf-cmd.git"
+		url = strings.Replace(url, ":", "/", 1)
+	}
+	if _, afterUser, ok := strings.Cut(url, "@"); ok {
+		url = afterUser
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	return strings.ToLower(url)
+}
+
+func projectFromDir(absDir string) Project {
+	name := filepath.Base(absDir)
+	return Project{
+		Key:  projectKey(name, absDir),
+		Name: name,
+	}
+}
+
+// projectKey returns a file name safe key from the name, suffixed with a
+// hash of the unique ID to tell apart projects with the same name.
+func projectKey(name, uniqueID string) string {
+	hash := sha256.Sum256([]byte(uniqueID))
+	safeName := strings.Trim(regexUnsafeKeyChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if safeName == "" {
+		safeName = "project"
+	}
+	return safeName + "-" + hex.EncodeToString(hash[:4])
+}
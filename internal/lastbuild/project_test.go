@@ -0,0 +1,44 @@
+package lastbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRemoteURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/iver-wharf/wharf-cmd", "github.com/iver-wharf/wharf-cmd"},
+		{"https://github.com/iver-wharf/wharf-cmd.git", "github.com/iver-wharf/wharf-cmd"},
+		{"git@github.com:iver-wharf/wharf-cmd.git", "github.com/iver-wharf/wharf-cmd"},
+		{"ssh://git@github.com/iver-wharf/wharf-cmd.git", "github.com/iver-wharf/wharf-cmd"},
+		{"https://user@dev.azure.com/org/proj/_git/Repo", "dev.azure.com/org/proj/_git/repo"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			assert.Equal(t, tc.want, normalizeRemoteURL(tc.url))
+		})
+	}
+}
+
+func TestProjectFromOrigin(t *testing.T) {
+	https := projectFromOrigin("https://github.com/iver-wharf/wharf-cmd.git", "iver-wharf", "wharf-cmd")
+	ssh := projectFromOrigin("git@github.com:iver-wharf/wharf-cmd.git", "iver-wharf", "wharf-cmd")
+	fork := projectFromOrigin("https://gitlab.com/iver-wharf/wharf-cmd.git", "iver-wharf", "wharf-cmd")
+
+	assert.Equal(t, "iver-wharf/wharf-cmd", https.Name)
+	assert.Regexp(t, `^iver-wharf-wharf-cmd-[0-9a-f]{8}$`, https.Key)
+	assert.Equal(t, https, ssh, "HTTPS and SSH clones share the same project")
+	assert.NotEqual(t, https.Key, fork.Key, "same name on different hosts are different projects")
+}
+
+func TestProjectFromDir(t *testing.T) {
+	a := projectFromDir("/home/user/My Repo")
+	b := projectFromDir("/home/other/My Repo")
+	assert.Equal(t, "My Repo", a.Name)
+	assert.Regexp(t, `^my-repo-[0-9a-f]{8}$`, a.Key)
+	assert.NotEqual(t, a.Key, b.Key)
+}
@@ -0,0 +1,70 @@
+package tarutil
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrIllegalPath is returned by Extract when a file in the tarball would be
+// written outside the destination directory.
+var ErrIllegalPath = errors.New("illegal path in tarball")
+
+// Extract writes all files and directories from a tarball into the
+// destination directory, which must already exist. Only regular files and
+// directories are extracted.
+func Extract(r io.Reader, destDir string) error {
+	rootDirPath, err := filepath.Abs(destDir)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(rootDirPath, filepath.FromSlash(header.Name))
+		if path != rootDirPath && !strings.HasPrefix(path, rootDirPath+fileSeparatorString) {
+			return fmt.Errorf("%w: %q", ErrIllegalPath, header.Name)
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		isDir := header.Typeflag == tar.TypeDir ||
+			strings.HasSuffix(header.Name, "/") ||
+			strings.HasSuffix(header.Name, fileSeparatorString)
+		if isDir {
+			if err := os.MkdirAll(path, mode|0700); err != nil {
+				return err
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := extractFile(tr, path, mode); err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, path string, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, r); err != nil {
+		return err
+	}
+	return file.Close()
+}
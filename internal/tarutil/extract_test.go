@@ -0,0 +1,40 @@
+package tarutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Dir(&buf, "../../test/tarutil/dirtest"))
+
+	destDir := t.TempDir()
+	require.NoError(t, Extract(&buf, destDir))
+
+	for _, name := range []string{"bar/moo", "somedir/.hidden", "somefile.txt"} {
+		want, err := os.ReadFile(filepath.Join("../../test/tarutil/dirtest", name))
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(destDir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestExtract_illegalPath(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: 1}))
+	_, err := tw.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	err = Extract(&buf, t.TempDir())
+	assert.ErrorIs(t, err, ErrIllegalPath)
+}
@@ -11,7 +11,12 @@ import (
 	"github.com/iver-wharf/wharf-api-client-go/v2/pkg/wharfapi"
 	"github.com/iver-wharf/wharf-cmd/internal/parallel"
 	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/typ.v4/sync2"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -25,7 +30,10 @@ import (
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
-var log = logger.NewScoped("AGGREGATOR")
+var (
+	log    = logger.NewScoped("AGGREGATOR")
+	tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/pkg/aggregator")
+)
 
 // NewK8sAggregator returns a new Aggregator implementation that targets
 // Kubernetes using a specific Kubernetes namespace and REST config.
@@ -99,6 +107,8 @@ func (a k8sAggr) Serve(ctx context.Context) error {
 	// a worker while its server wasn't running.
 	k8sruntime.ErrorHandlers = []func(error){}
 
+	metrics.ListenAndServe(a.aggrConfig.MetricsBindAddress)
+
 	for {
 		// TODO: Wait for Wharf API to be up first, with sane infinite retry logic.
 		//
@@ -181,8 +191,15 @@ func (a k8sAggr) handleRunningPodSlice(ctx context.Context, pods []workerPod) {
 	}
 }
 
-func (a k8sAggr) handleRunningPod(ctx context.Context, pod workerPod) error {
+func (a k8sAggr) handleRunningPod(ctx context.Context, pod workerPod) (finalErr error) {
 	defer a.inProgress.Remove(pod.UID)
+	workersInProgress.Inc()
+	defer workersInProgress.Dec()
+	ctx, span := startPodSpan(ctx, "relay running worker", pod)
+	defer func() {
+		tracing.RecordError(span, finalErr)
+		span.End()
+	}()
 
 	worker, err := newPortForwardedWorker(a, pod.Name, pod.buildID)
 	if err != nil && pod.Status.Phase == v1.PodRunning {
@@ -198,27 +215,27 @@ func (a k8sAggr) handleRunningPod(ctx context.Context, pod workerPod) error {
 	}
 
 	pg := parallel.Group{}
-	pg.AddFunc("logs", func(ctx context.Context) error {
+	pg.AddFunc("logs", tracePipeFunc("pipe logs", func(ctx context.Context) error {
 		logsPiper, err := newLogsPiper(ctx, a.wharfapi, worker, pod.buildID)
 		if err != nil {
 			return err
 		}
-		return pipeAndClose(logsPiper)
-	})
-	pg.AddFunc("status events", func(ctx context.Context) error {
+		return pipeAndClose(piperLogs, logsPiper)
+	}))
+	pg.AddFunc("status events", tracePipeFunc("pipe status events", func(ctx context.Context) error {
 		statusEventsPiper, err := newStatusEventsPiper(ctx, a.wharfapi, worker)
 		if err != nil {
 			return err
 		}
-		return pipeAndClose(&statusEventsPiper)
-	})
-	pg.AddFunc("artifact events", func(ctx context.Context) error {
+		return pipeAndClose(piperStatusEvents, &statusEventsPiper)
+	}))
+	pg.AddFunc("artifact events", tracePipeFunc("pipe artifact events", func(ctx context.Context) error {
 		artifactEventsPiper, err := newArtifactEventsPiper(ctx, a.wharfapi, worker)
 		if err != nil {
 			return err
 		}
-		return pipeAndClose(artifactEventsPiper)
-	})
+		return pipeAndClose(piperArtifactEvents, artifactEventsPiper)
+	}))
 	if err := pg.RunCancelEarly(ctx); err != nil {
 		return err
 	}
@@ -238,8 +255,15 @@ func (a k8sAggr) handleFailedPodSlice(ctx context.Context, pods []workerPod) {
 	}
 }
 
-func (a k8sAggr) handleFailedPod(ctx context.Context, pod workerPod) error {
+func (a k8sAggr) handleFailedPod(ctx context.Context, pod workerPod) (finalErr error) {
 	defer a.inProgress.Remove(pod.UID)
+	workersInProgress.Inc()
+	defer workersInProgress.Dec()
+	ctx, span := startPodSpan(ctx, "relay failed worker", pod)
+	defer func() {
+		tracing.RecordError(span, finalErr)
+		span.End()
+	}()
 
 	logsWriter, err := newLogsWriter(ctx, a.wharfapi, pod.buildID)
 	if err != nil {
@@ -336,14 +360,36 @@ func (a k8sAggr) terminatePod(ctx context.Context, pod workerPod) error {
 	return nil
 }
 
-func pipeAndClose(p PipeCloser) error {
+// startPodSpan starts a span that continues the trace of the provisioner that
+// created the worker pod, which is read from the pod's annotations.
+func startPodSpan(ctx context.Context, name string, pod workerPod) (context.Context, trace.Span) {
+	ctx = tracing.ContextFromAnnotations(ctx, pod.Annotations)
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("k8s.pod.name", pod.Name),
+		attribute.Int64("wharf.build.id", int64(pod.buildID)),
+	))
+}
+
+func tracePipeFunc(name string, f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, span := tracer.Start(ctx, name)
+		defer span.End()
+		err := f(ctx)
+		tracing.RecordError(span, err)
+		return err
+	}
+}
+
+func pipeAndClose(piper string, p PipeCloser) error {
 	defer p.Close()
 	for {
 		if err := p.PipeMessage(); err != nil {
 			if errors.Is(err, io.EOF) {
 				return nil
 			}
+			pipeErrors.WithLabelValues(piper).Inc()
 			return err
 		}
+		pipedMessages.WithLabelValues(piper).Inc()
 	}
 }
@@ -0,0 +1,47 @@
+package aggregator
+
+import (
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsSubsystem = "aggregator"
+
+// Names of the pipers, as used in the "piper" metrics label.
+const (
+	piperLogs           = "logs"
+	piperStatusEvents   = "status_events"
+	piperArtifactEvents = "artifact_events"
+)
+
+var (
+	pipedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "piped_messages_total",
+		Help:      "Number of messages piped from workers to the Wharf API, by piper.",
+	}, []string{"piper"})
+
+	pipeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pipe_errors_total",
+		Help:      "Number of times piping from a worker to the Wharf API failed, by piper.",
+	}, []string{"piper"})
+
+	logPipeLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "log_pipe_lag_seconds",
+		Help:      "Time from when a log line was written in the worker until it was sent to the Wharf API.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 300},
+	})
+
+	workersInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workers_in_progress",
+		Help:      "Number of worker pods whose results are currently being relayed to the Wharf API.",
+	})
+)
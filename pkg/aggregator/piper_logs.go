@@ -47,6 +47,9 @@ func (p logsPiper) PipeMessage() error {
 	if err := p.out.write(msg); err != nil {
 		return fmt.Errorf("write log: %w", err)
 	}
+	if line, ok := msg.(*workerclient.LogLine); ok && line.Timestamp != nil {
+		logPipeLag.Observe(time.Since(line.Timestamp.AsTime()).Seconds())
+	}
 	return nil
 }
 
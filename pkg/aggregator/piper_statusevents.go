@@ -96,7 +96,7 @@ func (p *statusEventsPiper) transformStatus(status v1.Status) (request.BuildStat
 	switch status {
 	case v1.StatusPending, v1.StatusScheduling:
 		return request.BuildScheduling, nil
-	case v1.StatusRunning, v1.StatusInitializing:
+	case v1.StatusRunning, v1.StatusInitializing, v1.StatusWarning:
 		return request.BuildRunning, nil
 	case v1.StatusSuccess:
 		return request.BuildCompleted, nil
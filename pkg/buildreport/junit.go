@@ -0,0 +1,106 @@
+package buildreport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Each stage is written as a test
+// suite, and each step as a test case. Steps that are allowed to fail are
+// written as passed test cases, with their error in the system-err element.
+func WriteJUnit(w io.Writer, report Report) error {
+	suites := junitTestSuites{
+		Name: fmt.Sprintf("wharf build #%d", report.BuildID),
+		Time: formatJUnitSeconds(report.DurationSeconds),
+	}
+	for _, stage := range report.Stages {
+		suite := junitTestSuite{
+			Name: stage.Name,
+			Time: formatJUnitSeconds(stage.DurationSeconds),
+		}
+		for _, step := range stage.Steps {
+			tc := junitTestCase{
+				Name:      step.Name,
+				ClassName: stage.Name,
+				Time:      formatJUnitSeconds(step.DurationSeconds),
+			}
+			switch step.Status {
+			case workermodel.StatusFailed:
+				tc.Failure = &junitMessage{
+					Message: firstLine(step.Error),
+					Type:    step.Type,
+					Text:    step.Error,
+				}
+				suite.Failures++
+			case workermodel.StatusCancelled, workermodel.StatusNone:
+				tc.Skipped = &junitMessage{Message: step.Status.String()}
+				suite.Skipped++
+			case workermodel.StatusWarning:
+				tc.SystemErr = step.Error
+			}
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatJUnitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,72 @@
+package buildreport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var markdownTableEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"\n", " ",
+	"\r", "",
+)
+
+// WriteMarkdown writes the report as a human-readable Markdown summary, with
+// one table per stage.
+func WriteMarkdown(w io.Writer, report Report) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Build #%d: %s\n\n", report.BuildID, report.Status)
+	fmt.Fprintf(bw, "- Duration: %s\n", formatSeconds(report.DurationSeconds))
+	if report.RerunOfBuildID != 0 {
+		fmt.Fprintf(bw, "- Re-run of build: #%d\n", report.RerunOfBuildID)
+	}
+	if g := report.Git; g != nil {
+		if g.Branch != "" {
+			fmt.Fprintf(bw, "- Branch: `%s`\n", g.Branch)
+		}
+		if g.Tag != "" {
+			fmt.Fprintf(bw, "- Tag: `%s`\n", g.Tag)
+		}
+		if g.Commit != "" {
+			fmt.Fprintf(bw, "- Commit: `%s` %s\n", g.Commit, g.CommitSubject)
+		}
+	}
+
+	var failedSteps []Step
+	for _, stage := range report.Stages {
+		fmt.Fprintf(bw, "\n## Stage %s: %s (%s)\n\n", stage.Name, stage.Status,
+			formatSeconds(stage.DurationSeconds))
+		bw.WriteString("| Step | Type | Status | Duration | Artifacts |\n")
+		bw.WriteString("| ---- | ---- | ------ | -------- | --------- |\n")
+		for _, step := range stage.Steps {
+			var artifactNames []string
+			for _, a := range step.Artifacts {
+				artifactNames = append(artifactNames, a.Name)
+			}
+			fmt.Fprintf(bw, "| %s | %s | %s | %s | %s |\n",
+				markdownTableEscaper.Replace(step.Name),
+				markdownTableEscaper.Replace(step.Type),
+				step.Status,
+				formatSeconds(step.DurationSeconds),
+				markdownTableEscaper.Replace(strings.Join(artifactNames, ", ")))
+			if step.Error != "" {
+				failedSteps = append(failedSteps, step)
+			}
+		}
+	}
+
+	if len(failedSteps) > 0 {
+		bw.WriteString("\n## Errors\n")
+		for _, step := range failedSteps {
+			fmt.Fprintf(bw, "\n### %s\n\n```\n%s\n```\n", step.Name, step.Error)
+		}
+	}
+	return bw.Flush()
+}
+
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Truncate(time.Millisecond).String()
+}
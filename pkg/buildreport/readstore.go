@@ -0,0 +1,235 @@
+package buildreport
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// Summary is a short summary of a build, such as for listing builds.
+type Summary struct {
+	BuildID         uint               `json:"buildId"`
+	RerunOfBuildID  uint               `json:"rerunOfBuildId,omitempty"`
+	Project         string             `json:"project,omitempty"`
+	Status          workermodel.Status `json:"status"`
+	StartedAt       *time.Time         `json:"startedAt,omitempty"`
+	DurationSeconds float64            `json:"durationSeconds"`
+	Stages          int                `json:"stages"`
+	Steps           int                `json:"steps"`
+	Artifacts       int                `json:"artifacts"`
+}
+
+// Summary returns a short summary of the report.
+func (r Report) Summary() Summary {
+	sum := Summary{
+		BuildID:         r.BuildID,
+		RerunOfBuildID:  r.RerunOfBuildID,
+		Project:         r.Project,
+		Status:          r.Status,
+		StartedAt:       r.StartedAt,
+		DurationSeconds: r.DurationSeconds,
+		Stages:          len(r.Stages),
+	}
+	for _, stage := range r.Stages {
+		sum.Steps += len(stage.Steps)
+		for _, step := range stage.Steps {
+			sum.Artifacts += len(step.Artifacts)
+		}
+	}
+	return sum
+}
+
+// unknownStageName is the stage name of steps without metadata.
+const unknownStageName = "unknown"
+
+// ReadStore creates a report from only the content of a result store, such
+// as for a build from a previous run. The statuses and durations are based
+// on the status updates of each step, and a build that was stopped
+// abruptly, such as when force quit, may have steps that are still in a
+// running status.
+//
+// The Git field is never set, as the result store does not contain any info
+// about the repository.
+func ReadStore(store resultstore.Store) (Report, error) {
+	buildMeta, err := store.ReadBuildMeta()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Report{}, err
+	}
+	report := Report{
+		BuildID:        buildMeta.BuildID,
+		RerunOfBuildID: buildMeta.RerunOfBuildID,
+		Project:        buildMeta.Project,
+		Status:         workermodel.StatusNone,
+		Stages:         []Stage{},
+	}
+	stepMetas, err := readStepMetas(store)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var buildSpan timeSpan
+	var stageSpans []timeSpan
+	stageIndex := map[string]int{}
+	for _, meta := range stepMetas {
+		step, span, err := readStoreStep(store, meta)
+		if err != nil {
+			return Report{}, fmt.Errorf("step %s/%s: %w", meta.StageName, meta.StepName, err)
+		}
+		i, ok := stageIndex[meta.StageName]
+		if !ok {
+			i = len(report.Stages)
+			stageIndex[meta.StageName] = i
+			report.Stages = append(report.Stages, Stage{Name: meta.StageName, Steps: []Step{}})
+			stageSpans = append(stageSpans, timeSpan{})
+		}
+		report.Stages[i].Steps = append(report.Stages[i].Steps, step)
+		stageSpans[i].extend(span)
+		buildSpan.extend(span)
+	}
+
+	var stageStatuses []workermodel.Status
+	for i := range report.Stages {
+		stage := &report.Stages[i]
+		var stepStatuses []workermodel.Status
+		for _, step := range stage.Steps {
+			stepStatuses = append(stepStatuses, step.Status)
+		}
+		stage.Status = aggregateStatus(stepStatuses)
+		stage.DurationSeconds = stageSpans[i].duration().Seconds()
+		stageStatuses = append(stageStatuses, stage.Status)
+	}
+	report.Status = aggregateStatus(stageStatuses)
+	report.DurationSeconds = buildSpan.duration().Seconds()
+	if !buildSpan.start.IsZero() {
+		startedAt := buildSpan.start
+		report.StartedAt = &startedAt
+	}
+	return report, nil
+}
+
+// readStepMetas returns the metadata of all steps, sorted by step ID. Steps
+// without metadata, such as from builds from before step metadata was added
+// to the result store, are given placeholder names based on their step IDs.
+func readStepMetas(store resultstore.Store) ([]resultstore.StepMeta, error) {
+	stepIDs, err := store.ListStepIDs()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stepIDs, func(i, j int) bool {
+		return stepIDs[i] < stepIDs[j]
+	})
+	metas := make([]resultstore.StepMeta, 0, len(stepIDs))
+	for _, stepID := range stepIDs {
+		meta, err := store.ReadStepMeta(stepID)
+		if errors.Is(err, fs.ErrNotExist) {
+			meta = resultstore.StepMeta{
+				StageName: unknownStageName,
+				StepName:  fmt.Sprintf("step-%d", stepID),
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("step %d: %w", stepID, err)
+		}
+		meta.StepID = stepID
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+func readStoreStep(store resultstore.Store, meta resultstore.StepMeta) (Step, timeSpan, error) {
+	step := Step{
+		Name:   meta.StepName,
+		Type:   meta.StepType,
+		Status: workermodel.StatusNone,
+	}
+	updates, err := store.ListStatusUpdates(meta.StepID)
+	if err != nil {
+		return Step{}, timeSpan{}, err
+	}
+	var span timeSpan
+	for _, u := range updates {
+		span.extend(timeSpan{u.Timestamp, u.Timestamp})
+	}
+	if len(updates) > 0 {
+		last := updates[len(updates)-1]
+		step.Status = last.Status
+		if last.Termination != nil && last.Status == workermodel.StatusFailed {
+			step.Error = formatTermination(*last.Termination)
+		}
+	}
+	step.DurationSeconds = span.duration().Seconds()
+	step.Artifacts, err = listArtifacts(store, meta.StepID)
+	if err != nil {
+		return Step{}, timeSpan{}, err
+	}
+	return step, span, nil
+}
+
+func formatTermination(t workermodel.Termination) string {
+	msg := fmt.Sprintf("exit code %d", t.ExitCode)
+	if t.Reason != "" {
+		msg += ": " + t.Reason
+	}
+	if t.Message != "" {
+		msg += ": " + t.Message
+	}
+	return msg
+}
+
+// aggregateStatus returns the status of a stage from the statuses of its
+// steps, or of a build from the statuses of its stages, in the same way as
+// the worker.
+func aggregateStatus(statuses []workermodel.Status) workermodel.Status {
+	if len(statuses) == 0 {
+		return workermodel.StatusNone
+	}
+	var anyFailed, anyCancelled, anyRunning bool
+	for _, s := range statuses {
+		switch s {
+		case workermodel.StatusFailed:
+			anyFailed = true
+		case workermodel.StatusCancelled:
+			anyCancelled = true
+		case workermodel.StatusScheduling, workermodel.StatusInitializing,
+			workermodel.StatusRunning:
+			anyRunning = true
+		}
+	}
+	// Failed takes precedence over cancelled, as the worker cancels the
+	// remaining steps of a stage when one of its steps fails.
+	switch {
+	case anyFailed:
+		return workermodel.StatusFailed
+	case anyCancelled:
+		return workermodel.StatusCancelled
+	case anyRunning:
+		return workermodel.StatusRunning
+	default:
+		return workermodel.StatusSuccess
+	}
+}
+
+type timeSpan struct {
+	start time.Time
+	end   time.Time
+}
+
+func (s *timeSpan) extend(other timeSpan) {
+	if other.start.IsZero() {
+		return
+	}
+	if s.start.IsZero() || other.start.Before(s.start) {
+		s.start = other.start
+	}
+	if other.end.After(s.end) {
+		s.end = other.end
+	}
+}
+
+func (s timeSpan) duration() time.Duration {
+	return s.end.Sub(s.start)
+}
@@ -0,0 +1,123 @@
+package buildreport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStore(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	start := time.Date(2022, 6, 28, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetBuildMeta(resultstore.BuildMeta{BuildID: 12, Project: "iver-wharf/wharf-cmd"}))
+	require.NoError(t, store.SetStepMeta(1, resultstore.StepMeta{StageName: "build", StepName: "image", StepType: "docker"}))
+	require.NoError(t, store.SetStepMeta(2, resultstore.StepMeta{StageName: "build", StepName: "lint", StepType: "container"}))
+	require.NoError(t, store.SetStepMeta(3, resultstore.StepMeta{StageName: "deploy", StepName: "helm", StepType: "helm"}))
+	require.NoError(t, store.AddStatusUpdate(1, start, workermodel.StatusRunning))
+	require.NoError(t, store.AddStatusUpdate(1, start.Add(80*time.Second), workermodel.StatusSuccess))
+	require.NoError(t, store.AddStatusUpdate(2, start.Add(time.Second), workermodel.StatusRunning))
+	require.NoError(t, store.AddTerminatedStatusUpdate(2, start.Add(3*time.Second), workermodel.StatusFailed,
+		workermodel.Termination{ExitCode: 1, Reason: "Error"}))
+	require.NoError(t, store.AddStatusUpdate(3, start.Add(90*time.Second), workermodel.StatusCancelled))
+	require.NoError(t, store.AddArtifactEvent(1, workermodel.ArtifactMeta{Name: "image.tar"}))
+
+	report, err := ReadStore(store)
+	require.NoError(t, err)
+	assert.Equal(t, uint(12), report.BuildID)
+	assert.Equal(t, "iver-wharf/wharf-cmd", report.Project)
+	assert.Equal(t, workermodel.StatusFailed, report.Status)
+	require.NotNil(t, report.StartedAt)
+	assert.Equal(t, start, *report.StartedAt)
+	assert.Equal(t, 90.0, report.DurationSeconds)
+
+	require.Len(t, report.Stages, 2)
+	build := report.Stages[0]
+	assert.Equal(t, "build", build.Name)
+	assert.Equal(t, workermodel.StatusFailed, build.Status)
+	assert.Equal(t, 80.0, build.DurationSeconds)
+	require.Len(t, build.Steps, 2)
+	assert.Equal(t, []Artifact{{ArtifactID: 1, Name: "image.tar"}}, build.Steps[0].Artifacts)
+	assert.Equal(t, "exit code 1: Error", build.Steps[1].Error)
+	assert.Equal(t, 2.0, build.Steps[1].DurationSeconds)
+	assert.Equal(t, workermodel.StatusCancelled, report.Stages[1].Status)
+
+	assert.Equal(t, Summary{
+		BuildID:         12,
+		Project:         "iver-wharf/wharf-cmd",
+		Status:          workermodel.StatusFailed,
+		StartedAt:       report.StartedAt,
+		DurationSeconds: 90,
+		Stages:          2,
+		Steps:           3,
+		Artifacts:       1,
+	}, report.Summary())
+}
+
+func TestReadStore_empty(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	report, err := ReadStore(store)
+	require.NoError(t, err)
+	assert.Equal(t, workermodel.StatusNone, report.Status)
+	assert.Nil(t, report.StartedAt)
+	assert.Empty(t, report.Stages)
+}
+
+func TestReadStore_failedStepWithCancelledSiblings(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	now := time.Now()
+	require.NoError(t, store.SetStepMeta(1, resultstore.StepMeta{StageName: "build", StepName: "app"}))
+	require.NoError(t, store.SetStepMeta(2, resultstore.StepMeta{StageName: "build", StepName: "db"}))
+	require.NoError(t, store.SetStepMeta(3, resultstore.StepMeta{StageName: "build", StepName: "lint"}))
+	require.NoError(t, store.AddStatusUpdate(1, now, workermodel.StatusFailed))
+	require.NoError(t, store.AddStatusUpdate(2, now, workermodel.StatusCancelled))
+	require.NoError(t, store.AddStatusUpdate(3, now, workermodel.StatusCancelled))
+
+	report, err := ReadStore(store)
+	require.NoError(t, err)
+	require.Len(t, report.Stages, 1)
+	assert.Equal(t, workermodel.StatusFailed, report.Stages[0].Status)
+	assert.Equal(t, workermodel.StatusFailed, report.Status)
+}
+
+func TestReadStore_stepsWithoutMeta(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	now := time.Now()
+	require.NoError(t, store.AddStatusUpdate(1, now, workermodel.StatusSuccess))
+	require.NoError(t, store.AddStatusUpdate(2, now, workermodel.StatusFailed))
+
+	report, err := ReadStore(store)
+	require.NoError(t, err)
+	assert.Equal(t, workermodel.StatusFailed, report.Status)
+	require.Len(t, report.Stages, 1)
+	stage := report.Stages[0]
+	assert.Equal(t, "unknown", stage.Name)
+	require.Len(t, stage.Steps, 2)
+	assert.Equal(t, "step-1", stage.Steps[0].Name)
+	assert.Equal(t, workermodel.StatusSuccess, stage.Steps[0].Status)
+	assert.Equal(t, "step-2", stage.Steps[1].Name)
+	assert.Equal(t, workermodel.StatusFailed, stage.Steps[1].Status)
+}
+
+func TestAggregateStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []workermodel.Status
+		want     workermodel.Status
+	}{
+		{"empty", nil, workermodel.StatusNone},
+		{"success", []workermodel.Status{workermodel.StatusSuccess, workermodel.StatusWarning}, workermodel.StatusSuccess},
+		{"failed", []workermodel.Status{workermodel.StatusSuccess, workermodel.StatusFailed}, workermodel.StatusFailed},
+		{"failed and cancelled", []workermodel.Status{workermodel.StatusFailed, workermodel.StatusCancelled}, workermodel.StatusFailed},
+		{"cancelled", []workermodel.Status{workermodel.StatusSuccess, workermodel.StatusCancelled}, workermodel.StatusCancelled},
+		{"running", []workermodel.Status{workermodel.StatusSuccess, workermodel.StatusScheduling}, workermodel.StatusRunning},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, aggregateStatus(tc.statuses))
+		})
+	}
+}
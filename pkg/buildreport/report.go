@@ -0,0 +1,177 @@
+// Package buildreport contains machine-readable reports of the results of a
+// build, such as JSON, JUnit XML, and Markdown.
+package buildreport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/gitutil"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// Format is an enum of report formats.
+type Format string
+
+const (
+	// FormatJSON is a JSON serialized Report.
+	FormatJSON Format = "json"
+	// FormatJUnit is a JUnit XML report, where each stage is a test suite and
+	// each step is a test case.
+	FormatJUnit Format = "junit"
+	// FormatMarkdown is a human-readable Markdown summary.
+	FormatMarkdown Format = "markdown"
+)
+
+// Report is the full result tree of a build.
+type Report struct {
+	BuildID         uint               `json:"buildId"`
+	RerunOfBuildID  uint               `json:"rerunOfBuildId,omitempty"`
+	Project         string             `json:"project,omitempty"`
+	Status          workermodel.Status `json:"status"`
+	StartedAt       *time.Time         `json:"startedAt,omitempty"`
+	DurationSeconds float64            `json:"durationSeconds"`
+	Git             *Git               `json:"git,omitempty"`
+	Stages          []Stage            `json:"stages"`
+}
+
+// Git contains info about the Git repository that was built.
+type Git struct {
+	Branch        string `json:"branch,omitempty"`
+	Tag           string `json:"tag,omitempty"`
+	Commit        string `json:"commit,omitempty"`
+	CommitSubject string `json:"commitSubject,omitempty"`
+	Revision      int    `json:"revision"`
+}
+
+// Stage is the result of a single stage in a build.
+type Stage struct {
+	Name            string             `json:"name"`
+	Status          workermodel.Status `json:"status"`
+	DurationSeconds float64            `json:"durationSeconds"`
+	Steps           []Step             `json:"steps"`
+}
+
+// Step is the result of a single step in a build.
+type Step struct {
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`
+	Status          workermodel.Status `json:"status"`
+	DurationSeconds float64            `json:"durationSeconds"`
+	Error           string             `json:"error,omitempty"`
+	Artifacts       []Artifact         `json:"artifacts,omitempty"`
+}
+
+// Artifact is metadata about an artifact created by a step.
+type Artifact struct {
+	ArtifactID uint64 `json:"artifactId"`
+	Name       string `json:"name"`
+}
+
+// New creates a new report from the result of a build. The result store is
+// used to add the build ID and each step's artifacts to the report.
+func New(res worker.Result, store resultstore.Store) (Report, error) {
+	report := Report{
+		Status:          res.Status,
+		DurationSeconds: res.Duration.Seconds(),
+		Stages:          make([]Stage, 0, len(res.Stages)),
+	}
+	buildMeta, err := store.ReadBuildMeta()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Report{}, err
+	}
+	report.BuildID = buildMeta.BuildID
+	report.RerunOfBuildID = buildMeta.RerunOfBuildID
+	report.Project = buildMeta.Project
+
+	stepMetas, err := store.ListStepMetas()
+	if err != nil {
+		return Report{}, err
+	}
+	stepIDs := make(map[string]uint64, len(stepMetas))
+	for _, meta := range stepMetas {
+		stepIDs[meta.StageName+"/"+meta.StepName] = meta.StepID
+	}
+
+	for _, stageRes := range res.Stages {
+		stage := Stage{
+			Name:            stageRes.Name,
+			Status:          stageRes.Status,
+			DurationSeconds: stageRes.Duration.Seconds(),
+			Steps:           make([]Step, 0, len(stageRes.Steps)),
+		}
+		for _, stepRes := range stageRes.Steps {
+			step := Step{
+				Name:            stepRes.Name,
+				Type:            stepRes.Type,
+				Status:          stepRes.Status,
+				DurationSeconds: stepRes.Duration.Seconds(),
+			}
+			if stepRes.Error != nil {
+				step.Error = stepRes.Error.Error()
+			}
+			if stepID, ok := stepIDs[stageRes.Name+"/"+stepRes.Name]; ok {
+				step.Artifacts, err = listArtifacts(store, stepID)
+				if err != nil {
+					return Report{}, fmt.Errorf("step %s/%s: %w", stageRes.Name, stepRes.Name, err)
+				}
+			}
+			stage.Steps = append(stage.Steps, step)
+		}
+		report.Stages = append(report.Stages, stage)
+	}
+	return report, nil
+}
+
+func listArtifacts(store resultstore.Store, stepID uint64) ([]Artifact, error) {
+	events, err := store.ListArtifactEvents(stepID)
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []Artifact
+	for _, ev := range events {
+		artifacts = append(artifacts, Artifact{
+			ArtifactID: ev.ArtifactID,
+			Name:       ev.Name,
+		})
+	}
+	return artifacts, nil
+}
+
+// NewGit creates the Git info of a report from the Git repository stats.
+func NewGit(stats gitutil.Stats) *Git {
+	return &Git{
+		Branch:        stats.CurrentBranch,
+		Tag:           stats.LatestTag,
+		Commit:        stats.CommitHash,
+		CommitSubject: stats.CommitSubject,
+		Revision:      stats.Revision,
+	}
+}
+
+// Write writes the report to the writer in the given format.
+func Write(w io.Writer, format Format, report Report) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, report)
+	case FormatJUnit:
+		return WriteJUnit(w, report)
+	case FormatMarkdown:
+		return WriteMarkdown(w, report)
+	default:
+		return fmt.Errorf("unknown report format: %q", format)
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func WriteJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,134 @@
+package buildreport
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReport(t *testing.T) Report {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	require.NoError(t, store.SetBuildMeta(resultstore.BuildMeta{BuildID: 12}))
+	require.NoError(t, store.SetStepMeta(1, resultstore.StepMeta{StageName: "build", StepName: "image", StepType: "docker"}))
+	require.NoError(t, store.SetStepMeta(2, resultstore.StepMeta{StageName: "build", StepName: "lint", StepType: "container"}))
+	require.NoError(t, store.AddArtifactEvent(1, workermodel.ArtifactMeta{Name: "image.tar"}))
+
+	res := worker.Result{
+		Status:   workermodel.StatusFailed,
+		Duration: 90 * time.Second,
+		Stages: []worker.StageResult{
+			{
+				Name:     "build",
+				Status:   workermodel.StatusFailed,
+				Duration: 80 * time.Second,
+				Steps: []worker.StepResult{
+					{Name: "image", Type: "docker", Status: workermodel.StatusSuccess, Duration: 80 * time.Second},
+					{Name: "lint", Type: "container", Status: workermodel.StatusFailed, Duration: 2500 * time.Millisecond, Error: errors.New("exit code 1")},
+				},
+			},
+		},
+	}
+	report, err := New(res, store)
+	require.NoError(t, err)
+	return report
+}
+
+func TestNew(t *testing.T) {
+	report := newTestReport(t)
+	assert.Equal(t, uint(12), report.BuildID)
+	assert.Equal(t, workermodel.StatusFailed, report.Status)
+	require.Len(t, report.Stages, 1)
+	require.Len(t, report.Stages[0].Steps, 2)
+	image := report.Stages[0].Steps[0]
+	assert.Equal(t, []Artifact{{ArtifactID: 1, Name: "image.tar"}}, image.Artifacts)
+	lint := report.Stages[0].Steps[1]
+	assert.Equal(t, "exit code 1", lint.Error)
+	assert.Equal(t, 2.5, lint.DurationSeconds)
+}
+
+func TestWriteJSON(t *testing.T) {
+	report := newTestReport(t)
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, report))
+	want := `{
+  "buildId": 12,
+  "status": "Failed",
+  "durationSeconds": 90,
+  "stages": [
+    {
+      "name": "build",
+      "status": "Failed",
+      "durationSeconds": 80,
+      "steps": [
+        {
+          "name": "image",
+          "type": "docker",
+          "status": "Success",
+          "durationSeconds": 80,
+          "artifacts": [{"artifactId": 1, "name": "image.tar"}]
+        },
+        {
+          "name": "lint",
+          "type": "container",
+          "status": "Failed",
+          "durationSeconds": 2.5,
+          "error": "exit code 1"
+        }
+      ]
+    }
+  ]
+}`
+	assert.JSONEq(t, want, buf.String())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	report := newTestReport(t)
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJUnit, report))
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="wharf build #12" tests="2" failures="1" skipped="0" time="90.000">
+  <testsuite name="build" tests="2" failures="1" skipped="0" time="80.000">
+    <testcase name="image" classname="build" time="80.000"></testcase>
+    <testcase name="lint" classname="build" time="2.500">
+      <failure message="exit code 1" type="container">exit code 1</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	report := newTestReport(t)
+	report.Git = &Git{Branch: "main", Commit: "abc123", CommitSubject: "Fix things"}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatMarkdown, report))
+	want := "# Build #12: Failed\n" +
+		"\n" +
+		"- Duration: 1m30s\n" +
+		"- Branch: `main`\n" +
+		"- Commit: `abc123` Fix things\n" +
+		"\n" +
+		"## Stage build: Failed (1m20s)\n" +
+		"\n" +
+		"| Step | Type | Status | Duration | Artifacts |\n" +
+		"| ---- | ---- | ------ | -------- | --------- |\n" +
+		"| image | docker | Success | 1m20s | image.tar |\n" +
+		"| lint | container | Failed | 2.5s |  |\n" +
+		"\n" +
+		"## Errors\n" +
+		"\n" +
+		"### lint\n" +
+		"\n" +
+		"```\n" +
+		"exit code 1\n" +
+		"```\n"
+	assert.Equal(t, want, buf.String())
+}
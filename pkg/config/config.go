@@ -1,13 +1,18 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/iver-wharf/wharf-core/v2/pkg/config"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
 )
 
 // Config holds all configurable settings for wharf-api.
@@ -57,6 +62,16 @@ type Config struct {
 	//
 	// Added in v0.8.0.
 	Watchdog WatchdogConfig
+	// Notifications holds settings for sending notifications about builds,
+	// such as to Slack or Microsoft Teams via webhooks.
+	//
+	// Added in v0.10.0.
+	Notifications NotificationsConfig
+	// Tracing holds settings for exporting OpenTelemetry traces of builds via
+	// OTLP.
+	//
+	// Added in v0.10.0.
+	Tracing TracingConfig
 
 	// InstanceID may be an arbitrary string that is used to identify different
 	// Wharf installations from each other. Needed when you use multiple Wharf
@@ -78,6 +93,137 @@ type K8sConfig struct {
 	//
 	// Added in v0.8.0.
 	Namespace string
+	// BuildNamespace holds settings for running the steps of each build in
+	// a dedicated Kubernetes namespace, instead of in the namespace from the
+	// k8s.namespace setting.
+	//
+	// Added in v0.10.0.
+	BuildNamespace K8sBuildNamespaceConfig
+}
+
+// K8sBuildNamespaceConfig holds settings for the dedicated Kubernetes
+// namespaces that are created per build.
+//
+// The ResourceQuota, LimitRange, and NetworkPolicy settings are given as YAML
+// strings instead of as YAML objects, as config keys are case-insensitive and
+// may not contain dots. Example:
+//
+//  k8s:
+//    buildNamespace:
+//      enabled: true
+//      labels:
+//        - example.com/team=my-team
+//      resourceQuota: |
+//        hard:
+//          requests.cpu: "4"
+//          requests.memory: 8Gi
+//      limitRange: |
+//        limits:
+//          - type: Container
+//            defaultRequest:
+//              cpu: 100m
+//              memory: 128Mi
+type K8sBuildNamespaceConfig struct {
+	// Enabled makes the worker create a new namespace for each build, run
+	// all step pods inside it, and then delete the namespace when the build
+	// finishes or is cancelled. The worker needs permission to create and
+	// delete namespaces, as well as to create ResourceQuotas, LimitRanges,
+	// and NetworkPolicies.
+	//
+	// Added in v0.10.0.
+	Enabled bool
+	// NamePrefix is prepended to the namespace names, which are followed by
+	// the build ID and a random suffix.
+	//
+	// Added in v0.10.0.
+	NamePrefix string
+	// Labels are extra labels to add to the namespace, on the format
+	// "key=value".
+	//
+	// Added in v0.10.0.
+	Labels []string
+	// ResourceQuota is the spec of a Kubernetes ResourceQuota, as a YAML
+	// string, to create in the namespace. No ResourceQuota is created if this
+	// is empty.
+	//
+	// Added in v0.10.0.
+	ResourceQuota string
+	// LimitRange is the spec of a Kubernetes LimitRange, as a YAML string, to
+	// create in the namespace. No LimitRange is created if this is empty.
+	//
+	// Added in v0.10.0.
+	LimitRange string
+	// NetworkPolicy is the spec of a Kubernetes NetworkPolicy, as a YAML
+	// string, to create in the namespace. Defaults to denying all incoming
+	// traffic to the step pods.
+	//
+	// Outgoing traffic is left open by default on purpose, as most steps need
+	// it, such as to push images, deploy Helm charts, or resolve DNS. Add
+	// "Egress" to its policyTypes, together with egress rules for what the
+	// steps need, to also deny all other outgoing traffic. No NetworkPolicy
+	// is created if this is empty.
+	//
+	// Added in v0.10.0.
+	NetworkPolicy string
+}
+
+// LabelsMap returns the Labels setting parsed as a map.
+func (c K8sBuildNamespaceConfig) LabelsMap() (map[string]string, error) {
+	labels := make(map[string]string, len(c.Labels))
+	for i, label := range c.Labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("labels[%d]=%s, must be on the format key=value", i, label)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// ResourceQuotaSpec returns the ResourceQuota setting parsed as a Kubernetes
+// ResourceQuota spec, or nil if it is empty.
+func (c K8sBuildNamespaceConfig) ResourceQuotaSpec() (*v1.ResourceQuotaSpec, error) {
+	var spec v1.ResourceQuotaSpec
+	if ok, err := parseYAMLString(c.ResourceQuota, &spec); !ok {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LimitRangeSpec returns the LimitRange setting parsed as a Kubernetes
+// LimitRange spec, or nil if it is empty.
+func (c K8sBuildNamespaceConfig) LimitRangeSpec() (*v1.LimitRangeSpec, error) {
+	var spec v1.LimitRangeSpec
+	if ok, err := parseYAMLString(c.LimitRange, &spec); !ok {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// NetworkPolicySpec returns the NetworkPolicy setting parsed as a Kubernetes
+// NetworkPolicy spec, or nil if it is empty.
+func (c K8sBuildNamespaceConfig) NetworkPolicySpec() (*networkingv1.NetworkPolicySpec, error) {
+	var spec networkingv1.NetworkPolicySpec
+	if ok, err := parseYAMLString(c.NetworkPolicy, &spec); !ok {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (c K8sBuildNamespaceConfig) validate() error {
+	if _, err := c.LabelsMap(); err != nil {
+		return err
+	}
+	if _, err := c.ResourceQuotaSpec(); err != nil {
+		return fmt.Errorf("resourceQuota: %w", err)
+	}
+	if _, err := c.LimitRangeSpec(); err != nil {
+		return fmt.Errorf("limitRange: %w", err)
+	}
+	if _, err := c.NetworkPolicySpec(); err != nil {
+		return fmt.Errorf("networkPolicy: %w", err)
+	}
+	return nil
 }
 
 // WorkerConfig holds settings for the worker.
@@ -86,10 +232,154 @@ type WorkerConfig struct {
 	//
 	// Added in v0.8.0.
 	Steps StepsConfig
+	// MaxParallelSteps is the maximum number of steps that the worker may run
+	// at the same time. Steps above this limit are queued until a previous
+	// step finishes. This limit applies on top of the max-parallel setting of
+	// each stage in the .wharf-ci.yml file. Zero means no limit.
+	//
+	// Added in v0.10.0.
+	MaxParallelSteps int
+	// UnschedulableTimeout is how long a step's pod may be unschedulable in
+	// Kubernetes before the step is failed, such as when no node has enough
+	// resources for it. The timer is reset if the cluster autoscaler triggers
+	// a scale-up. Zero means the step waits indefinitely.
+	//
+	// Added in v0.10.0.
+	UnschedulableTimeout time.Duration
+	// RepoTransfer holds settings for how the repository is transferred into
+	// the pods of steps when running in Kubernetes.
+	//
+	// Added in v0.10.0.
+	RepoTransfer RepoTransferConfig
+	// TarballCache holds settings for the persistent cache of repository
+	// tarballs that is shared between runs.
+	//
+	// Added in v0.10.0.
+	TarballCache TarballCacheConfig
+}
+
+// TarballCacheConfig holds settings for the persistent cache of repository
+// tarballs.
+//
+// Before a step's tarball is created, a hash is computed of the files that
+// would be included. If a tarball with the same hash was created by a
+// previous run, it is reused instead of copying and tar'ing the files again.
+// Tarballs of steps that use variable substitution on their files, such as
+// kubectl and helm steps, are never cached.
+type TarballCacheConfig struct {
+	// Enabled turns the tarball cache on or off.
+	//
+	// Added in v0.10.0.
+	Enabled bool
+	// Dir is the path to the cache directory. Defaults to
+	// "iver-wharf/wharf-cmd/tarstore" inside the user's cache directory,
+	// such as ~/.cache on Linux, if empty.
+	//
+	// Added in v0.10.0.
+	Dir string
+	// MaxSize is the max total size of all tarballs in the cache, using the
+	// same syntax as Kubernetes resource quantities, such as "1Gi" or
+	// "500M". The least recently used tarballs are evicted when the cache
+	// grows larger than this. Zero means no limit.
+	//
+	// Added in v0.10.0.
+	MaxSize string
+}
+
+// MaxSizeBytes parses the MaxSize field into a number of bytes.
+func (c TarballCacheConfig) MaxSizeBytes() (int64, error) {
+	if c.MaxSize == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(c.MaxSize)
+	if err != nil {
+		return 0, err
+	}
+	if q.Sign() < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return q.Value(), nil
 }
 
+// RepoTransferConfig holds settings for transferring the repository into the
+// pods of steps.
+//
+// Before transferring, the worker checks which compression tools are
+// available in the step pod's init container, and which files already exist
+// in the cache directory, if set. Only files that are missing are then sent.
+type RepoTransferConfig struct {
+	// Compression is the list of compression algorithms to use, in order of
+	// preference. The first one that is also available in the step pod's
+	// init container is used, or no compression at all if none of them are.
+	// Valid values are "zstd", "gzip", and "none".
+	//
+	// Added in v0.10.0.
+	Compression []RepoTransferCompression
+	// CacheDir is a path inside the step pod's init container to a
+	// content-addressed cache of repository files, such as a node-local
+	// hostPath volume mounted via the worker.steps.podTemplate setting.
+	// Larger files that already exist in the cache are copied from it instead
+	// of being transferred, and transferred files are added to it. No cache
+	// is used if this is empty.
+	//
+	// Added in v0.10.0.
+	CacheDir string
+}
+
+// RepoTransferCompression is an enum of compression algorithms to use when
+// transferring the repository into step pods.
+type RepoTransferCompression string
+
+const (
+	// RepoTransferCompressionZstd compresses using Zstandard. Requires the
+	// zstd command in the init container.
+	RepoTransferCompressionZstd RepoTransferCompression = "zstd"
+	// RepoTransferCompressionGzip compresses using gzip. Requires the gzip
+	// command in the init container.
+	RepoTransferCompressionGzip RepoTransferCompression = "gzip"
+	// RepoTransferCompressionNone transfers the repository uncompressed.
+	RepoTransferCompressionNone RepoTransferCompression = "none"
+)
+
 // StepsConfig holds settings for the different types of steps.
 type StepsConfig struct {
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied as a strategic merge patch onto the pods of all steps when
+	// running in Kubernetes. It uses the same format as the "template" field
+	// in a Kubernetes Deployment, with "metadata" and "spec" fields.
+	//
+	// It is given as a string instead of as a YAML object, as config keys
+	// are case-insensitive and would otherwise lose their casing. Example:
+	//
+	//  worker:
+	//    steps:
+	//      podTemplate: |
+	//        metadata:
+	//          labels:
+	//            example.com/team: my-team
+	//        spec:
+	//          priorityClassName: ci
+	//          imagePullSecrets:
+	//            - name: my-mirror
+	//          initContainers:
+	//            - name: init
+	//              image: my-mirror.example.com/alpine:3
+	//
+	// Lists of containers, volumes, and image pull secrets are merged by
+	// name, so the above replaces the image of the existing "init" container.
+	// The step type specific pod templates, such as in
+	// worker.steps.docker.podTemplate, are applied after this one.
+	//
+	// Labels and annotations that wharf-cmd uses to identify the step pods
+	// cannot be overridden.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
+	// Container holds settings for the container step type. (Running commands
+	// in a container)
+	//
+	// Added in v0.10.0.
+	Container ContainerStepConfig
 	// Docker holds settings for the docker step type. (Building docker images)
 	//
 	// Added in v0.8.0.
@@ -104,10 +394,26 @@ type StepsConfig struct {
 	//
 	// Added in v0.8.0.
 	Helm HelmStepConfig
+	// HelmPackage holds settings for the helm-package step type. (Packaging
+	// and uploading Helm charts)
+	//
+	// Added in v0.10.0.
+	HelmPackage HelmPackageStepConfig
+	// NuGetPackage holds settings for the nuget-package step type. (Packaging
+	// and uploading NuGet packages)
+	//
+	// Added in v0.10.0.
+	NuGetPackage NuGetPackageStepConfig
+}
 
-	// TODO: Add ContainerStepConfig, see pkg/worker/k8spodtemplating.go
-	// TODO: Add HelmPackageStepConfig, see pkg/worker/k8spodtemplating.go
-	// TODO: Add NuGetPackageStepConfig, see pkg/worker/k8spodtemplating.go
+// ContainerStepConfig holds settings for the container step type.
+type ContainerStepConfig struct {
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all container steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
 }
 
 // DockerStepConfig holds settings for the docker step type.
@@ -120,6 +426,12 @@ type DockerStepConfig struct {
 	//
 	// Added in v0.8.0.
 	ImageTag string
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all docker steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
 }
 
 // KubectlStepConfig holds settings for the kubectl step type.
@@ -132,6 +444,12 @@ type KubectlStepConfig struct {
 	//
 	// Added in v0.8.0.
 	ImageTag string
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all kubectl steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
 }
 
 // HelmStepConfig holds settings for the helm step type.
@@ -144,6 +462,32 @@ type HelmStepConfig struct {
 	//
 	// Added in v0.8.0.
 	Image string
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all helm steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
+}
+
+// HelmPackageStepConfig holds settings for the helm-package step type.
+type HelmPackageStepConfig struct {
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all helm-package steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
+}
+
+// NuGetPackageStepConfig holds settings for the nuget-package step type.
+type NuGetPackageStepConfig struct {
+	// PodTemplate is a Kubernetes Pod template, as a YAML string, that is
+	// applied onto the pods of all nuget-package steps, after the
+	// worker.steps.podTemplate.
+	//
+	// Added in v0.10.0.
+	PodTemplate string
 }
 
 // ProvisionerConfig holds settings for the provisioner.
@@ -299,6 +643,12 @@ type AggregatorConfig struct {
 	//
 	// Added in v0.8.0.
 	WorkerAPIExternalPort int16
+	// MetricsBindAddress is the IP-address and port, separated by a colon, to
+	// serve Prometheus metrics on via the /metrics HTTP path. Metrics are not
+	// served if empty.
+	//
+	// Added in v0.10.0.
+	MetricsBindAddress string
 }
 
 // WatchdogConfig holds settings for the watchdog.
@@ -312,16 +662,122 @@ type WatchdogConfig struct {
 	//
 	// Added in v0.8.0.
 	ProvisionerURL string
+	// MetricsBindAddress is the IP-address and port, separated by a colon, to
+	// serve Prometheus metrics on via the /metrics HTTP path. Metrics are not
+	// served if empty.
+	//
+	// Added in v0.10.0.
+	MetricsBindAddress string
+}
+
+// TracingConfig holds settings for OpenTelemetry tracing.
+type TracingConfig struct {
+	// Enabled turns on exporting spans via OTLP over HTTP, such as for each
+	// build, stage, and step.
+	//
+	// Added in v0.10.0.
+	Enabled bool
+	// Endpoint is the URL of the OTLP/HTTP collector, such as
+	// "http://localhost:4318". The path defaults to "/v1/traces" if omitted.
+	// If empty, the standard OTEL_EXPORTER_OTLP_ENDPOINT environment variable
+	// is used instead.
+	//
+	// Added in v0.10.0.
+	Endpoint string
+	// SampleRatio is the ratio of new traces to sample, between 0 and 1.
+	// Spans with a sampled parent, such as a worker's spans when the
+	// provisioner's span was sampled, are always sampled.
+	//
+	// Added in v0.10.0.
+	SampleRatio float64
 }
 
+// NotificationsConfig holds settings for build notifications.
+type NotificationsConfig struct {
+	// Webhooks is a list of webhooks that are sent an HTTP POST request with
+	// a JSON payload on build events.
+	//
+	// Added in v0.10.0.
+	Webhooks []WebhookConfig
+}
+
+// WebhookConfig holds settings for a single notification webhook.
+type WebhookConfig struct {
+	// Name is an optional name of the webhook, used in logging.
+	//
+	// Added in v0.10.0.
+	Name string
+	// URL is the address that the JSON payloads are sent to.
+	//
+	// Added in v0.10.0.
+	URL string
+	// Format is the format of the JSON payload. Must be one of "generic",
+	// "slack", or "teams". Defaults to "generic".
+	//
+	// Added in v0.10.0.
+	Format WebhookFormat
+	// Events is a list of which events to send. Must be any of
+	// "buildStarted", "stageFinished", "stepFailed", or "buildFinished".
+	// All events are sent if this is empty.
+	//
+	// Added in v0.10.0.
+	Events []NotificationEvent
+	// Secret is an optional key used to sign the payloads using HMAC-SHA256.
+	// The signature is sent as a hex-encoded string in the
+	// X-Wharf-Signature-256 HTTP header, prefixed with "sha256=".
+	//
+	// Added in v0.10.0.
+	Secret string
+	// Timeout is the maximum duration of each request. Defaults to 10 seconds.
+	//
+	// Added in v0.10.0.
+	Timeout time.Duration
+}
+
+// WebhookFormat is the format of a webhook's JSON payload.
+type WebhookFormat string
+
+// Known webhook formats.
+const (
+	WebhookFormatGeneric WebhookFormat = "generic"
+	WebhookFormatSlack   WebhookFormat = "slack"
+	WebhookFormatTeams   WebhookFormat = "teams"
+)
+
+// NotificationEvent is a type of build event that notifications are sent for.
+type NotificationEvent string
+
+// Known notification events.
+const (
+	NotificationEventBuildStarted  NotificationEvent = "buildStarted"
+	NotificationEventStageFinished NotificationEvent = "stageFinished"
+	NotificationEventStepFailed    NotificationEvent = "stepFailed"
+	NotificationEventBuildFinished NotificationEvent = "buildFinished"
+)
+
 // DefaultConfig is the hard-coded default values for wharf-cmd's configs.
 var DefaultConfig = Config{
 	InstanceID: "local",
 	K8s: K8sConfig{
 		Context:   "",
 		Namespace: "",
+		BuildNamespace: K8sBuildNamespaceConfig{
+			NamePrefix:    "wharf-build-",
+			NetworkPolicy: "podSelector: {}\npolicyTypes:\n  - Ingress\n",
+		},
 	},
 	Worker: WorkerConfig{
+		UnschedulableTimeout: 2 * time.Minute,
+		RepoTransfer: RepoTransferConfig{
+			Compression: []RepoTransferCompression{
+				RepoTransferCompressionZstd,
+				RepoTransferCompressionGzip,
+			},
+		},
+		TarballCache: TarballCacheConfig{
+			Enabled: true,
+			MaxSize: "1Gi",
+		},
 		Steps: StepsConfig{
 			Docker: DockerStepConfig{
 				Image:    "gcr.io/kaniko-project/executor",
@@ -363,10 +819,15 @@ var DefaultConfig = Config{
 	Aggregator: AggregatorConfig{
 		WharfAPIURL:           "http://localhost:5001",
 		WorkerAPIExternalPort: 5010,
+		MetricsBindAddress:    "0.0.0.0:5011",
 	},
 	Watchdog: WatchdogConfig{
-		WharfAPIURL:    "http://localhost:5001",
-		ProvisionerURL: "http://localhost:5009",
+		WharfAPIURL:        "http://localhost:5001",
+		ProvisionerURL:     "http://localhost:5009",
+		MetricsBindAddress: "0.0.0.0:5012",
+	},
+	Tracing: TracingConfig{
+		SampleRatio: 1,
 	},
 }
 
@@ -409,9 +870,167 @@ func (c *Config) validate() error {
 	if !ok {
 		return fmt.Errorf("invalid pull policy: provisioner.worker.container.imagePullPolicy=%s", w.Container.ImagePullPolicy)
 	}
+
+	if c.Worker.MaxParallelSteps < 0 {
+		return fmt.Errorf("invalid max parallel steps: worker.maxParallelSteps=%d, must not be negative", c.Worker.MaxParallelSteps)
+	}
+
+	for i, compression := range c.Worker.RepoTransfer.Compression {
+		parsed, ok := parseRepoTransferCompression(compression)
+		if !ok {
+			return fmt.Errorf("invalid compression: worker.repoTransfer.compression[%d]=%s, must be one of: zstd, gzip, none", i, compression)
+		}
+		c.Worker.RepoTransfer.Compression[i] = parsed
+	}
+
+	if _, err := c.Worker.TarballCache.MaxSizeBytes(); err != nil {
+		return fmt.Errorf("invalid max size: worker.tarballCache.maxSize=%s: %w", c.Worker.TarballCache.MaxSize, err)
+	}
+
+	if err := c.K8s.BuildNamespace.validate(); err != nil {
+		return fmt.Errorf("invalid build namespace: k8s.buildNamespace.%w", err)
+	}
+
+	for _, tmpl := range c.Worker.Steps.podTemplates() {
+		if _, err := ParsePodTemplate(tmpl.value); err != nil {
+			return fmt.Errorf("invalid pod template: %s: %w", tmpl.key, err)
+		}
+	}
+
+	for i := range c.Notifications.Webhooks {
+		if err := c.Notifications.Webhooks[i].validate(); err != nil {
+			return fmt.Errorf("invalid webhook: notifications.webhooks[%d]: %w", i, err)
+		}
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("invalid sample ratio: tracing.sampleRatio=%g, must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+	return nil
+}
+
+// PodTemplateForStepType returns the pod template specific to a step type,
+// such as "docker" or "helm-package". Returns an empty string if there is
+// none.
+func (c StepsConfig) PodTemplateForStepType(stepType string) string {
+	switch stepType {
+	case "container":
+		return c.Container.PodTemplate
+	case "docker":
+		return c.Docker.PodTemplate
+	case "kubectl":
+		return c.Kubectl.PodTemplate
+	case "helm":
+		return c.Helm.PodTemplate
+	case "helm-package":
+		return c.HelmPackage.PodTemplate
+	case "nuget-package":
+		return c.NuGetPackage.PodTemplate
+	default:
+		return ""
+	}
+}
+
+type keyedPodTemplate struct {
+	key   string
+	value string
+}
+
+func (c StepsConfig) podTemplates() []keyedPodTemplate {
+	return []keyedPodTemplate{
+		{"worker.steps.podTemplate", c.PodTemplate},
+		{"worker.steps.container.podTemplate", c.Container.PodTemplate},
+		{"worker.steps.docker.podTemplate", c.Docker.PodTemplate},
+		{"worker.steps.kubectl.podTemplate", c.Kubectl.PodTemplate},
+		{"worker.steps.helm.podTemplate", c.Helm.PodTemplate},
+		{"worker.steps.helmPackage.podTemplate", c.HelmPackage.PodTemplate},
+		{"worker.steps.nugetPackage.podTemplate", c.NuGetPackage.PodTemplate},
+	}
+}
+
+// ParsePodTemplate parses a Kubernetes Pod template from a YAML string, as
+// used in the worker.steps.podTemplate setting. Unknown fields are reported
+// as errors, to catch typos. An empty string results in an empty template.
+func ParsePodTemplate(tmpl string) (v1.PodTemplateSpec, error) {
+	var spec v1.PodTemplateSpec
+	if _, err := parseYAMLString(tmpl, &spec); err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+	return spec, nil
+}
+
+// parseYAMLString unmarshals a YAML string from the config, where unknown
+// fields are reported as errors to catch typos. Returns false if the string
+// was empty or if it failed to parse.
+func parseYAMLString(s string, v any) (bool, error) {
+	if strings.TrimSpace(s) == "" {
+		return false, nil
+	}
+	if err := yaml.UnmarshalStrict([]byte(s), v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (w *WebhookConfig) validate() error {
+	if w.URL == "" {
+		return errors.New("url must not be empty")
+	}
+	format, ok := parseWebhookFormat(w.Format)
+	if !ok {
+		return fmt.Errorf("format=%s, must be one of: generic, slack, teams", w.Format)
+	}
+	w.Format = format
+	for i, event := range w.Events {
+		parsed, ok := parseNotificationEvent(event)
+		if !ok {
+			return fmt.Errorf("events[%d]=%s, must be one of: buildStarted, stageFinished, stepFailed, buildFinished", i, event)
+		}
+		w.Events[i] = parsed
+	}
 	return nil
 }
 
+func parseRepoTransferCompression(c RepoTransferCompression) (RepoTransferCompression, bool) {
+	switch strings.ToLower(string(c)) {
+	case "zstd":
+		return RepoTransferCompressionZstd, true
+	case "gzip":
+		return RepoTransferCompressionGzip, true
+	case "none":
+		return RepoTransferCompressionNone, true
+	default:
+		return RepoTransferCompression(""), false
+	}
+}
+
+func parseWebhookFormat(f WebhookFormat) (WebhookFormat, bool) {
+	switch strings.ToLower(string(f)) {
+	case "", "generic":
+		return WebhookFormatGeneric, true
+	case "slack":
+		return WebhookFormatSlack, true
+	case "teams":
+		return WebhookFormatTeams, true
+	default:
+		return WebhookFormat(""), false
+	}
+}
+
+func parseNotificationEvent(e NotificationEvent) (NotificationEvent, bool) {
+	for _, known := range []NotificationEvent{
+		NotificationEventBuildStarted,
+		NotificationEventStageFinished,
+		NotificationEventStepFailed,
+		NotificationEventBuildFinished,
+	} {
+		if strings.EqualFold(string(e), string(known)) {
+			return known, true
+		}
+	}
+	return NotificationEvent(""), false
+}
+
 func parseImagePolicy(p v1.PullPolicy) (v1.PullPolicy, bool) {
 	switch strings.ToLower(string(p)) {
 	case "always":
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -135,3 +136,112 @@ func TestParsePullPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateWebhooks(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.Notifications.Webhooks = []WebhookConfig{
+		{URL: "http://localhost/hook"},
+		{URL: "http://localhost/slack", Format: "Slack", Events: []NotificationEvent{"stepfailed"}},
+	}
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, WebhookFormatGeneric, cfg.Notifications.Webhooks[0].Format)
+	assert.Equal(t, WebhookFormatSlack, cfg.Notifications.Webhooks[1].Format)
+	assert.Equal(t, []NotificationEvent{NotificationEventStepFailed}, cfg.Notifications.Webhooks[1].Events)
+
+	cfg.Notifications.Webhooks = []WebhookConfig{{URL: "http://localhost", Format: "discord"}}
+	assert.EqualError(t, cfg.validate(), "invalid webhook: notifications.webhooks[0]: format=discord, must be one of: generic, slack, teams")
+
+	cfg.Notifications.Webhooks = []WebhookConfig{{URL: "http://localhost", Events: []NotificationEvent{"stepStarted"}}}
+	assert.Error(t, cfg.validate())
+
+	cfg.Notifications.Webhooks = []WebhookConfig{{}}
+	assert.Error(t, cfg.validate())
+}
+
+func TestValidateTracingSampleRatio(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.Tracing.SampleRatio = 0.25
+	require.NoError(t, cfg.validate())
+
+	cfg.Tracing.SampleRatio = 1.5
+	assert.EqualError(t, cfg.validate(), "invalid sample ratio: tracing.sampleRatio=1.5, must be between 0 and 1")
+}
+
+func TestValidatePodTemplates(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.Worker.Steps.PodTemplate = `
+spec:
+  priorityClassName: ci
+`
+	cfg.Worker.Steps.Docker.PodTemplate = `
+metadata:
+  labels:
+    example.com/team: my-team
+`
+	require.NoError(t, cfg.validate())
+
+	cfg.Worker.Steps.Docker.PodTemplate = `
+spec:
+  priorityClass: ci
+`
+	err := cfg.validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pod template: worker.steps.docker.podTemplate:")
+}
+
+func TestStepsConfigPodTemplateForStepType(t *testing.T) {
+	steps := StepsConfig{
+		Container:    ContainerStepConfig{PodTemplate: "container"},
+		HelmPackage:  HelmPackageStepConfig{PodTemplate: "helm-package"},
+		NuGetPackage: NuGetPackageStepConfig{PodTemplate: "nuget-package"},
+	}
+	assert.Equal(t, "container", steps.PodTemplateForStepType("container"))
+	assert.Equal(t, "helm-package", steps.PodTemplateForStepType("helm-package"))
+	assert.Equal(t, "nuget-package", steps.PodTemplateForStepType("nuget-package"))
+	assert.Equal(t, "", steps.PodTemplateForStepType("docker"))
+	assert.Equal(t, "", steps.PodTemplateForStepType("unknown"))
+}
+
+func TestValidateBuildNamespace(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.K8s.BuildNamespace = DefaultConfig.K8s.BuildNamespace
+	cfg.K8s.BuildNamespace.Labels = []string{"example.com/team=my-team", "empty="}
+	require.NoError(t, cfg.validate())
+	labels, err := cfg.K8s.BuildNamespace.LabelsMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"example.com/team": "my-team", "empty": ""}, labels)
+
+	cfg.K8s.BuildNamespace.Labels = []string{"no-value"}
+	assert.EqualError(t, cfg.validate(), "invalid build namespace: k8s.buildNamespace.labels[0]=no-value, must be on the format key=value")
+
+	cfg.K8s.BuildNamespace.Labels = nil
+	cfg.K8s.BuildNamespace.ResourceQuota = "hrad:\n  pods: 10\n"
+	err = cfg.validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid build namespace: k8s.buildNamespace.resourceQuota:")
+}
+
+func TestValidateRepoTransferCompression(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.Worker.RepoTransfer.Compression = []RepoTransferCompression{"ZSTD", "none"}
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, []RepoTransferCompression{RepoTransferCompressionZstd, RepoTransferCompressionNone}, cfg.Worker.RepoTransfer.Compression)
+
+	cfg.Worker.RepoTransfer.Compression = []RepoTransferCompression{"gzip", "brotli"}
+	assert.EqualError(t, cfg.validate(), "invalid compression: worker.repoTransfer.compression[1]=brotli, must be one of: zstd, gzip, none")
+}
+
+func TestValidateTarballCacheMaxSize(t *testing.T) {
+	cfg := newConfigWithPullPolicies("always", "always")
+	cfg.Worker.TarballCache.MaxSize = "1Gi"
+	require.NoError(t, cfg.validate())
+	size, err := cfg.Worker.TarballCache.MaxSizeBytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), size)
+
+	cfg.Worker.TarballCache.MaxSize = "-1"
+	assert.EqualError(t, cfg.validate(), "invalid max size: worker.tarballCache.maxSize=-1: must not be negative")
+
+	cfg.Worker.TarballCache.MaxSize = "lots"
+	assert.Error(t, cfg.validate())
+}
@@ -0,0 +1,319 @@
+// Package dockerengine contains a minimal client for the Docker Engine HTTP
+// API, only covering what is needed to run build steps as containers. It only
+// uses endpoints that are also provided by Podman's Docker-compatible API.
+//
+// Docs: https://docs.docker.com/engine/api/latest/
+package dockerengine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultDockerHost is the default Docker Engine API address on Linux.
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+	// DefaultPodmanHost is the default rootful Podman API address on Linux.
+	DefaultPodmanHost = "unix:///run/podman/podman.sock"
+
+	unixSocketBaseURL = "http://docker"
+)
+
+// ErrUnsupportedHost is returned by NewClient when the host URL scheme is not
+// supported.
+var ErrUnsupportedHost = errors.New("unsupported engine host")
+
+// Error is an error response from the engine API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("engine API: %d %s: %s", e.StatusCode,
+		http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound returns true if the error is a 404 Not Found engine API error.
+func IsNotFound(err error) bool {
+	var engineErr *Error
+	return errors.As(err, &engineErr) && engineErr.StatusCode == http.StatusNotFound
+}
+
+// Client is a Docker Engine API client.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a new client for the engine API at the given host. The
+// host can either be a unix socket address such as
+// "unix:///var/run/docker.sock", or an HTTP address such as "tcp://host:2375"
+// or "http://host:2375".
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parse engine host: %w", err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socketPath := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		return NewClientWithHTTP(unixSocketBaseURL, &http.Client{Transport: transport}), nil
+	case "tcp", "http":
+		return NewClientWithHTTP("http://"+u.Host, http.DefaultClient), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedHost, host)
+	}
+}
+
+// NewClientWithHTTP creates a new client that sends requests to the given base
+// URL using the given HTTP client.
+func NewClientWithHTTP(baseURL string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    httpClient,
+	}
+}
+
+// DefaultPodmanHostForUser returns the Podman API address of the current
+// user when running Podman rootless, falling back to DefaultPodmanHost.
+func DefaultPodmanHostForUser() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		socketPath := filepath.Join(runtimeDir, "podman", "podman.sock")
+		if _, err := os.Stat(socketPath); err == nil {
+			return "unix://" + socketPath
+		}
+	}
+	return DefaultPodmanHost
+}
+
+// Ping checks that the engine API is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/_ping", nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ImageExists returns true if the image is already pulled.
+func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, resp.Body.Close()
+}
+
+// PullImage pulls an image, and waits for the pull to complete. The image
+// may contain a tag or digest.
+func (c *Client) PullImage(ctx context.Context, image string) error {
+	query := url.Values{"fromImage": {image}}
+	if !strings.ContainsAny(lastPathElem(image), ":@") {
+		query.Set("tag", "latest")
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The pull progress is streamed as JSON messages, where errors are
+	// reported inside the messages instead of via the HTTP status code.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull image %q: %s", image, msg.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+func lastPathElem(image string) string {
+	if idx := strings.LastIndexByte(image, '/'); idx != -1 {
+		return image[idx+1:]
+	}
+	return image
+}
+
+// CreateVolume creates a new volume. The engine generates a name for the
+// volume if the name is left empty.
+func (c *Client) CreateVolume(ctx context.Context, req VolumeCreateRequest) (Volume, error) {
+	var vol Volume
+	if err := c.doJSON(ctx, http.MethodPost, "/volumes/create", nil, req, &vol); err != nil {
+		return Volume{}, err
+	}
+	return vol, nil
+}
+
+// RemoveVolume forcefully removes a volume.
+func (c *Client) RemoveVolume(ctx context.Context, name string) error {
+	query := url.Values{"force": {"true"}}
+	return c.doJSON(ctx, http.MethodDelete, "/volumes/"+name, query, nil, nil)
+}
+
+// CreateContainer creates a new container, but does not start it.
+func (c *Client) CreateContainer(ctx context.Context, name string, config ContainerConfig) (string, error) {
+	var query url.Values
+	if name != "" {
+		query = url.Values{"name": {name}}
+	}
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/create", query, config, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// PutArchive extracts a tar archive into a directory inside the container.
+// The container does not need to be started.
+func (c *Client) PutArchive(ctx context.Context, containerID, path string, tarball io.Reader) error {
+	query := url.Values{"path": {path}}
+	resp, err := c.do(ctx, http.MethodPut, "/containers/"+containerID+"/archive", query, tarball)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// StartContainer starts a created container.
+func (c *Client) StartContainer(ctx context.Context, containerID string) error {
+	return c.doJSON(ctx, http.MethodPost, "/containers/"+containerID+"/start", nil, nil, nil)
+}
+
+// WaitContainer waits for a container to stop, and returns its exit code.
+func (c *Client) WaitContainer(ctx context.Context, containerID string) (int, error) {
+	query := url.Values{"condition": {"not-running"}}
+	var resp struct {
+		StatusCode int `json:"StatusCode"`
+		Error      *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/"+containerID+"/wait", query, nil, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Error != nil && resp.Error.Message != "" {
+		return resp.StatusCode, errors.New(resp.Error.Message)
+	}
+	return resp.StatusCode, nil
+}
+
+// InspectContainer returns the state of a container.
+func (c *Client) InspectContainer(ctx context.Context, containerID string) (ContainerState, error) {
+	var resp struct {
+		State ContainerState `json:"State"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/containers/"+containerID+"/json", nil, nil, &resp); err != nil {
+		return ContainerState{}, err
+	}
+	return resp.State, nil
+}
+
+// ContainerLogs returns the combined stdout and stderr logs of a container.
+// The logs are multiplexed, and should be read using DemuxLogs, as all
+// containers created by this package are created without a TTY.
+func (c *Client) ContainerLogs(ctx context.Context, containerID string, follow bool) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+		"follow": {fmt.Sprint(follow)},
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+containerID+"/logs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// RemoveContainer forcefully removes a container, even if it is running,
+// including its anonymous volumes.
+func (c *Client) RemoveContainer(ctx context.Context, containerID string) error {
+	query := url.Values{"force": {"true"}, "v": {"true"}}
+	return c.doJSON(ctx, http.MethodDelete, "/containers/"+containerID, query, nil, nil)
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, reqBody, respBody any) error {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if respBody == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		if method == http.MethodPut {
+			req.Header.Set("Content-Type", "application/x-tar")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(b, &errResp); err != nil || errResp.Message == "" {
+			errResp.Message = strings.TrimSpace(string(b))
+		}
+		return nil, &Error{StatusCode: resp.StatusCode, Message: errResp.Message}
+	}
+	return resp, nil
+}
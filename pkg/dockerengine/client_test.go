@@ -0,0 +1,80 @@
+package dockerengine_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine"
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine/dockerenginetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient_unsupportedHost(t *testing.T) {
+	_, err := dockerengine.NewClient("ssh://somewhere")
+	assert.ErrorIs(t, err, dockerengine.ErrUnsupportedHost)
+}
+
+func TestClient_containerLifecycle(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		return "hello\n", "oops\n", 3
+	}
+	client := engine.Client()
+	ctx := context.Background()
+
+	require.NoError(t, client.Ping(ctx))
+	exists, err := client.ImageExists(ctx, "alpine:3")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, client.PullImage(ctx, "alpine:3"))
+	exists, err = client.ImageExists(ctx, "alpine:3")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	id, err := client.CreateContainer(ctx, "my-container", dockerengine.ContainerConfig{Image: "alpine:3"})
+	require.NoError(t, err)
+	require.NoError(t, client.PutArchive(ctx, id, "/mnt/repo", strings.NewReader("tar")))
+	require.NoError(t, client.StartContainer(ctx, id))
+	exitCode, err := client.WaitContainer(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+
+	logs, err := client.ContainerLogs(ctx, id, true)
+	require.NoError(t, err)
+	defer logs.Close()
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, dockerengine.DemuxLogs(logs, &stdout, &stderr))
+	assert.Equal(t, "hello\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+
+	require.NoError(t, client.RemoveContainer(ctx, id))
+	containers := engine.Containers()
+	require.Len(t, containers, 1)
+	assert.Equal(t, "my-container", containers[0].Name)
+	assert.Equal(t, []byte("tar"), containers[0].Archives["/mnt/repo"])
+	assert.True(t, containers[0].Removed)
+}
+
+func TestClient_errorResponse(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	err := engine.Client().StartContainer(context.Background(), "missing")
+	assert.True(t, dockerengine.IsNotFound(err))
+	assert.EqualError(t, err, "engine API: 404 Not Found: No such container: missing")
+}
+
+func TestClient_PullImageStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"Pulling"}` + "\n" + `{"error":"manifest unknown"}` + "\n"))
+	}))
+	defer server.Close()
+	client := dockerengine.NewClientWithHTTP(server.URL, server.Client())
+	err := client.PullImage(context.Background(), "alpine:nope")
+	assert.EqualError(t, err, `pull image "alpine:nope": manifest unknown`)
+}
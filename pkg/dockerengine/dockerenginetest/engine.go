@@ -0,0 +1,246 @@
+// Package dockerenginetest contains a fake in-memory Docker Engine API server,
+// meant to be used in tests of code that uses the dockerengine package.
+package dockerenginetest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine"
+)
+
+// RunFunc is called when a container is started, and returns what the
+// container outputs and its exit code.
+type RunFunc func(c *Container) (stdout, stderr string, exitCode int)
+
+// Container is a container created in the fake engine.
+type Container struct {
+	ID     string
+	Name   string
+	Config dockerengine.ContainerConfig
+	// Archives holds the tarballs uploaded to the container, keyed on the
+	// target path.
+	Archives map[string][]byte
+	Started  bool
+	Removed  bool
+	ExitCode int
+	// OOMKilled can be set by the RunFunc to mark the container as killed
+	// due to running out of memory.
+	OOMKilled bool
+	logs      []byte
+}
+
+// Engine is a fake Docker Engine API server.
+type Engine struct {
+	// RunFunc decides the output and exit code of started containers. By
+	// default, containers exit with code 0 without any output.
+	RunFunc RunFunc
+
+	server *httptest.Server
+
+	mutex         sync.Mutex
+	lastID        int
+	containers    map[string]*Container
+	volumes       map[string]bool
+	images        map[string]bool
+	pulledImages  []string
+	removedVolume []string
+}
+
+// NewEngine starts a new fake engine server. Call Close when done.
+func NewEngine() *Engine {
+	e := &Engine{
+		containers: make(map[string]*Container),
+		volumes:    make(map[string]bool),
+		images:     make(map[string]bool),
+	}
+	e.server = httptest.NewServer(http.HandlerFunc(e.handle))
+	return e
+}
+
+// Client returns a new client that targets this fake engine.
+func (e *Engine) Client() *dockerengine.Client {
+	return dockerengine.NewClientWithHTTP(e.server.URL, e.server.Client())
+}
+
+// Close shuts down the fake engine server.
+func (e *Engine) Close() {
+	e.server.Close()
+}
+
+// AddImage marks an image as already pulled.
+func (e *Engine) AddImage(image string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.images[image] = true
+}
+
+// Containers returns all containers that has been created, in creation order.
+func (e *Engine) Containers() []*Container {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	list := make([]*Container, 0, len(e.containers))
+	for i := 1; i <= e.lastID; i++ {
+		if c, ok := e.containers[fmt.Sprint(i)]; ok {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// PulledImages returns the images that has been pulled, in pull order.
+func (e *Engine) PulledImages() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.pulledImages...)
+}
+
+// Volumes returns the names of all volumes that has not been removed.
+func (e *Engine) Volumes() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var names []string
+	for name, exists := range e.volumes {
+		if exists {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (e *Engine) handle(w http.ResponseWriter, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	path := r.URL.Path
+	switch {
+	case path == "/_ping":
+		io.WriteString(w, "OK")
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/json"):
+		image := strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")
+		if !e.images[image] {
+			writeError(w, http.StatusNotFound, "No such image: "+image)
+			return
+		}
+		io.WriteString(w, "{}")
+	case r.Method == http.MethodPost && path == "/images/create":
+		image := r.URL.Query().Get("fromImage")
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			image += ":" + tag
+		}
+		e.images[image] = true
+		e.pulledImages = append(e.pulledImages, image)
+		io.WriteString(w, `{"status":"Pulling"}`+"\n"+`{"status":"Done"}`+"\n")
+	case r.Method == http.MethodPost && path == "/volumes/create":
+		var req dockerengine.VolumeCreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Name == "" {
+			req.Name = fmt.Sprintf("volume-%d", len(e.volumes)+1)
+		}
+		e.volumes[req.Name] = true
+		writeJSON(w, http.StatusCreated, dockerengine.Volume{Name: req.Name})
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/volumes/"):
+		name := strings.TrimPrefix(path, "/volumes/")
+		if !e.volumes[name] {
+			writeError(w, http.StatusNotFound, "no such volume")
+			return
+		}
+		e.volumes[name] = false
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var config dockerengine.ContainerConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !e.images[config.Image] {
+			writeError(w, http.StatusNotFound, "No such image: "+config.Image)
+			return
+		}
+		e.lastID++
+		c := &Container{
+			ID:       fmt.Sprint(e.lastID),
+			Name:     r.URL.Query().Get("name"),
+			Config:   config,
+			Archives: make(map[string][]byte),
+		}
+		e.containers[c.ID] = c
+		writeJSON(w, http.StatusCreated, map[string]string{"Id": c.ID})
+	case strings.HasPrefix(path, "/containers/"):
+		e.handleContainer(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func (e *Engine) handleContainer(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")
+	c, ok := e.containers[id]
+	if !ok || c.Removed {
+		writeError(w, http.StatusNotFound, "No such container: "+id)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && action == "archive":
+		b, _ := io.ReadAll(r.Body)
+		c.Archives[r.URL.Query().Get("path")] = b
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && action == "start":
+		if c.Started {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		c.Started = true
+		var stdout, stderr string
+		if e.RunFunc != nil {
+			stdout, stderr, c.ExitCode = e.RunFunc(c)
+		}
+		c.logs = append(muxFrame(dockerengine.StreamStdout, stdout), muxFrame(dockerengine.StreamStderr, stderr)...)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && action == "wait":
+		writeJSON(w, http.StatusOK, map[string]int{"StatusCode": c.ExitCode})
+	case r.Method == http.MethodGet && action == "json":
+		status := "created"
+		if c.Started {
+			status = "exited"
+		}
+		writeJSON(w, http.StatusOK, map[string]dockerengine.ContainerState{
+			"State": {Status: status, ExitCode: c.ExitCode, OOMKilled: c.OOMKilled},
+		})
+	case r.Method == http.MethodGet && action == "logs":
+		w.Write(c.logs)
+	case r.Method == http.MethodDelete && action == "":
+		c.Removed = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func muxFrame(stream dockerengine.Stream, payload string) []byte {
+	if payload == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	header := [8]byte{byte(stream)}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.WriteString(payload)
+	return buf.Bytes()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,111 @@
+package dockerengine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ContainerConfig is the request body when creating a container.
+type ContainerConfig struct {
+	Image        string            `json:"Image"`
+	Entrypoint   []string          `json:"Entrypoint,omitempty"`
+	Cmd          []string          `json:"Cmd,omitempty"`
+	Env          []string          `json:"Env,omitempty"`
+	WorkingDir   string            `json:"WorkingDir,omitempty"`
+	User         string            `json:"User,omitempty"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	Tty          bool              `json:"Tty"`
+	AttachStdout bool              `json:"AttachStdout"`
+	AttachStderr bool              `json:"AttachStderr"`
+	HostConfig   HostConfig        `json:"HostConfig"`
+}
+
+// HostConfig is the host-specific configuration of a container.
+type HostConfig struct {
+	Mounts     []Mount `json:"Mounts,omitempty"`
+	Privileged bool    `json:"Privileged,omitempty"`
+}
+
+// ContainerState is the state of a container, as returned by
+// Client.InspectContainer.
+type ContainerState struct {
+	Status     string    `json:"Status"`
+	ExitCode   int       `json:"ExitCode"`
+	OOMKilled  bool      `json:"OOMKilled"`
+	Error      string    `json:"Error"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+}
+
+// MountType is an enum of mount types.
+type MountType string
+
+const (
+	// MountTypeVolume mounts a named volume into the container.
+	MountTypeVolume MountType = "volume"
+)
+
+// Mount is a mount of a volume into a container.
+type Mount struct {
+	Type     MountType `json:"Type"`
+	Source   string    `json:"Source"`
+	Target   string    `json:"Target"`
+	ReadOnly bool      `json:"ReadOnly,omitempty"`
+}
+
+// VolumeCreateRequest is the request body when creating a volume.
+type VolumeCreateRequest struct {
+	Name   string            `json:"Name,omitempty"`
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+// Volume is a named volume.
+type Volume struct {
+	Name       string `json:"Name"`
+	Mountpoint string `json:"Mountpoint"`
+}
+
+// Stream is an enum of the output streams in multiplexed container logs.
+type Stream byte
+
+const (
+	// StreamStdin is the standard input stream.
+	StreamStdin Stream = 0
+	// StreamStdout is the standard output stream.
+	StreamStdout Stream = 1
+	// StreamStderr is the standard error stream.
+	StreamStderr Stream = 2
+)
+
+// DemuxLogs reads multiplexed container logs, as returned by
+// Client.ContainerLogs, and writes the stdout and stderr payloads to the
+// respective writers. The same writer may be used for both streams.
+//
+// Each frame is prefixed by an 8 byte header, where the first byte is the
+// stream type and the last 4 bytes is the big-endian payload size.
+func DemuxLogs(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("read log frame header: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		var w io.Writer
+		switch Stream(header[0]) {
+		case StreamStdout:
+			w = stdout
+		case StreamStderr:
+			w = stderr
+		default:
+			w = io.Discard
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return fmt.Errorf("read log frame: %w", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package metrics serves Prometheus metrics that are registered by the other
+// wharf-cmd packages, such as the durations and outcomes of builds and steps
+// in the wharf-cmd-worker.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var log = logger.NewScoped("METRICS")
+
+// Namespace is the prefix of all wharf-cmd metric names.
+const Namespace = "wharf_cmd"
+
+// Path is the HTTP path where metrics are served.
+const Path = "/metrics"
+
+const readHeaderTimeout = 10 * time.Second
+
+// Handler returns an HTTP handler that serves all registered metrics in the
+// Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Register adds the /metrics endpoint to a gin router.
+func Register(r gin.IRoutes) {
+	r.GET(Path, gin.WrapH(Handler()))
+}
+
+// ListenAndServe starts an HTTP server in a background goroutine that only
+// serves the /metrics endpoint, for components that do not have an HTTP
+// server of their own. Does nothing if the bind address is empty.
+func ListenAndServe(bindAddress string) {
+	if bindAddress == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	server := &http.Server{
+		Addr:              bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	go func() {
+		log.Info().WithString("address", bindAddress).Message("Serving metrics.")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().
+				WithError(err).
+				WithString("address", bindAddress).
+				Message("Failed to serve metrics.")
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	counter := promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "test_register_total",
+		Help:      "Test counter.",
+	})
+	counter.Add(3)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + Path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "wharf_cmd_test_register_total 3")
+}
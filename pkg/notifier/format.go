@@ -0,0 +1,83 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color string `json:"color"`
+	Text  string `json:"text"`
+}
+
+// teamsMessageCard is a Microsoft Teams "legacy actionable message card", as
+// accepted by Teams incoming webhooks.
+type teamsMessageCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	ThemeColor string `json:"themeColor"`
+	Summary    string `json:"summary"`
+	Title      string `json:"title"`
+	Text       string `json:"text,omitempty"`
+}
+
+func formatPayload(format config.WebhookFormat, p Payload) ([]byte, error) {
+	switch format {
+	case config.WebhookFormatSlack:
+		msg := slackMessage{Text: p.Title()}
+		if details := p.Details(); len(details) > 0 {
+			msg.Attachments = []slackAttachment{{
+				Color: slackColor(p.Status),
+				Text:  strings.Join(details, "\n"),
+			}}
+		}
+		return json.Marshal(msg)
+	case config.WebhookFormatTeams:
+		return json.Marshal(teamsMessageCard{
+			Type:       "MessageCard",
+			Context:    "https://schema.org/extensions",
+			ThemeColor: teamsColor(p.Status),
+			Summary:    p.Title(),
+			Title:      p.Title(),
+			// Teams uses markdown, where lines need two trailing spaces to
+			// not be joined together.
+			Text: strings.Join(p.Details(), "  \n"),
+		})
+	default:
+		return json.Marshal(p)
+	}
+}
+
+func slackColor(status workermodel.Status) string {
+	switch status {
+	case workermodel.StatusSuccess:
+		return "good"
+	case workermodel.StatusFailed:
+		return "danger"
+	case workermodel.StatusWarning, workermodel.StatusCancelled:
+		return "warning"
+	default:
+		return "#439FE0"
+	}
+}
+
+func teamsColor(status workermodel.Status) string {
+	switch status {
+	case workermodel.StatusSuccess:
+		return "2EB886"
+	case workermodel.StatusFailed:
+		return "A30200"
+	case workermodel.StatusWarning, workermodel.StatusCancelled:
+		return "DAA038"
+	default:
+		return "439FE0"
+	}
+}
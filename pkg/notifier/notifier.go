@@ -0,0 +1,265 @@
+// Package notifier sends notifications about build events, such as when a
+// build starts or a step fails, to webhooks configured in the wharf-cmd
+// config.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+)
+
+var log = logger.NewScoped("NOTIFIER")
+
+// HTTP headers sent with each webhook request.
+const (
+	HeaderEvent     = "X-Wharf-Event"
+	HeaderSignature = "X-Wharf-Signature-256"
+)
+
+const (
+	signaturePrefix   = "sha256="
+	defaultTimeout    = 10 * time.Second
+	statusSubBuffer   = 100
+	payloadQueueLimit = 100
+)
+
+// Options holds information about the build that is included in all
+// notifications.
+type Options struct {
+	InstanceID string
+	BuildID    uint
+	ProjectID  uint
+	// HTTPClient is the client used to send the requests. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Notifier sends build notifications to webhooks. The notifications are sent
+// in order in a background goroutine, so they never block the build.
+type Notifier struct {
+	webhooks []config.WebhookConfig
+	opts     Options
+	queue    chan Payload
+	done     chan struct{}
+
+	watchMutex sync.Mutex
+	watchStore resultstore.Store
+	watchCh    <-chan resultstore.StatusUpdate
+	watchDone  chan struct{}
+}
+
+// New creates a new notifier and starts its background goroutine. Call Close
+// when done to wait for all notifications to be sent.
+func New(webhooks []config.WebhookConfig, opts Options) *Notifier {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	n := &Notifier{
+		webhooks: webhooks,
+		opts:     opts,
+		queue:    make(chan Payload, payloadQueueLimit),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(n.done)
+		for p := range n.queue {
+			n.sendToAll(p)
+		}
+	}()
+	return n
+}
+
+// BuildStarted sends the buildStarted event.
+func (n *Notifier) BuildStarted() {
+	n.enqueue(Payload{
+		Event:  config.NotificationEventBuildStarted,
+		Status: workermodel.StatusRunning,
+	})
+}
+
+// WatchStatusUpdates subscribes to the status updates of the result store and
+// sends the stepFailed and stageFinished events. The step metadata of all
+// steps must already have been written to the store, which is done when
+// creating a worker.Builder.
+func (n *Notifier) WatchStatusUpdates(store resultstore.Store) error {
+	metas, err := store.ListStepMetas()
+	if err != nil {
+		return fmt.Errorf("list step metadata: %w", err)
+	}
+	ch, err := store.SubAllStatusUpdates(statusSubBuffer)
+	if err != nil {
+		return fmt.Errorf("subscribe to status updates: %w", err)
+	}
+	n.watchMutex.Lock()
+	n.watchStore = store
+	n.watchCh = ch
+	n.watchDone = make(chan struct{})
+	n.watchMutex.Unlock()
+	tracker := newStageTracker(metas)
+	go func() {
+		defer close(n.watchDone)
+		for update := range ch {
+			for _, p := range tracker.apply(update) {
+				n.enqueue(p)
+			}
+		}
+	}()
+	return nil
+}
+
+// BuildFinished stops watching the status updates, after sending any events
+// from the updates that were already published, and then sends the
+// buildFinished event.
+func (n *Notifier) BuildFinished(res worker.Result) {
+	n.stopWatching()
+	p := Payload{
+		Event:    config.NotificationEventBuildFinished,
+		Status:   res.Status,
+		Duration: Duration(res.Duration),
+	}
+	for _, stage := range res.Stages {
+		summary := StageSummary{
+			Name:     stage.Name,
+			Status:   stage.Status,
+			Duration: Duration(stage.Duration),
+			Steps:    make([]StepSummary, 0, len(stage.Steps)),
+		}
+		for _, step := range stage.Steps {
+			stepSummary := StepSummary{
+				Name:     step.Name,
+				Status:   step.Status,
+				Duration: Duration(step.Duration),
+			}
+			if step.Error != nil {
+				stepSummary.Error = step.Error.Error()
+			}
+			summary.Steps = append(summary.Steps, stepSummary)
+		}
+		p.Stages = append(p.Stages, summary)
+	}
+	n.enqueue(p)
+}
+
+// Close waits for all queued notifications to be sent. The notifier must not
+// be used after it is closed.
+func (n *Notifier) Close() error {
+	n.stopWatching()
+	close(n.queue)
+	<-n.done
+	return nil
+}
+
+func (n *Notifier) stopWatching() {
+	n.watchMutex.Lock()
+	defer n.watchMutex.Unlock()
+	if n.watchCh == nil {
+		return
+	}
+	if err := n.watchStore.UnsubAllStatusUpdates(n.watchCh); err != nil {
+		log.Debug().WithError(err).Message("Failed to unsubscribe from status updates.")
+	}
+	<-n.watchDone
+	n.watchCh = nil
+}
+
+func (n *Notifier) enqueue(p Payload) {
+	if len(n.webhooks) == 0 {
+		return
+	}
+	p.Timestamp = time.Now()
+	p.InstanceID = n.opts.InstanceID
+	p.BuildID = n.opts.BuildID
+	p.ProjectID = n.opts.ProjectID
+	n.queue <- p
+}
+
+func (n *Notifier) sendToAll(p Payload) {
+	for _, webhook := range n.webhooks {
+		if !shouldSendEvent(webhook, p.Event) {
+			continue
+		}
+		if err := n.send(webhook, p); err != nil {
+			log.Warn().
+				WithError(err).
+				WithString("webhook", webhookName(webhook)).
+				WithString("event", string(p.Event)).
+				Message("Failed to send notification.")
+			continue
+		}
+		log.Debug().
+			WithString("webhook", webhookName(webhook)).
+			WithString("event", string(p.Event)).
+			Message("Sent notification.")
+	}
+}
+
+func (n *Notifier) send(webhook config.WebhookConfig, p Payload) error {
+	body, err := formatPayload(webhook.Format, p)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+	timeout := webhook.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(p.Event))
+	if webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	}
+	resp, err := n.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature of a payload, as sent in the
+// X-Wharf-Signature-256 HTTP header, in the format "sha256=<hex>".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func shouldSendEvent(webhook config.WebhookConfig, event config.NotificationEvent) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookName(webhook config.WebhookConfig) string {
+	if webhook.Name != "" {
+		return webhook.Name
+	}
+	return webhook.URL
+}
@@ -0,0 +1,174 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	header http.Header
+	body   []byte
+}
+
+type testServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []testRequest
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mutex.Lock()
+		s.requests = append(s.requests, testRequest{header: r.Header, body: body})
+		s.mutex.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) payloads(t *testing.T) []Payload {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var payloads []Payload
+	for _, req := range s.requests {
+		var p Payload
+		require.NoError(t, json.Unmarshal(req.body, &p))
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+func newTestStore(t *testing.T, metas ...resultstore.StepMeta) resultstore.Store {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	for _, meta := range metas {
+		require.NoError(t, store.SetStepMeta(meta.StepID, meta))
+	}
+	return store
+}
+
+func TestNotifier_buildLifecycle(t *testing.T) {
+	server := newTestServer(t)
+	store := newTestStore(t,
+		resultstore.StepMeta{StepID: 1, StageName: "build", StepName: "app"},
+		resultstore.StepMeta{StepID: 2, StageName: "test", StepName: "unit"},
+		resultstore.StepMeta{StepID: 3, StageName: "test", StepName: "lint"},
+	)
+	n := New([]config.WebhookConfig{{URL: server.URL}}, Options{BuildID: 12, ProjectID: 3})
+
+	n.BuildStarted()
+	require.NoError(t, n.WatchStatusUpdates(store))
+	now := time.Now()
+	store.AddStatusUpdate(1, now, workermodel.StatusRunning)
+	store.AddStatusUpdate(1, now.Add(time.Minute), workermodel.StatusSuccess)
+	store.AddStatusUpdate(2, now, workermodel.StatusRunning)
+	store.AddStatusUpdate(3, now, workermodel.StatusRunning)
+	store.AddTerminatedStatusUpdate(2, now, workermodel.StatusFailed, workermodel.Termination{ExitCode: 2, Reason: "Error"})
+	store.AddStatusUpdate(3, now, workermodel.StatusCancelled)
+	n.BuildFinished(worker.Result{
+		Status: workermodel.StatusFailed,
+		Stages: []worker.StageResult{
+			{Name: "build", Status: workermodel.StatusSuccess, Steps: []worker.StepResult{
+				{Name: "app", Status: workermodel.StatusSuccess},
+			}},
+			{Name: "test", Status: workermodel.StatusFailed, Steps: []worker.StepResult{
+				{Name: "unit", Status: workermodel.StatusFailed, Error: errors.New("non-zero exit code: 2")},
+				{Name: "lint", Status: workermodel.StatusCancelled},
+			}},
+		},
+	})
+	require.NoError(t, n.Close())
+
+	payloads := server.payloads(t)
+	var events []config.NotificationEvent
+	for _, p := range payloads {
+		events = append(events, p.Event)
+		assert.Equal(t, uint(12), p.BuildID)
+		assert.Equal(t, uint(3), p.ProjectID)
+	}
+	want := []config.NotificationEvent{
+		config.NotificationEventBuildStarted,
+		config.NotificationEventStageFinished,
+		config.NotificationEventStepFailed,
+		config.NotificationEventStageFinished,
+		config.NotificationEventBuildFinished,
+	}
+	require.Equal(t, want, events)
+
+	assert.Equal(t, "build", payloads[1].Stage)
+	assert.Equal(t, workermodel.StatusSuccess, payloads[1].Status)
+	assert.Equal(t, Duration(time.Minute), payloads[1].Duration)
+
+	assert.Equal(t, "unit", payloads[2].Step)
+	require.NotNil(t, payloads[2].Termination)
+	assert.Equal(t, int32(2), payloads[2].Termination.ExitCode)
+
+	assert.Equal(t, "test", payloads[3].Stage)
+	assert.Equal(t, workermodel.StatusFailed, payloads[3].Status)
+
+	assert.Equal(t, workermodel.StatusFailed, payloads[4].Status)
+	require.Len(t, payloads[4].Stages, 2)
+	assert.Equal(t, "non-zero exit code: 2", payloads[4].Stages[1].Steps[0].Error)
+}
+
+func TestNotifier_filtersEventsAndSigns(t *testing.T) {
+	server := newTestServer(t)
+	n := New([]config.WebhookConfig{{
+		URL:    server.URL,
+		Events: []config.NotificationEvent{config.NotificationEventBuildFinished},
+		Secret: "my-secret",
+	}}, Options{BuildID: 1})
+
+	n.BuildStarted()
+	n.BuildFinished(worker.Result{Status: workermodel.StatusSuccess})
+	require.NoError(t, n.Close())
+
+	require.Len(t, server.requests, 1)
+	req := server.requests[0]
+	assert.Equal(t, string(config.NotificationEventBuildFinished), req.header.Get(HeaderEvent))
+	assert.Equal(t, Sign("my-secret", req.body), req.header.Get(HeaderSignature))
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", req.header.Get(HeaderSignature))
+}
+
+func TestNotifier_formats(t *testing.T) {
+	slackServer := newTestServer(t)
+	teamsServer := newTestServer(t)
+	n := New([]config.WebhookConfig{
+		{URL: slackServer.URL, Format: config.WebhookFormatSlack},
+		{URL: teamsServer.URL, Format: config.WebhookFormatTeams},
+	}, Options{BuildID: 5})
+
+	n.BuildFinished(worker.Result{Status: workermodel.StatusFailed, Duration: 90 * time.Second})
+	require.NoError(t, n.Close())
+
+	require.Len(t, slackServer.requests, 1)
+	assert.JSONEq(t, `{
+		"text": "Build #5 finished: Failed",
+		"attachments": [{"color": "danger", "text": "Duration: 1m30s"}]
+	}`, string(slackServer.requests[0].body))
+
+	require.Len(t, teamsServer.requests, 1)
+	assert.JSONEq(t, `{
+		"@type": "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"themeColor": "A30200",
+		"summary": "Build #5 finished: Failed",
+		"title": "Build #5 finished: Failed",
+		"text": "Duration: 1m30s"
+	}`, string(teamsServer.requests[0].body))
+}
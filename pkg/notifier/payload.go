@@ -0,0 +1,119 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// Payload is the JSON body sent to webhooks that use the generic format.
+type Payload struct {
+	Event      config.NotificationEvent `json:"event"`
+	Timestamp  time.Time                `json:"timestamp"`
+	InstanceID string                   `json:"instanceId,omitempty"`
+	BuildID    uint                     `json:"buildId"`
+	ProjectID  uint                     `json:"projectId,omitempty"`
+	Status     workermodel.Status       `json:"status"`
+	// Stage is set on the stageFinished and stepFailed events.
+	Stage string `json:"stage,omitempty"`
+	// Step is set on the stepFailed event.
+	Step string `json:"step,omitempty"`
+	// Duration is set on the stageFinished and buildFinished events.
+	Duration Duration `json:"duration,omitempty"`
+	// Termination is set on the stepFailed event, if the step's process or
+	// container terminated.
+	Termination *workermodel.Termination `json:"termination,omitempty"`
+	// Stages is set on the buildFinished event.
+	Stages []StageSummary `json:"stages,omitempty"`
+}
+
+// StageSummary is the result of a stage, as sent in the buildFinished event.
+type StageSummary struct {
+	Name     string             `json:"name"`
+	Status   workermodel.Status `json:"status"`
+	Duration Duration           `json:"duration"`
+	Steps    []StepSummary      `json:"steps"`
+}
+
+// StepSummary is the result of a step, as sent in the buildFinished event.
+type StepSummary struct {
+	Name     string             `json:"name"`
+	Status   workermodel.Status `json:"status"`
+	Duration Duration           `json:"duration"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// Duration is a time.Duration that is encoded as a string in JSON, such as
+// "1m30s".
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	dur, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// Title returns a short human-readable summary of the event, such as
+// "Build #123 failed".
+func (p Payload) Title() string {
+	switch p.Event {
+	case config.NotificationEventBuildStarted:
+		return fmt.Sprintf("Build #%d started", p.BuildID)
+	case config.NotificationEventStageFinished:
+		return fmt.Sprintf("Stage %q of build #%d finished: %s", p.Stage, p.BuildID, p.Status)
+	case config.NotificationEventStepFailed:
+		return fmt.Sprintf("Step %q of build #%d failed", p.Stage+"/"+p.Step, p.BuildID)
+	case config.NotificationEventBuildFinished:
+		return fmt.Sprintf("Build #%d finished: %s", p.BuildID, p.Status)
+	default:
+		return fmt.Sprintf("Build #%d: %s", p.BuildID, p.Event)
+	}
+}
+
+// Details returns the lines of text that are shown below the title in the
+// Slack and Microsoft Teams formats.
+func (p Payload) Details() []string {
+	var lines []string
+	if p.ProjectID != 0 {
+		lines = append(lines, fmt.Sprintf("Project ID: %d", p.ProjectID))
+	}
+	if t := p.Termination; t != nil {
+		line := fmt.Sprintf("Exit code: %d", t.ExitCode)
+		if t.Reason != "" {
+			line += fmt.Sprintf(" (%s)", t.Reason)
+		}
+		lines = append(lines, line)
+		if t.Message != "" {
+			lines = append(lines, t.Message)
+		}
+	}
+	if p.Duration != 0 {
+		lines = append(lines, fmt.Sprintf("Duration: %s", time.Duration(p.Duration).Truncate(time.Second)))
+	}
+	for _, stage := range p.Stages {
+		var failed []string
+		for _, step := range stage.Steps {
+			if step.Status == workermodel.StatusFailed {
+				failed = append(failed, step.Name)
+			}
+		}
+		line := fmt.Sprintf("%s: %s", stage.Name, stage.Status)
+		if len(failed) > 0 {
+			line += fmt.Sprintf(" (failed steps: %s)", strings.Join(failed, ", "))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
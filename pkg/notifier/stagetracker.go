@@ -0,0 +1,96 @@
+package notifier
+
+import (
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// stageTracker keeps track of the latest status of each step, to derive when
+// a step fails and when all steps in a stage are done.
+type stageTracker struct {
+	steps    map[uint64]resultstore.StepMeta
+	stages   map[string]*trackedStage
+	finished map[uint64]bool
+}
+
+type trackedStage struct {
+	stepCount int
+	done      int
+	failed    bool
+	startedAt time.Time
+}
+
+func newStageTracker(metas []resultstore.StepMeta) *stageTracker {
+	t := &stageTracker{
+		steps:    make(map[uint64]resultstore.StepMeta, len(metas)),
+		stages:   make(map[string]*trackedStage),
+		finished: make(map[uint64]bool),
+	}
+	for _, meta := range metas {
+		t.steps[meta.StepID] = meta
+		stage, ok := t.stages[meta.StageName]
+		if !ok {
+			stage = &trackedStage{}
+			t.stages[meta.StageName] = stage
+		}
+		stage.stepCount++
+	}
+	return t
+}
+
+// apply returns the events caused by the status update, if any.
+func (t *stageTracker) apply(update resultstore.StatusUpdate) []Payload {
+	meta, ok := t.steps[update.StepID]
+	if !ok {
+		return nil
+	}
+	stage := t.stages[meta.StageName]
+	if stage.startedAt.IsZero() {
+		stage.startedAt = update.Timestamp
+	}
+	if !isDoneStatus(update.Status) || t.finished[update.StepID] {
+		return nil
+	}
+	t.finished[update.StepID] = true
+	stage.done++
+
+	var payloads []Payload
+	if update.Status != workermodel.StatusSuccess && update.Status != workermodel.StatusWarning {
+		stage.failed = true
+	}
+	if update.Status == workermodel.StatusFailed {
+		payloads = append(payloads, Payload{
+			Event:       config.NotificationEventStepFailed,
+			Status:      update.Status,
+			Stage:       meta.StageName,
+			Step:        meta.StepName,
+			Termination: update.Termination,
+		})
+	}
+	if stage.done == stage.stepCount {
+		status := workermodel.StatusSuccess
+		if stage.failed {
+			status = workermodel.StatusFailed
+		}
+		payloads = append(payloads, Payload{
+			Event:    config.NotificationEventStageFinished,
+			Status:   status,
+			Stage:    meta.StageName,
+			Duration: Duration(update.Timestamp.Sub(stage.startedAt)),
+		})
+	}
+	return payloads
+}
+
+func isDoneStatus(status workermodel.Status) bool {
+	switch status {
+	case workermodel.StatusSuccess, workermodel.StatusFailed,
+		workermodel.StatusCancelled, workermodel.StatusWarning:
+		return true
+	default:
+		return false
+	}
+}
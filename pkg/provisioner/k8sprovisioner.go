@@ -9,6 +9,10 @@ import (
 
 	"github.com/iver-wharf/wharf-cmd/internal/util"
 	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/typ.v4"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,8 +21,11 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+var tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/pkg/provisioner")
+
 type k8sProvisioner struct {
 	k8sWorkerConf config.ProvisionerK8sWorkerConfig
+	tracingConf   config.TracingConfig
 	extraEnvs     []v1.EnvVar
 	clientset     *kubernetes.Clientset
 	pods          corev1.PodInterface
@@ -48,6 +55,7 @@ func NewK8sProvisioner(config *config.Config, restConfig *rest.Config) (Provisio
 	}
 	return k8sProvisioner{
 		k8sWorkerConf: config.Provisioner.K8s.Worker,
+		tracingConf:   config.Tracing,
 		extraEnvs:     extraEnvs,
 		clientset:     clientset,
 		pods:          clientset.CoreV1().Pods(config.K8s.Namespace),
@@ -74,7 +82,10 @@ func (p k8sProvisioner) listPods(ctx context.Context, opts metav1.ListOptions) (
 	return p.pods.List(ctx, opts)
 }
 
-func (p k8sProvisioner) DeleteWorker(ctx context.Context, workerID string) error {
+func (p k8sProvisioner) DeleteWorker(ctx context.Context, workerID string) (finalErr error) {
+	defer func() {
+		workersDeleted.WithLabelValues(metricsResult(finalErr)).Inc()
+	}()
 	pod, err := p.getPod(ctx, workerID)
 	if err != nil {
 		return err
@@ -87,8 +98,15 @@ func (p k8sProvisioner) CreateWorker(ctx context.Context, args WorkerArgs) (Work
 	if args.GitCloneURL == "" {
 		return Worker{}, errors.New("missing required Git clone URL")
 	}
-	podMeta := p.newWorkerPod(args)
+	ctx, span := tracer.Start(ctx, "create worker", trace.WithAttributes(
+		attribute.Int64("wharf.build.id", int64(args.BuildID)),
+		attribute.Int64("wharf.project.id", int64(args.ProjectID)),
+	))
+	defer span.End()
+	podMeta := p.newWorkerPod(ctx, args)
 	newPod, err := p.pods.Create(ctx, &podMeta, metav1.CreateOptions{})
+	tracing.RecordError(span, err)
+	workersCreated.WithLabelValues(metricsResult(err)).Inc()
 	return convertPodToWorker(newPod), err
 }
 
@@ -105,7 +123,7 @@ func (p k8sProvisioner) getPod(ctx context.Context, workerID string) (*v1.Pod, e
 	return nil, fmt.Errorf("found no worker with appropriate labels matching workerID: %s", workerID)
 }
 
-func (p k8sProvisioner) newWorkerPod(args WorkerArgs) v1.Pod {
+func (p k8sProvisioner) newWorkerPod(ctx context.Context, args WorkerArgs) v1.Pod {
 	const (
 		repoVolumeName      = "repo"
 		repoVolumeMountPath = "/mnt/repo"
@@ -233,8 +251,18 @@ func (p k8sProvisioner) newWorkerPod(args WorkerArgs) v1.Pod {
 				},
 			},
 		},
+		{
+			Name: "WHARF_KUBERNETES_OWNER_NAMESPACE",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "metadata.namespace",
+				},
+			},
+		},
 	}
 
+	wharfEnvs = append(wharfEnvs, p.tracingEnvs(ctx)...)
 	wharfEnvs = append(wharfEnvs, p.extraEnvs...)
 
 	for k, v := range args.AdditionalVars {
@@ -248,6 +276,7 @@ func (p k8sProvisioner) newWorkerPod(args WorkerArgs) v1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: "wharf-cmd-worker-",
 			Labels:       labels,
+			Annotations:  tracing.AnnotationsFromContext(ctx),
 		},
 		Spec: v1.PodSpec{
 			ServiceAccountName: p.k8sWorkerConf.ServiceAccountName,
@@ -278,6 +307,29 @@ func (p k8sProvisioner) newWorkerPod(args WorkerArgs) v1.Pod {
 	}
 }
 
+// tracingEnvs returns the environment variables that propagate the trace
+// context and tracing config to the wharf-cmd-worker, so its spans end up in
+// the same trace as the provisioner's.
+func (p k8sProvisioner) tracingEnvs(ctx context.Context) []v1.EnvVar {
+	var envs []v1.EnvVar
+	if p.tracingConf.Enabled {
+		envs = append(envs,
+			v1.EnvVar{Name: "WHARF_TRACING_ENABLED", Value: "true"},
+			v1.EnvVar{Name: "WHARF_TRACING_SAMPLERATIO", Value: strconv.FormatFloat(p.tracingConf.SampleRatio, 'g', -1, 64)},
+		)
+		if p.tracingConf.Endpoint != "" {
+			envs = append(envs, v1.EnvVar{Name: "WHARF_TRACING_ENDPOINT", Value: p.tracingConf.Endpoint})
+		}
+	}
+	traceEnv := tracing.EnvFromContext(ctx)
+	for _, name := range []string{tracing.EnvTraceParent, tracing.EnvTraceState} {
+		if v, ok := traceEnv[name]; ok {
+			envs = append(envs, v1.EnvVar{Name: name, Value: v})
+		}
+	}
+	return envs
+}
+
 func convLocalObjectReferences(refs []config.K8sLocalObjectReference) []v1.LocalObjectReference {
 	var k8sRefs []v1.LocalObjectReference
 	for _, r := range refs {
@@ -0,0 +1,88 @@
+package provisioner
+
+import (
+	"context"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var log = logger.NewScoped("PROVISIONER")
+
+const (
+	metricsSubsystem = "provisioner"
+
+	metricsResultSuccess = "success"
+	metricsResultError   = "error"
+
+	listWorkersTimeout = 10 * time.Second
+)
+
+var (
+	workersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workers_created_total",
+		Help:      "Number of workers created, by result.",
+	}, []string{"result"})
+
+	workersDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workers_deleted_total",
+		Help:      "Number of workers deleted, by result.",
+	}, []string{"result"})
+
+	workersActiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, metricsSubsystem, "workers_active"),
+		"Number of existing workers, by status.",
+		[]string{"status"}, nil,
+	)
+)
+
+func metricsResult(err error) string {
+	if err != nil {
+		return metricsResultError
+	}
+	return metricsResultSuccess
+}
+
+// NewWorkersCollector returns a Prometheus collector that lists the workers
+// of the provisioner on each scrape, and reports how many there are of each
+// status.
+func NewWorkersCollector(prov Provisioner) prometheus.Collector {
+	return workersCollector{prov}
+}
+
+type workersCollector struct {
+	prov Provisioner
+}
+
+// Describe implements prometheus.Collector.
+func (c workersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workersActiveDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c workersCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), listWorkersTimeout)
+	defer cancel()
+	workers, err := c.prov.ListWorkers(ctx)
+	if err != nil {
+		log.Warn().WithError(err).Message("Failed to list workers for metrics.")
+		ch <- prometheus.NewInvalidMetric(workersActiveDesc, err)
+		return
+	}
+	counts := make(map[workermodel.Status]int)
+	for _, w := range workers {
+		counts[w.Status]++
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(workersActiveDesc,
+			prometheus.GaugeValue, float64(count), status.String())
+	}
+}
@@ -0,0 +1,42 @@
+package provisioner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockProvisioner struct {
+	Provisioner
+	workers []Worker
+	err     error
+}
+
+func (p mockProvisioner) ListWorkers(context.Context) ([]Worker, error) {
+	return p.workers, p.err
+}
+
+func TestWorkersCollector(t *testing.T) {
+	collector := NewWorkersCollector(mockProvisioner{workers: []Worker{
+		{WorkerID: "a", Status: workermodel.StatusRunning},
+		{WorkerID: "b", Status: workermodel.StatusRunning},
+		{WorkerID: "c", Status: workermodel.StatusScheduling},
+	}})
+	want := `
+# HELP wharf_cmd_provisioner_workers_active Number of existing workers, by status.
+# TYPE wharf_cmd_provisioner_workers_active gauge
+wharf_cmd_provisioner_workers_active{status="Running"} 2
+wharf_cmd_provisioner_workers_active{status="Scheduling"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(want)))
+}
+
+func TestWorkersCollector_listError(t *testing.T) {
+	collector := NewWorkersCollector(mockProvisioner{err: errors.New("k8s is down")})
+	assert.Error(t, testutil.CollectAndCompare(collector, strings.NewReader("")))
+}
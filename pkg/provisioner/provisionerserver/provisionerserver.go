@@ -4,15 +4,24 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
 	"github.com/iver-wharf/wharf-cmd/pkg/provisioner"
 	"github.com/iver-wharf/wharf-cmd/pkg/provisioner/provisionerserver/docs"
 	"github.com/iver-wharf/wharf-core/v2/pkg/ginutil"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/swaggo/gin-swagger/swaggerFiles"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var log = logger.NewScoped("PROV-SERVER")
+var (
+	log    = logger.NewScoped("PROV-SERVER")
+	tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/pkg/provisioner/provisionerserver")
+)
 
 // Serve starts an HTTP server.
 //
@@ -33,11 +42,16 @@ func Serve(prov provisioner.Provisioner, config config.ProvisionerConfig) error
 	r.Use(
 		ginutil.DefaultLoggerHandler,
 		ginutil.RecoverProblem,
+		traceHandler,
 	)
 
 	applyCORS(r, config.HTTP.CORS)
 
 	r.GET("", pingHandler)
+	if err := prometheus.Register(provisioner.NewWorkersCollector(prov)); err != nil {
+		log.Warn().WithError(err).Message("Failed to register workers metrics collector.")
+	}
+	metrics.Register(r)
 	api := r.Group("/api")
 	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, func(c *ginSwagger.Config) {
 		c.InstanceName = docs.SwaggerInfoprovisionerapi.InstanceName()
@@ -89,6 +103,20 @@ type Ping struct {
 // @produce json
 // @success 200 {object} Ping
 // @router / [get]
+// traceHandler continues the trace from the W3C Trace Context HTTP headers,
+// if any, so callers such as wharf-api can follow a build through the
+// provisioner and into the wharf-cmd-worker.
+func traceHandler(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := tracer.Start(ctx, c.Request.Method+" "+c.FullPath(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.method", c.Request.Method)))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+	span.SetAttributes(attribute.Int("http.status_code", c.Writer.Status()))
+}
+
 func pingHandler(c *gin.Context) {
 	c.JSON(200, Ping{Message: "pong"})
 }
@@ -39,6 +39,16 @@ func (s *store) AddArtifactEvent(stepID uint64, artifactMeta workermodel.Artifac
 	return nil
 }
 
+func (s *store) ListArtifactEvents(stepID uint64) ([]ArtifactEvent, error) {
+	s.artifactMutex.LockKey(stepID)
+	defer s.artifactMutex.UnlockKey(stepID)
+	list, err := s.readArtifactEventsFile(stepID)
+	if err != nil {
+		return nil, err
+	}
+	return list.ArtifactEvents, nil
+}
+
 func (s *store) readArtifactEventsFile(stepID uint64) (ArtifactEventList, error) {
 	file, err := s.fs.OpenRead(s.resolveArtifactEventsPath(stepID))
 	if errors.Is(err, fs.ErrNotExist) {
@@ -17,7 +17,7 @@ type FS interface {
 	// data is appended to the end.
 	OpenAppend(name string) (io.WriteCloser, error)
 	// OpenWrite creates or opens a file in write-only mode, meaning all written
-	// data is written from the start.
+	// data is written from the start. Any existing content is truncated.
 	OpenWrite(name string) (io.WriteCloser, error)
 	// OpenRead opens a file in read-only-mode, reading data from the start of
 	// the file.
@@ -47,7 +47,7 @@ func (fs osFS) OpenAppend(name string) (io.WriteCloser, error) {
 }
 
 func (fs osFS) OpenWrite(name string) (io.WriteCloser, error) {
-	return fs.openFileMkdirAll(name, os.O_WRONLY|os.O_CREATE, 0644)
+	return fs.openFileMkdirAll(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 }
 
 func (fs osFS) OpenRead(name string) (io.ReadCloser, error) {
@@ -22,6 +22,8 @@ var (
 	fileNameLogs = "logs.log"
 )
 
+const logLineSourceSep = "|"
+
 func (s *store) SubAllLogLines(buffer int) (<-chan LogLine, error) {
 	s.logSubMutex.Lock()
 	defer s.logSubMutex.Unlock()
@@ -98,13 +100,9 @@ func (s *store) resolveLogPath(stepID uint64) string {
 }
 
 func (s *store) parseAndPubLogLine(stepID uint64, logID uint64, line string) LogLine {
-	tim, msg := parseLogLine(line)
-	logLine := LogLine{
-		StepID:    stepID,
-		LogID:     logID,
-		Message:   msg,
-		Timestamp: tim,
-	}
+	logLine := parseLogLine(line)
+	logLine.StepID = stepID
+	logLine.LogID = logID
 	// Locking to prevent new data being added during fetching existing data
 	// part of when a new subscription is made.
 	s.logSubMutex.RLock()
@@ -113,16 +111,43 @@ func (s *store) parseAndPubLogLine(stepID uint64, logID uint64, line string) Log
 	return logLine
 }
 
-func parseLogLine(line string) (time.Time, string) {
-	timeStr, message, hasCut := strings.Cut(line, " ")
+// parseLogLine parses a line from the log file, which has the format:
+//
+//	<timestamp> <message>
+//	<timestamp>|<container>|<stream> <message>
+//
+// where the latter is used for log lines written with WriteContainerLogLine.
+func parseLogLine(line string) LogLine {
+	prefix, message, hasCut := strings.Cut(line, " ")
 	if !hasCut {
-		return time.Time{}, line
+		return LogLine{Message: line}
 	}
+	timeStr, source, hasSource := strings.Cut(prefix, logLineSourceSep)
 	t, err := time.Parse(time.RFC3339Nano, timeStr)
 	if err != nil {
-		return time.Time{}, line
+		return LogLine{Message: line}
 	}
-	return t, message
+	logLine := LogLine{Message: message, Timestamp: t}
+	if hasSource {
+		container, stream, _ := strings.Cut(source, logLineSourceSep)
+		logLine.Container = container
+		logLine.Stream = LogStream(stream)
+	}
+	return logLine
+}
+
+func formatContainerLogLine(container string, stream LogStream, line string) string {
+	timeStr, message, hasCut := strings.Cut(line, " ")
+	if !hasCut || !isLogLineTimestamp(timeStr) {
+		timeStr = time.Now().UTC().Format(time.RFC3339Nano)
+		message = line
+	}
+	return fmt.Sprintf("%s|%s|%s %s", timeStr, container, stream, message)
+}
+
+func isLogLineTimestamp(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
 }
 
 func sanitizeLogLine(line string) string {
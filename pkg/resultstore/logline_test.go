@@ -15,10 +15,12 @@ import (
 func TestVisitLogLine(t *testing.T) {
 	zeroTime := time.Time{}
 	testCases := []struct {
-		name     string
-		input    string
-		wantTime time.Time
-		wantLine string
+		name          string
+		input         string
+		wantTime      time.Time
+		wantLine      string
+		wantContainer string
+		wantStream    LogStream
 	}{
 		{
 			name:     "empty line",
@@ -44,12 +46,30 @@ func TestVisitLogLine(t *testing.T) {
 			wantTime: zeroTime,
 			wantLine: "2021-99-09T55:13:65.1234Z hello world",
 		},
+		{
+			name:          "with container and stream",
+			input:         sampleTimeStr + "|init|stderr hello world",
+			wantTime:      sampleTime,
+			wantLine:      "hello world",
+			wantContainer: "init",
+			wantStream:    LogStreamStderr,
+		},
+		{
+			name:          "with container only",
+			input:         sampleTimeStr + "|step| hello world",
+			wantTime:      sampleTime,
+			wantLine:      "hello world",
+			wantContainer: "step",
+			wantStream:    LogStreamUnknown,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			tim, line := parseLogLine(tc.input)
-			assert.Equal(t, tc.wantLine, line, "log line")
-			assert.Equal(t, tc.wantTime, tim, "log time")
+			got := parseLogLine(tc.input)
+			assert.Equal(t, tc.wantLine, got.Message, "log line")
+			assert.Equal(t, tc.wantTime, got.Timestamp, "log time")
+			assert.Equal(t, tc.wantContainer, got.Container, "log container")
+			assert.Equal(t, tc.wantStream, got.Stream, "log stream")
 		})
 	}
 }
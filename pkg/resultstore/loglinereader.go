@@ -44,13 +44,10 @@ func (r *logLineReadCloser) ReadLogLine() (LogLine, error) {
 }
 
 func (r *logLineReadCloser) parseLogLine(text string) LogLine {
-	tim, msg := parseLogLine(text)
-	return LogLine{
-		StepID:    r.stepID,
-		LogID:     r.nextLogID,
-		Message:   msg,
-		Timestamp: tim,
-	}
+	logLine := parseLogLine(text)
+	logLine.StepID = r.stepID
+	logLine.LogID = r.nextLogID
+	return logLine
 }
 
 func (r *logLineReadCloser) Close() error {
@@ -83,6 +83,10 @@ func (w *logLineWriteCloser) WriteLogLine(line string) (LogLine, error) {
 	return w.store.parseAndPubLogLine(w.stepID, logID, sanitized), nil
 }
 
+func (w *logLineWriteCloser) WriteContainerLogLine(container string, stream LogStream, line string) (LogLine, error) {
+	return w.WriteLogLine(formatContainerLogLine(container, stream, line))
+}
+
 func (w *logLineWriteCloser) Close() error {
 	w.store.logWritersOpened.Delete(w.stepID)
 	return w.writeCloser.Close()
@@ -42,6 +42,26 @@ func TestLogLineWriteCloser_Sanitizes(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestLogLineWriteCloser_WriteContainerLogLine(t *testing.T) {
+	var buf bytes.Buffer
+	var w LogLineWriteCloser = &logLineWriteCloser{
+		writeCloser: nopWriteCloser{&buf},
+		store:       &store{},
+	}
+	got, err := w.WriteContainerLogLine("init", LogStreamStderr, sampleTimeStr+" Foo bar")
+	require.NoError(t, err)
+
+	assert.Equal(t, sampleTimeStr+"|init|stderr Foo bar\n", buf.String())
+	want := LogLine{
+		LogID:     1,
+		Message:   "Foo bar",
+		Timestamp: sampleTime,
+		Container: "init",
+		Stream:    LogStreamStderr,
+	}
+	assert.Equal(t, want, got)
+}
+
 func TestStore_OpenLogWriterCollision(t *testing.T) {
 	s := NewStore(mockFS{
 		openRead: func(string) (io.ReadCloser, error) {
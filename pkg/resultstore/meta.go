@@ -0,0 +1,127 @@
+package resultstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+var (
+	fileNameBuildMeta = "build.json"
+	fileNameStepMeta  = "step.json"
+)
+
+// BuildMeta is metadata about the build. This is the data structure that is
+// serialized in the build metadata file in the root of the store.
+type BuildMeta struct {
+	BuildID uint `json:"buildId"`
+	// Project is the human readable name of the project that the build
+	// belongs to, such as "iver-wharf/wharf-cmd".
+	Project string `json:"project,omitempty"`
+	// RerunOfBuildID is the ID of the build that this build re-runs the failed
+	// steps of, or zero if this build is not a re-run.
+	RerunOfBuildID uint `json:"rerunOfBuildId,omitempty"`
+	// WorkerServerURL is the URL of the worker server that serves the results
+	// of this build while it runs, or empty if not served. It is cleared
+	// when the build finishes. The server at this URL may be serving another
+	// build, so the build served there must be checked before use.
+	WorkerServerURL string `json:"workerServerUrl,omitempty"`
+}
+
+// StepMeta is metadata about a build step, such as its name and which stage
+// it belongs to. This is the data structure that is serialized in the step
+// metadata file for a given step.
+type StepMeta struct {
+	StepID    uint64 `json:"-"`
+	StageName string `json:"stageName"`
+	StepName  string `json:"stepName"`
+	StepType  string `json:"stepType"`
+}
+
+func (s *store) SetBuildMeta(meta BuildMeta) error {
+	if s.frozen {
+		return ErrFrozen
+	}
+	return s.writeJSONFile(fileNameBuildMeta, &meta)
+}
+
+func (s *store) ReadBuildMeta() (BuildMeta, error) {
+	var meta BuildMeta
+	if err := s.readJSONFile(fileNameBuildMeta, &meta); err != nil {
+		return BuildMeta{}, err
+	}
+	return meta, nil
+}
+
+func (s *store) SetStepMeta(stepID uint64, meta StepMeta) error {
+	if s.frozen {
+		return ErrFrozen
+	}
+	return s.writeJSONFile(s.resolveStepMetaPath(stepID), &meta)
+}
+
+func (s *store) ReadStepMeta(stepID uint64) (StepMeta, error) {
+	var meta StepMeta
+	if err := s.readJSONFile(s.resolveStepMetaPath(stepID), &meta); err != nil {
+		return StepMeta{}, err
+	}
+	meta.StepID = stepID
+	return meta, nil
+}
+
+func (s *store) ListStepMetas() ([]StepMeta, error) {
+	stepIDs, err := s.listAllStepIDs()
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]StepMeta, 0, len(stepIDs))
+	for _, stepID := range stepIDs {
+		var meta StepMeta
+		err := s.readJSONFile(s.resolveStepMetaPath(stepID), &meta)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		meta.StepID = stepID
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+func (s *store) ListStepIDs() ([]uint64, error) {
+	return s.listAllStepIDs()
+}
+
+func (s *store) resolveStepMetaPath(stepID uint64) string {
+	return filepath.Join(dirNameSteps, fmt.Sprint(stepID), fileNameStepMeta)
+}
+
+func (s *store) writeJSONFile(name string, v any) error {
+	file, err := s.fs.OpenWrite(name)
+	if err != nil {
+		return fmt.Errorf("open %s for writing: %w", name, err)
+	}
+	defer file.Close()
+	enc := json.NewEncoder(file)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *store) readJSONFile(name string, v any) error {
+	file, err := s.fs.OpenRead(name)
+	if err != nil {
+		return fmt.Errorf("open %s for reading: %w", name, err)
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package resultstore
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SetBuildMeta(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStore(mockFS{
+		openWrite: func(name string) (io.WriteCloser, error) {
+			require.Equal(t, fileNameBuildMeta, name)
+			return nopWriteCloser{&buf}, nil
+		},
+	})
+	err := s.SetBuildMeta(BuildMeta{BuildID: 12, RerunOfBuildID: 10})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"buildId": 12, "rerunOfBuildId": 10}`, buf.String())
+}
+
+func TestStore_SetBuildMeta_overwritesLongerContent(t *testing.T) {
+	s := NewStore(NewFS(t.TempDir()))
+	require.NoError(t, s.SetBuildMeta(BuildMeta{BuildID: 12, WorkerServerURL: "http://localhost:5010"}))
+	require.NoError(t, s.SetBuildMeta(BuildMeta{BuildID: 12}))
+	got, err := s.ReadBuildMeta()
+	require.NoError(t, err)
+	assert.Equal(t, BuildMeta{BuildID: 12}, got)
+}
+
+func TestStore_ListStepMetas(t *testing.T) {
+	files := map[string]string{
+		filepath.Join(dirNameSteps, "1", fileNameStepMeta): `{"stageName": "build", "stepName": "docker", "stepType": "docker"}`,
+		filepath.Join(dirNameSteps, "3", fileNameStepMeta): `{"stageName": "deploy", "stepName": "helm", "stepType": "helm"}`,
+	}
+	s := NewStore(mockFS{
+		listDirEntries: func(string) ([]fs.DirEntry, error) {
+			return []fs.DirEntry{
+				newMockDirEntryDir("1"),
+				newMockDirEntryDir("2"),
+				newMockDirEntryDir("3"),
+			}, nil
+		},
+		openRead: func(name string) (io.ReadCloser, error) {
+			content, ok := files[name]
+			if !ok {
+				return nil, fs.ErrNotExist
+			}
+			return io.NopCloser(bytes.NewBufferString(content)), nil
+		},
+	})
+	got, err := s.ListStepMetas()
+	require.NoError(t, err)
+	want := []StepMeta{
+		{StepID: 1, StageName: "build", StepName: "docker", StepType: "docker"},
+		{StepID: 3, StageName: "deploy", StepName: "helm", StepType: "helm"},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestStore_ReadStepMeta(t *testing.T) {
+	s := NewStore(mockFS{
+		openRead: func(name string) (io.ReadCloser, error) {
+			require.Equal(t, filepath.Join(dirNameSteps, "4", fileNameStepMeta), name)
+			return io.NopCloser(bytes.NewBufferString(`{"stageName": "test", "stepName": "unit", "stepType": "container"}`)), nil
+		},
+	})
+	got, err := s.ReadStepMeta(4)
+	require.NoError(t, err)
+	want := StepMeta{StepID: 4, StageName: "test", StepName: "unit", StepType: "container"}
+	assert.Equal(t, want, got)
+}
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,8 +36,24 @@ type LogLine struct {
 	LogID     uint64
 	Message   string
 	Timestamp time.Time
+	// Container is the name of the container that wrote the log line, such as
+	// the step's init container or a sidecar. Empty if unknown.
+	Container string
+	// Stream is the output stream that the log line was written to. Empty if
+	// unknown, such as when reading logs from Kubernetes, which combines them.
+	Stream LogStream
 }
 
+// LogStream is the output stream of a log line.
+type LogStream string
+
+// Known log streams.
+const (
+	LogStreamUnknown LogStream = ""
+	LogStreamStdout  LogStream = "stdout"
+	LogStreamStderr  LogStream = "stderr"
+)
+
 // GoString implements fmt.Stringer
 func (log LogLine) String() string {
 	return fmt.Sprintf("%s %s", log.Timestamp.Format(time.RFC3339Nano), log.Message)
@@ -57,6 +77,9 @@ type StatusUpdate struct {
 	UpdateID  uint64             `json:"updateId"`
 	Timestamp time.Time          `json:"timestamp"`
 	Status    workermodel.Status `json:"status"`
+	// Termination is set on the final status update of a step whose process
+	// or container terminated.
+	Termination *workermodel.Termination `json:"termination,omitempty"`
 }
 
 // ArtifactEventList is a list of artifact events. This is the data structure
@@ -110,6 +133,18 @@ type Store interface {
 	// Will return ErrFrozen if the store is frozen.
 	AddStatusUpdate(stepID uint64, timestamp time.Time, newStatus workermodel.Status) error
 
+	// AddTerminatedStatusUpdate adds a status update to a step in the same way
+	// as AddStatusUpdate, but also includes how the step's process or
+	// container terminated, such as its exit code.
+	//
+	// Will return ErrFrozen if the store is frozen.
+	AddTerminatedStatusUpdate(stepID uint64, timestamp time.Time, newStatus workermodel.Status, termination workermodel.Termination) error
+
+	// ListStatusUpdates returns all status updates for a step, in the order
+	// they were added. Returns an empty slice if the step has no status
+	// updates.
+	ListStatusUpdates(stepID uint64) ([]StatusUpdate, error)
+
 	// SubAllStatusUpdates creates a new channel that streams all status updates
 	// from this result store since the beginning, and keeps on streaming new
 	// updates until unsubscribed.
@@ -125,6 +160,10 @@ type Store interface {
 	// Will return ErrFrozen if the store is frozen.
 	AddArtifactEvent(stepID uint64, artifactMeta workermodel.ArtifactMeta) error
 
+	// ListArtifactEvents returns all artifact events for a step, in the order
+	// they were added. Returns an empty slice if the step has no artifacts.
+	ListArtifactEvents(stepID uint64) ([]ArtifactEvent, error)
+
 	// SubAllArtifactEvents creates a new channel that streams all artifact
 	// events from this result store since the beginning, and keeps on
 	// streaming new events until unsubscribed.
@@ -134,6 +173,36 @@ type Store interface {
 	// events created via SubAllStatusUpdates.
 	UnsubAllArtifactEvents(ch <-chan ArtifactEvent) error
 
+	// SetBuildMeta writes metadata about the build, such as its build ID. This
+	// is expected to only be called once per store.
+	//
+	// Will return ErrFrozen if the store is frozen.
+	SetBuildMeta(meta BuildMeta) error
+
+	// ReadBuildMeta reads the metadata about the build.
+	//
+	// Will return fs.ErrNotExist if no build metadata has been written.
+	ReadBuildMeta() (BuildMeta, error)
+
+	// SetStepMeta writes metadata about a step, such as its name and what
+	// stage it belongs to. This is expected to only be called once per step.
+	//
+	// Will return ErrFrozen if the store is frozen.
+	SetStepMeta(stepID uint64, meta StepMeta) error
+
+	// ReadStepMeta reads the metadata about a step.
+	//
+	// Will return fs.ErrNotExist if no metadata has been written for the step.
+	ReadStepMeta(stepID uint64) (StepMeta, error)
+
+	// ListStepMetas returns the metadata of all steps that has any. Steps
+	// without metadata are left out.
+	ListStepMetas() ([]StepMeta, error)
+
+	// ListStepIDs returns the IDs of all steps, including steps without
+	// metadata, such as steps of builds from before step metadata was added.
+	ListStepIDs() ([]uint64, error)
+
 	// Freeze waits for all write operations to finish, closes any open writers
 	// and causes future write operations to error. This cannot be undone.
 	//
@@ -155,6 +224,10 @@ type LogLineWriteCloser interface {
 	// to write, such as if the file system has run out of disk space or if the
 	// file was removed.
 	WriteLogLine(line string) (LogLine, error)
+	// WriteContainerLogLine works like WriteLogLine, but also stores what
+	// container and output stream the log line came from. If the line does
+	// not start with a timestamp, then the current time is used.
+	WriteContainerLogLine(container string, stream LogStream, line string) (LogLine, error)
 }
 
 // LogLineReadCloser is the interface for reading log lines and ability to
@@ -173,22 +246,159 @@ type LogLineReadCloser interface {
 	ReadLastLogLine() (LogLine, error)
 }
 
-// NewStoreForBuildID creates a new store using a newly created temporary
-// directory. The location depends on the OS, e.g:
+// legacyBuildDirPrefix is the prefix of the temporary directories of builds
+// from before they were grouped per project.
+const legacyBuildDirPrefix = "wharf-cmd-build-"
+
+// BuildsDir returns the path to the directory that contains the result store
+// directories of all builds, grouped per project. The location depends on
+// the OS, e.g:
 //
-//  - Linux:   /tmp/wharf-cmd-build-00123-xxxx
-//  - Windows: C:/Temp/wharf-cmd-build-00123-xxxx
+//  - Linux:   /tmp/wharf-cmd-builds
+//  - Windows: C:/Temp/wharf-cmd-builds
 //
 // It gets this path via os.TempDir, which itself is OS dependent:
 //
 //  - Unix:    $TMPDIR or "/tmp"
 //  - Windows: GetTempPath syscall, which uses %TMP%, %TEMP%, %USERPROFILE%
-func NewStoreForBuildID(buildID uint) (Store, error) {
-	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("wharf-cmd-build-%05d-", buildID))
+func BuildsDir() string {
+	return filepath.Join(os.TempDir(), "wharf-cmd-builds")
+}
+
+// ProjectBuildsDir returns the path to the directory that contains the result
+// store directories of all builds of a project.
+func ProjectBuildsDir(projectKey string) string {
+	return filepath.Join(BuildsDir(), projectKey)
+}
+
+// NewStoreForBuildID creates a new store using a newly created directory
+// inside the project's builds directory, e.g:
+//
+//  /tmp/wharf-cmd-builds/iver-wharf-wharf-cmd-1a2b3c4d/build-00123-xxxx
+//
+// See BuildsDir for the OS dependent location.
+func NewStoreForBuildID(projectKey string, buildID uint) (Store, error) {
+	projectDir := ProjectBuildsDir(projectKey)
+	if err := os.MkdirAll(projectDir, 0775); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(projectDir, fmt.Sprintf("build-%05d-", buildID))
 	if err != nil {
 		return nil, err
 	}
-	return NewStore(NewFS(tmpDir)), nil
+	return NewStore(NewFS(dir)), nil
+}
+
+// OpenStoreForBuildID opens the store of a previous build of a project, as
+// created by NewStoreForBuildID. If multiple directories exist for the same
+// build ID, such as from dry-runs, then the most recently modified directory
+// is used.
+//
+// The returned store is frozen, as it is only meant for reading.
+func OpenStoreForBuildID(projectKey string, buildID uint) (Store, error) {
+	pattern := filepath.Join(ProjectBuildsDir(projectKey), fmt.Sprintf("build-%05d-*", buildID))
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var latestDir string
+	var latestModTime time.Time
+	for _, dir := range matches {
+		stat, err := os.Stat(dir)
+		if err != nil || !stat.IsDir() {
+			continue
+		}
+		if latestDir == "" || stat.ModTime().After(latestModTime) {
+			latestDir = dir
+			latestModTime = stat.ModTime()
+		}
+	}
+	if latestDir == "" {
+		return nil, fmt.Errorf("no results found for build %d: %w", buildID, fs.ErrNotExist)
+	}
+	s := NewStore(NewFS(latestDir))
+	if err := s.Freeze(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// BuildDir is the result store directory of a build, as created by
+// NewStoreForBuildID.
+type BuildDir struct {
+	ProjectKey string
+	BuildID    uint
+	Path       string
+}
+
+// ListBuildDirs returns the result store directories of all builds of a
+// project, or of all projects if the project key is empty. Directories are
+// sorted by project key and then by build ID.
+func ListBuildDirs(projectKey string) ([]BuildDir, error) {
+	projectPattern := projectKey
+	if projectPattern == "" {
+		projectPattern = "*"
+	}
+	matches, err := filepath.Glob(filepath.Join(BuildsDir(), projectPattern, "build-*"))
+	if err != nil {
+		return nil, err
+	}
+	var dirs []BuildDir
+	for _, dir := range matches {
+		var buildID uint
+		if _, err := fmt.Sscanf(filepath.Base(dir), "build-%05d-", &buildID); err != nil {
+			continue
+		}
+		if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+			continue
+		}
+		dirs = append(dirs, BuildDir{
+			ProjectKey: filepath.Base(filepath.Dir(dir)),
+			BuildID:    buildID,
+			Path:       dir,
+		})
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		if dirs[i].ProjectKey != dirs[j].ProjectKey {
+			return dirs[i].ProjectKey < dirs[j].ProjectKey
+		}
+		return dirs[i].BuildID < dirs[j].BuildID
+	})
+	return dirs, nil
+}
+
+// MigrateLegacyStores moves the result store directories of builds from
+// before they were grouped per project, such as /tmp/wharf-cmd-build-00123-xxxx,
+// into the given project's builds directory. As it is unknown which project
+// those builds belong to, they should be moved into a dedicated project.
+//
+// Returns the paths of the moved directories, after moving them.
+func MigrateLegacyStores(projectKey string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), legacyBuildDirPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	projectDir := ProjectBuildsDir(projectKey)
+	if err := os.MkdirAll(projectDir, 0775); err != nil {
+		return nil, err
+	}
+	var moved []string
+	for _, oldDir := range matches {
+		stat, err := os.Stat(oldDir)
+		if err != nil || !stat.IsDir() {
+			continue
+		}
+		name := "build-" + strings.TrimPrefix(filepath.Base(oldDir), legacyBuildDirPrefix)
+		newDir := filepath.Join(projectDir, name)
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return moved, err
+		}
+		moved = append(moved, newDir)
+	}
+	return moved, nil
 }
 
 // NewStore creates a new store using a given filesystem.
@@ -285,6 +495,9 @@ func (s *store) Close() error {
 
 func (s *store) listAllStepIDs() ([]uint64, error) {
 	entries, err := s.fs.ListDirEntries(dirNameSteps)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []uint64{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
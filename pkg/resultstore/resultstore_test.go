@@ -3,6 +3,8 @@ package resultstore
 import (
 	"errors"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -90,3 +92,74 @@ func TestStore_Close(t *testing.T) {
 	err = store.Close()
 	assert.NoError(t, err, "close store again")
 }
+
+func TestStoreForBuildIDGroupedPerProject(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	storeA, err := NewStoreForBuildID("project-a", 1)
+	require.NoError(t, err)
+	require.NoError(t, storeA.SetBuildMeta(BuildMeta{BuildID: 1, Project: "a"}))
+	require.NoError(t, storeA.Close())
+	storeB, err := NewStoreForBuildID("project-b", 1)
+	require.NoError(t, err)
+	require.NoError(t, storeB.SetBuildMeta(BuildMeta{BuildID: 1, Project: "b"}))
+	require.NoError(t, storeB.Close())
+
+	assert.Equal(t, ProjectBuildsDir("project-a"), filepath.Dir(storeA.Path()))
+
+	opened, err := OpenStoreForBuildID("project-b", 1)
+	require.NoError(t, err)
+	defer opened.Close()
+	meta, err := opened.ReadBuildMeta()
+	require.NoError(t, err)
+	assert.Equal(t, "b", meta.Project)
+
+	_, err = OpenStoreForBuildID("project-c", 1)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestMigrateLegacyStores(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "wharf-cmd-build-00012-abc"), 0775))
+
+	moved, err := MigrateLegacyStores("legacy")
+	require.NoError(t, err)
+	wantDir := filepath.Join(ProjectBuildsDir("legacy"), "build-00012-abc")
+	assert.Equal(t, []string{wantDir}, moved)
+	assert.DirExists(t, wantDir)
+	assert.NoDirExists(t, filepath.Join(tmpDir, "wharf-cmd-build-00012-abc"))
+
+	store, err := OpenStoreForBuildID("legacy", 12)
+	require.NoError(t, err)
+	store.Close()
+
+	moved, err = MigrateLegacyStores("legacy")
+	require.NoError(t, err)
+	assert.Empty(t, moved)
+}
+
+func TestListBuildDirs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	for _, b := range []struct {
+		project string
+		buildID uint
+	}{{"project-b", 2}, {"project-a", 10}, {"project-a", 9}} {
+		store, err := NewStoreForBuildID(b.project, b.buildID)
+		require.NoError(t, err)
+		require.NoError(t, store.Close())
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(ProjectBuildsDir("project-a"), "build-not-a-dir"), nil, 0644))
+
+	dirs, err := ListBuildDirs("project-a")
+	require.NoError(t, err)
+	require.Len(t, dirs, 2)
+	assert.Equal(t, uint(9), dirs[0].BuildID)
+	assert.Equal(t, uint(10), dirs[1].BuildID)
+	assert.Equal(t, "project-a", dirs[0].ProjectKey)
+
+	dirs, err = ListBuildDirs("")
+	require.NoError(t, err)
+	require.Len(t, dirs, 3)
+	assert.Equal(t, "project-b", dirs[2].ProjectKey)
+}
@@ -17,6 +17,14 @@ var (
 )
 
 func (s *store) AddStatusUpdate(stepID uint64, timestamp time.Time, newStatus workermodel.Status) error {
+	return s.addStatusUpdate(stepID, timestamp, newStatus, nil)
+}
+
+func (s *store) AddTerminatedStatusUpdate(stepID uint64, timestamp time.Time, newStatus workermodel.Status, termination workermodel.Termination) error {
+	return s.addStatusUpdate(stepID, timestamp, newStatus, &termination)
+}
+
+func (s *store) addStatusUpdate(stepID uint64, timestamp time.Time, newStatus workermodel.Status, termination *workermodel.Termination) error {
 	s.statusMutex.LockKey(stepID)
 	defer s.statusMutex.UnlockKey(stepID)
 	if s.frozen {
@@ -33,10 +41,11 @@ func (s *store) AddStatusUpdate(stepID uint64, timestamp time.Time, newStatus wo
 	list.LastID++
 	updateID := list.LastID
 	statusUpdate := StatusUpdate{
-		StepID:    stepID,
-		UpdateID:  updateID,
-		Timestamp: timestamp,
-		Status:    newStatus,
+		StepID:      stepID,
+		UpdateID:    updateID,
+		Timestamp:   timestamp,
+		Status:      newStatus,
+		Termination: termination,
 	}
 	list.StatusUpdates = append(list.StatusUpdates, statusUpdate)
 	if err := s.writeStatusUpdatesFile(stepID, list); err != nil {
@@ -46,6 +55,16 @@ func (s *store) AddStatusUpdate(stepID uint64, timestamp time.Time, newStatus wo
 	return nil
 }
 
+func (s *store) ListStatusUpdates(stepID uint64) ([]StatusUpdate, error) {
+	s.statusMutex.LockKey(stepID)
+	defer s.statusMutex.UnlockKey(stepID)
+	list, err := s.readStatusUpdatesFile(stepID)
+	if err != nil {
+		return nil, err
+	}
+	return list.StatusUpdates, nil
+}
+
 func (s *store) readStatusUpdatesFile(stepID uint64) (StatusList, error) {
 	file, err := s.fs.OpenRead(s.resolveStatusPath(stepID))
 	if errors.Is(err, fs.ErrNotExist) {
@@ -197,6 +197,44 @@ func TestStore_AddStatusUpdateSecond(t *testing.T) {
 	assert.JSONEq(t, want, buf.String())
 }
 
+func TestStore_AddTerminatedStatusUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStore(mockFS{
+		openRead: func(name string) (io.ReadCloser, error) {
+			return nil, fs.ErrNotExist
+		},
+		openWrite: func(name string) (io.WriteCloser, error) {
+			return nopWriteCloser{&buf}, nil
+		},
+	})
+	const stepID uint64 = 1
+	err := s.AddTerminatedStatusUpdate(stepID, sampleTime, workermodel.StatusFailed, workermodel.Termination{
+		ExitCode:   137,
+		Reason:     "OOMKilled",
+		StartedAt:  sampleTime,
+		FinishedAt: sampleTime,
+	})
+	require.NoError(t, err)
+	want := fmt.Sprintf(`
+{
+	"lastId": 1,
+	"statusUpdates": [
+		{
+			"updateId": 1,
+			"timestamp": "%[1]s",
+			"status": "Failed",
+			"termination": {
+				"exitCode": 137,
+				"reason": "OOMKilled",
+				"startedAt": "%[1]s",
+				"finishedAt": "%[1]s"
+			}
+		}
+	]
+}`, sampleTimeStr)
+	assert.JSONEq(t, want, buf.String())
+}
+
 func TestStore_AddStatusUpdateSkipIfSameStatus(t *testing.T) {
 	content := `{
 	"statusUpdates": [
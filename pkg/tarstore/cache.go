@@ -0,0 +1,282 @@
+package tarstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/filecopy"
+	"github.com/iver-wharf/wharf-cmd/internal/ignorer"
+)
+
+const (
+	cacheTarballsDir = "tarballs"
+	cacheTempDir     = "tmp"
+	cacheTarballExt  = ".tar"
+
+	// cacheKeyVersion is included in all cache keys, and must be changed
+	// whenever the tarball format or the key computation changes, so old
+	// tarballs are not reused.
+	cacheKeyVersion = "wharf-cmd-tarball-v1"
+
+	// staleTempDirAge is how old a temporary directory must be for it to be
+	// removed when pruning. Newer ones may still be in use by a running
+	// build.
+	staleTempDirAge = 24 * time.Hour
+)
+
+// Cache is a persistent directory of tarballs that is shared between runs.
+// Tarballs are keyed by a hash of the included files together with the
+// configuration of the copier. When the total size exceeds the max size,
+// the least recently used tarballs are evicted.
+type Cache struct {
+	// Dir is the path to the cache directory. It is created if it does not
+	// exist.
+	Dir string
+	// MaxSize is the max total size in bytes of all tarballs in the cache.
+	// Zero means no limit.
+	MaxSize int64
+}
+
+// CacheEntry is a tarball in the cache.
+type CacheEntry struct {
+	Key      string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// DefaultCacheDir returns the default path of the tarball cache directory,
+// inside the user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iver-wharf", "wharf-cmd", "tarstore"), nil
+}
+
+// Entries returns all tarballs in the cache, sorted with the most recently
+// used first.
+func (c Cache) Entries() ([]CacheEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(c.Dir, cacheTarballsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []CacheEntry
+	for _, d := range dirEntries {
+		name := d.Name()
+		if !d.Type().IsRegular() || !strings.HasSuffix(name, cacheTarballExt) {
+			continue
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Evicted by another process since reading the directory.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, CacheEntry{
+			Key:      strings.TrimSuffix(name, cacheTarballExt),
+			Path:     filepath.Join(c.Dir, cacheTarballsDir, name),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune removes the least recently used tarballs until the total size of the
+// cache is at most maxSize bytes. A maxSize of zero removes all tarballs.
+// Temporary directories left behind by runs that were killed are also
+// removed. Returns the removed tarballs.
+func (c Cache) Prune(maxSize int64) ([]CacheEntry, error) {
+	removed, err := c.evict(maxSize)
+	if err != nil {
+		return removed, err
+	}
+	return removed, c.removeStaleTempDirs()
+}
+
+func (c Cache) evict(maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	var removed []CacheEntry
+	for _, e := range entries {
+		total += e.Size
+		if total <= maxSize {
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+func (c Cache) removeStaleTempDirs() error {
+	tempDir := filepath.Join(c.Dir, cacheTempDir)
+	dirEntries, err := os.ReadDir(tempDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, d := range dirEntries {
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) < staleTempDirAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(tempDir, d.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c Cache) tarballPath(key string) string {
+	return filepath.Join(c.Dir, cacheTarballsDir, key+cacheTarballExt)
+}
+
+func (c Cache) mkdirTemp() (string, error) {
+	tempDir := filepath.Join(c.Dir, cacheTempDir)
+	if err := os.MkdirAll(tempDir, dirFileMode); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(tempDir, "wharf-cmd-repo-")
+}
+
+// linkTo hard links the cached tarball to the given path, and marks it as
+// recently used. Returns false if the tarball is not in the cache.
+//
+// Hard links are used so the tarball stays available to the caller even if
+// it is evicted from the cache by another run.
+func (c Cache) linkTo(key, path string) (bool, error) {
+	cachedPath := c.tarballPath(key)
+	if err := os.Link(cachedPath, path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	now := time.Now()
+	if err := os.Chtimes(cachedPath, now, now); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Debug().
+			WithError(err).
+			WithString("key", key).
+			Message("Failed to mark cached tarball as recently used.")
+	}
+	return true, nil
+}
+
+// add hard links the tarball into the cache, and then evicts the least
+// recently used tarballs if the cache is over its max size.
+func (c Cache) add(key, path string) error {
+	if err := os.MkdirAll(filepath.Join(c.Dir, cacheTarballsDir), dirFileMode); err != nil {
+		return err
+	}
+	// Another run may have added the same tarball concurrently, which is
+	// fine as both have the same content.
+	if err := os.Link(path, c.tarballPath(key)); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	removed, err := c.evict(c.MaxSize)
+	for _, e := range removed {
+		log.Debug().
+			WithString("key", e.Key).
+			WithInt64("size", e.Size).
+			Message("Evicted tarball from cache.")
+	}
+	return err
+}
+
+// cacheKey returns a hash of the files in the source directory that are not
+// ignored, including their paths, modes, and content, together with the
+// cache key of the copier. Returns false if the copier does not support
+// caching.
+//
+// The files are walked in the same way as filecopy.CopyDirIgnorer, so the
+// key covers exactly the files that would be included in the tarball.
+func cacheKey(srcPath string, copier filecopy.Copier, ign ignorer.Ignorer) (string, bool, error) {
+	keyer, ok := copier.(filecopy.CacheKeyer)
+	if !ok {
+		return "", false, nil
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\ncopier %q\n", cacheKeyVersion, keyer.CacheKey())
+	err := fs.WalkDir(os.DirFS(srcPath), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		absPath := filepath.Join(srcPath, path)
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return err
+		}
+		if ign != nil && ign.Ignore(path) {
+			if info.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			fmt.Fprintf(hash, "dir %q %o\n", path, info.Mode())
+			return nil
+		}
+		if info.Mode().Type() != 0 {
+			return nil
+		}
+		fileHash, err := hashFile(absPath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "file %q %o %s\n", path, info.Mode(), fileHash)
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), true, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,146 @@
+package tarstore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/filecopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUncachedCopier struct{}
+
+func (testUncachedCopier) Copy(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func prepareTestTarball(t *testing.T, srcDir string, cache Cache, copier filecopy.Copier) {
+	store, err := NewWithCache(srcDir, cache)
+	require.NoError(t, err)
+	defer store.Close()
+	tarball, err := store.GetPreparedTarball(context.Background(), copier, nil, "full")
+	require.NoError(t, err)
+	_, err = os.Stat(string(tarball))
+	require.NoError(t, err)
+}
+
+func TestStoreWithCache(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("hello"), 0644))
+	cache := Cache{Dir: t.TempDir()}
+
+	prepareTestTarball(t, srcDir, cache, filecopy.IOCopier)
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	firstKey := entries[0].Key
+
+	prepareTestTarball(t, srcDir, cache, filecopy.IOCopier)
+	entries, err = cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "unchanged files reuse the cached tarball")
+
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("world"), 0644))
+	prepareTestTarball(t, srcDir, cache, filecopy.IOCopier)
+	entries, err = cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "changed files add a new tarball")
+	assert.NotEqual(t, firstKey, entries[0].Key)
+
+	tempDirs, err := os.ReadDir(filepath.Join(cache.Dir, cacheTempDir))
+	require.NoError(t, err)
+	assert.Empty(t, tempDirs, "closed stores remove their temp dirs")
+}
+
+func TestStoreWithCache_uncachedCopier(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("hello"), 0644))
+	cache := Cache{Dir: t.TempDir()}
+
+	prepareTestTarball(t, srcDir, cache, testUncachedCopier{})
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCacheKey(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "b.txt"), []byte("hello"), 0644))
+
+	key, ok, err := cacheKey(srcDir, filecopy.IOCopier, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ignoredKey, _, err := cacheKey(srcDir, filecopy.IOCopier, testIgnorer("b.txt"))
+	require.NoError(t, err)
+	assert.NotEqual(t, key, ignoredKey, "ignored files are excluded from the key")
+
+	require.NoError(t, os.Chmod(filepath.Join(srcDir, "a.txt"), 0755))
+	chmodKey, _, err := cacheKey(srcDir, filecopy.IOCopier, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, chmodKey, "file modes are included in the key")
+
+	_, ok, err = cacheKey(srcDir, testUncachedCopier{}, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+type testIgnorer string
+
+func (i testIgnorer) Ignore(relPath string) bool {
+	return relPath == string(i)
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := Cache{Dir: t.TempDir()}
+	require.NoError(t, os.MkdirAll(filepath.Join(cache.Dir, cacheTarballsDir), dirFileMode))
+	now := time.Now()
+	for i, key := range []string{"newest", "middle", "oldest"} {
+		path := cache.tarballPath(key)
+		require.NoError(t, os.WriteFile(path, make([]byte, 100), 0644))
+		usedAt := now.Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, os.Chtimes(path, usedAt, usedAt))
+	}
+	staleTempDir := filepath.Join(cache.Dir, cacheTempDir, "wharf-cmd-repo-stale")
+	require.NoError(t, os.MkdirAll(staleTempDir, dirFileMode))
+	staleAt := now.Add(-2 * staleTempDirAge)
+	require.NoError(t, os.Chtimes(staleTempDir, staleAt, staleAt))
+
+	removed, err := cache.Prune(250)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "oldest", removed[0].Key)
+	assert.NoDirExists(t, staleTempDir)
+
+	removed, err = cache.Prune(0)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCacheAdd_evictsLeastRecentlyUsed(t *testing.T) {
+	cache := Cache{Dir: t.TempDir(), MaxSize: 150}
+	src := filepath.Join(t.TempDir(), "src.tar")
+	require.NoError(t, os.WriteFile(src, make([]byte, 100), 0644))
+	require.NoError(t, cache.add("first", src))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(cache.tarballPath("first"), old, old))
+
+	src2 := filepath.Join(t.TempDir(), "src2.tar")
+	require.NoError(t, os.WriteFile(src2, make([]byte, 100), 0644))
+	require.NoError(t, cache.add("second", src2))
+
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Key)
+}
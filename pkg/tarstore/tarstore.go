@@ -1,6 +1,7 @@
 package tarstore
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/fs"
@@ -10,11 +11,17 @@ import (
 	"github.com/iver-wharf/wharf-cmd/internal/filecopy"
 	"github.com/iver-wharf/wharf-cmd/internal/ignorer"
 	"github.com/iver-wharf/wharf-cmd/internal/tarutil"
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/typ.v4/sync2"
 )
 
-var log = logger.NewScoped("TARSTORE")
+var (
+	log    = logger.NewScoped("TARSTORE")
+	tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/pkg/tarstore")
+)
 
 const dirFileMode fs.FileMode = 0775
 
@@ -30,7 +37,10 @@ func (t Tarball) Open() (io.ReadCloser, error) {
 type Store interface {
 	io.Closer
 
-	GetPreparedTarball(copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error)
+	// GetPreparedTarball returns the tarball with the given ID, creating it
+	// on the first call. Concurrent calls with the same ID wait for the
+	// same tarball to be created.
+	GetPreparedTarball(ctx context.Context, copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error)
 }
 
 // New creates a new Store with a given directory path as the repo root.
@@ -46,9 +56,28 @@ func New(srcPath string) (Store, error) {
 	}, nil
 }
 
+// NewWithCache creates a new Store with a given directory path as the repo
+// root, that reuses tarballs from the persistent cache when the included
+// files have not changed since a previous run, and adds new tarballs to it.
+//
+// Tarballs created with a copier that does not implement
+// filecopy.CacheKeyer are never cached.
+func NewWithCache(srcPath string, cache Cache) (Store, error) {
+	tmpPath, err := cache.mkdirTemp()
+	if err != nil {
+		return nil, err
+	}
+	return &store{
+		tmpPath: tmpPath,
+		srcPath: srcPath,
+		cache:   &cache,
+	}, nil
+}
+
 type store struct {
 	tmpPath string
 	srcPath string
+	cache   *Cache
 	onceMap sync2.Map[string, *sync2.Once2[Tarball, error]]
 }
 
@@ -56,17 +85,67 @@ func (s *store) Close() error {
 	return os.RemoveAll(s.tmpPath)
 }
 
-func (s *store) GetPreparedTarball(copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error) {
+func (s *store) GetPreparedTarball(ctx context.Context, copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error) {
 	if id == "" {
 		return "", errors.New("tarball name cannot be empty")
 	}
+	ctx, span := tracer.Start(ctx, "prepare repo tarball")
+	defer span.End()
+	span.SetAttributes(attribute.String("wharf.tarball.id", id))
 	once, _ := s.onceMap.LoadOrStore(id, new(sync2.Once2[Tarball, error]))
-	return once.Do(func() (Tarball, error) {
-		return s.prepare(copier, ignorer, id)
+	created := false
+	tarball, err := once.Do(func() (Tarball, error) {
+		created = true
+		if s.cache != nil {
+			return s.prepareCached(ctx, copier, ignorer, id)
+		}
+		return s.prepare(ctx, copier, ignorer, id)
 	})
+	span.SetAttributes(attribute.Bool("wharf.tarball.created", created))
+	tracing.RecordError(span, err)
+	return tarball, err
+}
+
+func (s *store) prepareCached(ctx context.Context, copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error) {
+	_, hashSpan := tracer.Start(ctx, "hash repo files")
+	key, ok, err := cacheKey(s.srcPath, copier, ignorer)
+	tracing.RecordError(hashSpan, err)
+	hashSpan.End()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return s.prepare(ctx, copier, ignorer, id)
+	}
+	tarPath := filepath.Join(s.tmpPath, id+".tar")
+	hit, err := s.cache.linkTo(key, tarPath)
+	if err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("key", key).
+			Message("Failed to read tarball from cache. Creating it instead.")
+	}
+	if hit {
+		log.Info().
+			WithString("key", key).
+			WithString("path", tarPath).
+			Message("Reusing cached tarball.")
+		return Tarball(tarPath), nil
+	}
+	tarball, err := s.prepare(ctx, copier, ignorer, id)
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.add(key, string(tarball)); err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("key", key).
+			Message("Failed to add tarball to cache.")
+	}
+	return tarball, nil
 }
 
-func (s *store) prepare(copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error) {
+func (s *store) prepare(ctx context.Context, copier filecopy.Copier, ignorer ignorer.Ignorer, id string) (Tarball, error) {
 	dstPath := filepath.Join(s.tmpPath, id)
 	if err := os.MkdirAll(dstPath, dirFileMode); err != nil {
 		return "", err
@@ -75,7 +154,11 @@ func (s *store) prepare(copier filecopy.Copier, ignorer ignorer.Ignorer, id stri
 		WithString("src", s.srcPath).
 		WithString("dst", dstPath).
 		Message("Copying files.")
-	if err := filecopy.CopyDirIgnorer(dstPath, s.srcPath, copier, ignorer); err != nil {
+	_, copySpan := tracer.Start(ctx, "copy repo files")
+	err := filecopy.CopyDirIgnorer(dstPath, s.srcPath, copier, ignorer)
+	tracing.RecordError(copySpan, err)
+	copySpan.End()
+	if err != nil {
 		return "", err
 	}
 	log.Debug().
@@ -91,7 +174,11 @@ func (s *store) prepare(copier filecopy.Copier, ignorer ignorer.Ignorer, id stri
 	log.Info().
 		WithString("path", tarPath).
 		Message("Creating tarball.")
-	if err := tarutil.Dir(tarFile, dstPath); err != nil {
+	_, tarSpan := tracer.Start(ctx, "create tarball")
+	err = tarutil.Dir(tarFile, dstPath)
+	tracing.RecordError(tarSpan, err)
+	tarSpan.End()
+	if err != nil {
 		return "", err
 	}
 	log.Debug().
@@ -0,0 +1,178 @@
+// Package tracing sets up OpenTelemetry tracing, where spans are exported via
+// OTLP over HTTP, and propagates the trace context between the different
+// wharf-cmd components, such as from the wharf-cmd-provisioner to the
+// wharf-cmd-worker pods it creates.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var log = logger.NewScoped("TRACING")
+
+// Environment variables used to propagate the trace context into processes,
+// such as into the wharf-cmd-worker pods. The names and values follow the W3C
+// Trace Context specification.
+const (
+	EnvTraceParent = "TRACEPARENT"
+	EnvTraceState  = "TRACESTATE"
+)
+
+// Kubernetes annotations used to propagate the trace context via a pod's
+// metadata, such as from the wharf-cmd-provisioner to the
+// wharf-cmd-aggregator.
+const (
+	AnnotationTraceParent = "wharf.iver.com/traceparent"
+	AnnotationTraceState  = "wharf.iver.com/tracestate"
+)
+
+const defaultTracesURLPath = "/v1/traces"
+
+var propagator = propagation.TraceContext{}
+
+// ShutdownFunc flushes any remaining spans and stops the exporter.
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup registers the global OpenTelemetry tracer provider and propagator.
+// When tracing is disabled in the config, the global tracer provider is left
+// as a no-op, while the trace context is still propagated.
+func Setup(cfg config.TracingConfig, serviceName, serviceVersion string) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts, err := exporterOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(serviceVersion),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Debug().WithError(err).Message("OpenTelemetry error.")
+	}))
+	log.Debug().
+		WithString("endpoint", loggedEndpoint(cfg)).
+		WithString("service", serviceName).
+		Message("Exporting traces.")
+	return provider.Shutdown, nil
+}
+
+func exporterOptions(cfg config.TracingConfig) ([]otlptracehttp.Option, error) {
+	if cfg.Endpoint == "" {
+		// Falls back to the OTEL_EXPORTER_OTLP_* environment variables,
+		// which are read by the exporter itself.
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse tracing endpoint: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("parse tracing endpoint: missing host: %q", cfg.Endpoint)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("parse tracing endpoint: unsupported scheme %q, must be http or https", u.Scheme)
+	}
+	urlPath := strings.TrimSuffix(u.Path, "/")
+	if urlPath == "" {
+		urlPath = defaultTracesURLPath
+	}
+	opts = append(opts, otlptracehttp.WithURLPath(urlPath))
+	return opts, nil
+}
+
+func loggedEndpoint(cfg config.TracingConfig) string {
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// EnvFromContext returns the environment variables that propagate the trace
+// context of the current span, or an empty map if there is no span.
+func EnvFromContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	env := make(map[string]string, len(carrier))
+	if v := carrier.Get("traceparent"); v != "" {
+		env[EnvTraceParent] = v
+	}
+	if v := carrier.Get("tracestate"); v != "" {
+		env[EnvTraceState] = v
+	}
+	return env
+}
+
+// ContextFromEnv returns a copy of the context with the remote span context
+// read from the TRACEPARENT and TRACESTATE environment variables, if set.
+func ContextFromEnv(ctx context.Context) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier{
+		"traceparent": os.Getenv(EnvTraceParent),
+		"tracestate":  os.Getenv(EnvTraceState),
+	})
+}
+
+// AnnotationsFromContext returns the Kubernetes annotations that propagate
+// the trace context of the current span, or an empty map if there is no span.
+func AnnotationsFromContext(ctx context.Context) map[string]string {
+	env := EnvFromContext(ctx)
+	annotations := make(map[string]string, len(env))
+	if v, ok := env[EnvTraceParent]; ok {
+		annotations[AnnotationTraceParent] = v
+	}
+	if v, ok := env[EnvTraceState]; ok {
+		annotations[AnnotationTraceState] = v
+	}
+	return annotations
+}
+
+// ContextFromAnnotations returns a copy of the context with the remote span
+// context read from the Kubernetes annotations, if set.
+func ContextFromAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier{
+		"traceparent": annotations[AnnotationTraceParent],
+		"tracestate":  annotations[AnnotationTraceState],
+	})
+}
+
+// RecordError records the error on the span and marks the span as failed.
+// Does nothing if the error is nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
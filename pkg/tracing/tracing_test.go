@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCollector is a stand-in for an OTLP/HTTP collector, that records the
+// spans it receives.
+type testCollector struct {
+	*httptest.Server
+	mutex sync.Mutex
+	paths []string
+	spans []*tracepb.Span
+}
+
+func newTestCollector(t *testing.T) *testCollector {
+	c := &testCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.mutex.Lock()
+		c.paths = append(c.paths, r.URL.Path)
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+		c.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestSetup_exportsToCollector(t *testing.T) {
+	collector := newTestCollector(t)
+	shutdown, err := Setup(config.TracingConfig{
+		Enabled:     true,
+		Endpoint:    collector.URL,
+		SampleRatio: 1,
+	}, "wharf-cmd-test", "v0.0.0")
+	require.NoError(t, err)
+
+	tracer := otel.Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "build")
+	_, child := tracer.Start(ctx, "stage")
+	child.End()
+	parent.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	assert.Equal(t, []string{defaultTracesURLPath}, collector.paths)
+	require.Len(t, collector.spans, 2)
+	assert.Equal(t, "stage", collector.spans[0].Name)
+	assert.Equal(t, "build", collector.spans[1].Name)
+	assert.Equal(t, collector.spans[1].SpanId, collector.spans[0].ParentSpanId)
+}
+
+func TestSetup_disabled(t *testing.T) {
+	shutdown, err := Setup(config.TracingConfig{}, "wharf-cmd-test", "v0.0.0")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestExporterOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantErr  bool
+		wantOpts int
+	}{
+		{name: "empty uses env vars", endpoint: "", wantOpts: 0},
+		{name: "http", endpoint: "http://localhost:4318", wantOpts: 3},
+		{name: "https with path", endpoint: "https://otel.example.com/custom/v1/traces", wantOpts: 2},
+		{name: "missing scheme", endpoint: "localhost:4318", wantErr: true},
+		{name: "invalid scheme", endpoint: "grpc://localhost:4317", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := exporterOptions(config.TracingConfig{Endpoint: tc.endpoint})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, opts, tc.wantOpts)
+		})
+	}
+}
+
+func TestEnvRoundTrip(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), newTestSpanContext())
+	env := EnvFromContext(ctx)
+	assert.Equal(t, map[string]string{
+		EnvTraceParent: "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01",
+	}, env)
+
+	t.Setenv(EnvTraceParent, env[EnvTraceParent])
+	got := trace.SpanContextFromContext(ContextFromEnv(context.Background()))
+	assert.Equal(t, newTestSpanContext().TraceID(), got.TraceID())
+	assert.Equal(t, newTestSpanContext().SpanID(), got.SpanID())
+	assert.True(t, got.IsRemote())
+}
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), newTestSpanContext())
+	annotations := AnnotationsFromContext(ctx)
+	require.Contains(t, annotations, AnnotationTraceParent)
+
+	got := trace.SpanContextFromContext(ContextFromAnnotations(context.Background(), annotations))
+	assert.Equal(t, newTestSpanContext().TraceID(), got.TraceID())
+
+	empty := trace.SpanContextFromContext(ContextFromAnnotations(context.Background(), nil))
+	assert.False(t, empty.IsValid())
+	assert.Empty(t, EnvFromContext(context.Background()))
+}
+
+func newTestSpanContext() trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+}
@@ -0,0 +1,40 @@
+package watchdog
+
+import (
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsSubsystem = "watchdog"
+
+var (
+	checksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "checks_total",
+		Help:      "Number of checks for stray builds and workers, by result.",
+	}, []string{"result"})
+
+	killedBuilds = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "killed_builds_total",
+		Help:      "Number of stray builds that were marked as failed in the Wharf API.",
+	})
+
+	killedWorkers = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "killed_workers_total",
+		Help:      "Number of stray workers that were deleted via the provisioner.",
+	})
+)
+
+func observeCheck(err error) {
+	if err != nil {
+		checksTotal.WithLabelValues("error").Inc()
+	} else {
+		checksTotal.WithLabelValues("success").Inc()
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/iver-wharf/wharf-api-client-go/v2/pkg/model/response"
 	"github.com/iver-wharf/wharf-api-client-go/v2/pkg/wharfapi"
 	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
 	"github.com/iver-wharf/wharf-cmd/pkg/provisioner"
 	"github.com/iver-wharf/wharf-cmd/pkg/provisioner/provisionerclient"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
@@ -29,6 +30,7 @@ func Watch(config config.WatchdogConfig) error {
 			APIURL: config.ProvisionerURL,
 		},
 	}
+	metrics.ListenAndServe(config.MetricsBindAddress)
 	wd.startTicker()
 	return wd.listenForTicks()
 }
@@ -45,6 +47,7 @@ func (wd *watchdog) startTicker() {
 
 func (wd *watchdog) listenForTicks() error {
 	res, err := wd.performCheck(time.Now())
+	observeCheck(err)
 	if err != nil {
 		log.Error().
 			WithError(err).
@@ -59,6 +62,7 @@ func (wd *watchdog) listenForTicks() error {
 		Message("Done with initial check. Waiting for next interval tick.")
 	for now := range wd.ticker.C {
 		res, err := wd.performCheck(now)
+		observeCheck(err)
 		if err != nil {
 			log.Warn().
 				WithError(err).
@@ -139,6 +143,7 @@ func (wd *watchdog) killBuilds(buildsToKill []response.Build) error {
 			WithString("newStatus", string(updated.Status)).
 			WithTime("scheduled", b.ScheduledOn.Time).
 			Message("Killed stray build.")
+		killedBuilds.Inc()
 	}
 	return nil
 }
@@ -153,6 +158,7 @@ func (wd *watchdog) killWorkers(workersToKill []provisioner.Worker) error {
 		if err := wd.prov.DeleteWorker(w.WorkerID); err != nil {
 			return fmt.Errorf("kill worker by ID %q: %w", w.WorkerID, err)
 		}
+		killedWorkers.Inc()
 	}
 	return nil
 }
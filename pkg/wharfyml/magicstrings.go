@@ -5,6 +5,10 @@ const (
 	propInputs       = "inputs"
 	propEnvironments = "environments"
 	propRunsIf       = "runs-if"
+	propFailFast     = "fail-fast"
+	propMaxParallel  = "max-parallel"
+	propAllowFailure = "allow-failure"
+	propIgnore       = "ignore"
 
 	// Map keys in .wharf-vars.yml
 	propVars = "vars"
@@ -11,7 +11,8 @@ import (
 
 // Errors related to parsing stages.
 var (
-	ErrStageEmpty = errors.New("stage is missing steps")
+	ErrStageEmpty               = errors.New("stage is missing steps")
+	ErrStageMaxParallelNegative = errors.New("max parallel steps must not be negative")
 )
 
 // Stage holds the name, environment filter, and list of steps for this Wharf
@@ -24,10 +25,22 @@ type Stage struct {
 	Steps   []Step
 
 	RunsIf StageRunsIf
+	// FailFast controls if a failing step should cancel the other steps in the
+	// stage. A nil value means the default, which is true.
+	FailFast *bool
+	// MaxParallel is the maximum number of steps in the stage that may run at
+	// the same time. Zero means no limit.
+	MaxParallel int
 
 	Node visit.MapItem
 }
 
+// ShouldFailFast returns true if a failing step should cancel all other steps
+// in the stage.
+func (s Stage) ShouldFailFast() bool {
+	return s.FailFast == nil || *s.FailFast
+}
+
 // ShouldSkip returns true if the stage should be skipped based on its run
 // conditions.
 func (s Stage) ShouldSkip(anyPreviousStageHasFailed bool) bool {
@@ -69,6 +82,25 @@ func visitStageNode(nameNode visit.StringNode, node *yaml.Node, args Args, sourc
 			runsIf, errs := visitStageRunsIfNode(stepNode.Value)
 			stage.RunsIf = runsIf
 			errSlice.Add(errutil.ScopeSlice(errs, propRunsIf)...)
+		case propFailFast:
+			failFast, err := visit.Bool(stepNode.Value)
+			if err != nil {
+				errSlice.Add(errutil.Scope(err, propFailFast))
+				continue
+			}
+			stage.FailFast = &failFast
+		case propMaxParallel:
+			maxParallel, err := visit.Int(stepNode.Value)
+			if err != nil {
+				errSlice.Add(errutil.Scope(err, propMaxParallel))
+				continue
+			}
+			if maxParallel < 0 {
+				err := errutil.NewPosFromNode(ErrStageMaxParallelNegative, stepNode.Value)
+				errSlice.Add(errutil.Scope(err, propMaxParallel))
+				continue
+			}
+			stage.MaxParallel = maxParallel
 		default:
 			step, errs := visitStepNode(stepNode.Key, stepNode.Value, args, source)
 			stage.Steps = append(stage.Steps, step)
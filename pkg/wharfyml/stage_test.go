@@ -29,6 +29,52 @@ func TestVisitStage_Name(t *testing.T) {
 	assert.Equal(t, "myStage", stage.Name)
 }
 
+func TestVisitStage_FailFast(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStage:
+  fail-fast: false
+  myStep:
+    helm-package: {}
+`)
+	stage, errs := visitStageNode(key, node, Args{}, nil)
+	testutil.RequireNotContainsErr(t, errs, visit.ErrInvalidFieldType)
+	assert.Len(t, stage.Steps, 1)
+	assert.False(t, stage.ShouldFailFast())
+}
+
+func TestVisitStage_FailFastDefault(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStage:
+  myStep:
+    helm-package: {}
+`)
+	stage, _ := visitStageNode(key, node, Args{}, nil)
+	assert.True(t, stage.ShouldFailFast())
+}
+
+func TestVisitStage_MaxParallel(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStage:
+  max-parallel: 2
+  myStep:
+    helm-package: {}
+`)
+	stage, _ := visitStageNode(key, node, Args{}, nil)
+	assert.Len(t, stage.Steps, 1)
+	assert.Equal(t, 2, stage.MaxParallel)
+}
+
+func TestVisitStage_ErrIfMaxParallelNegative(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStage:
+  max-parallel: -1
+  myStep:
+    helm-package: {}
+`)
+	_, errs := visitStageNode(key, node, Args{}, nil)
+	testutil.RequireContainsErr(t, errs, ErrStageMaxParallelNegative)
+}
+
 func TestShouldRun(t *testing.T) {
 	testCases := []struct {
 		name                      string
@@ -2,6 +2,7 @@ package wharfyml
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/iver-wharf/wharf-cmd/internal/errutil"
 	"github.com/iver-wharf/wharf-cmd/pkg/varsub"
@@ -21,6 +22,14 @@ type Step struct {
 	Name string
 	Type StepType
 	Meta StepTypeMeta
+
+	// AllowFailure means a failure of this step will be recorded, but will not
+	// cancel the other steps in the stage nor fail the stage or build.
+	AllowFailure bool
+	// Ignore is a list of patterns, in .gitignore syntax, of files in the
+	// repository to not transfer to this step. They are applied on top of
+	// the .gitignore and .wharfignore files.
+	Ignore []string
 }
 
 func visitStepNode(name visit.StringNode, node *yaml.Node, args Args, source varsub.Source) (step Step, errSlice errutil.Slice) {
@@ -28,15 +37,32 @@ func visitStepNode(name visit.StringNode, node *yaml.Node, args Args, source var
 	step.Name = name.Value
 	nodes, errs := visit.MapSlice(node)
 	errSlice.Add(errs...)
-	if len(nodes) == 0 {
+	var stepTypeNodes []visit.MapItem
+	for _, n := range nodes {
+		switch n.Key.Value {
+		case propAllowFailure:
+			allowFailure, err := visit.Bool(n.Value)
+			if err != nil {
+				errSlice.Add(errutil.Scope(err, propAllowFailure))
+			}
+			step.AllowFailure = allowFailure
+		case propIgnore:
+			ignore, errs := visitStepIgnore(n.Value)
+			errSlice.Add(errs...)
+			step.Ignore = ignore
+		default:
+			stepTypeNodes = append(stepTypeNodes, n)
+		}
+	}
+	if len(stepTypeNodes) == 0 {
 		errSlice.Add(errutil.NewPosFromNode(ErrStepEmpty, node))
 		return
 	}
-	if len(nodes) > 1 {
+	if len(stepTypeNodes) > 1 {
 		errSlice.Add(errutil.NewPosFromNode(ErrStepMultipleStepTypes, node))
 		// Continue, it's not a fatal issue
 	}
-	for _, stepTypeNode := range nodes {
+	for _, stepTypeNode := range stepTypeNodes {
 		stepType, meta, errs := visitStepTypeNode(
 			name.Value, stepTypeNode.Key, stepTypeNode.Value, args, source)
 		step.Type = stepType
@@ -49,3 +75,21 @@ func visitStepNode(name visit.StringNode, node *yaml.Node, args Args, source var
 	}
 	return
 }
+
+func visitStepIgnore(node *yaml.Node) ([]string, errutil.Slice) {
+	seq, err := visit.Sequence(node)
+	if err != nil {
+		return nil, errutil.Slice{errutil.Scope(err, propIgnore)}
+	}
+	var errSlice errutil.Slice
+	patterns := make([]string, 0, len(seq))
+	for i, n := range seq {
+		pattern, err := visit.String(n)
+		if err != nil {
+			errSlice.Add(errutil.Scope(err, fmt.Sprintf("%s[%d]", propIgnore, i)))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, errSlice
+}
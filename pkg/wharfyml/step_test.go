@@ -41,3 +41,46 @@ myStep:
 	}
 	assert.Equal(t, "myStep", step.Name)
 }
+
+func TestVisitStep_AllowFailure(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStep:
+  allow-failure: true
+  helm-package: {}
+`)
+	step, errs := visitStepNode(key, node, Args{}, nil)
+	testutil.RequireNotContainsErr(t, errs, ErrStepMultipleStepTypes)
+	assert.True(t, step.AllowFailure)
+}
+
+func TestVisitStep_Ignore(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStep:
+  ignore:
+    - docs/
+    - "*.log"
+  helm-package: {}
+`)
+	step, errs := visitStepNode(key, node, Args{}, nil)
+	testutil.RequireNotContainsErr(t, errs, ErrStepMultipleStepTypes)
+	assert.Equal(t, []string{"docs/", "*.log"}, step.Ignore)
+}
+
+func TestVisitStep_ErrIfIgnoreNotList(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStep:
+  ignore: docs/
+  helm-package: {}
+`)
+	_, errs := visitStepNode(key, node, Args{}, nil)
+	testutil.RequireContainsErr(t, errs, visit.ErrInvalidFieldType)
+}
+
+func TestVisitStep_ErrIfOnlyAllowFailure(t *testing.T) {
+	key, node := testutil.NewKeyedNode(t, `
+myStep:
+  allow-failure: true
+`)
+	_, errs := visitStepNode(key, node, Args{}, nil)
+	testutil.RequireContainsErr(t, errs, ErrStepEmpty)
+}
@@ -4,13 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
 	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrNoStepsMatchFilter is returned when creating a builder where the step
+// filters exclude all steps, such as from a typo in a filter pattern.
+var ErrNoStepsMatchFilter = errors.New("no steps match the step filters")
+
+// ErrNothingToRerun is returned when creating a builder that re-runs a
+// previous build where all steps succeeded.
+var ErrNothingToRerun = errors.New("all steps succeeded in the build to re-run, nothing to re-run")
+
 type builder struct {
 	opts         BuildOptions
 	def          wharfyml.Definition
@@ -19,12 +30,28 @@ type builder struct {
 
 // New returns a new Builder implementation that uses the provided StageRunner
 // to run all build stages in series.
-func New(ctx context.Context, stageRunFactory StageRunnerFactory, def wharfyml.Definition, opts BuildOptions) (Builder, error) {
+func New(ctx context.Context, stageRunFactory StageRunnerFactory, def wharfyml.Definition, opts BuildOptions) (_ Builder, finalErr error) {
+	ctx, span := tracer.Start(ctx, "prepare steps")
+	defer func() {
+		tracing.RecordError(span, finalErr)
+		span.End()
+	}()
 	filteredStages := filterStages(def.Stages, opts.StageFilter)
+	filteredStages, err := filterStagesSteps(filteredStages, opts.StepFilter, opts.SkipStepFilter)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RerunSucceededSteps != nil {
+		filteredStages, err = filterStagesForRerun(filteredStages, opts.RerunSucceededSteps)
+		if err != nil {
+			return nil, err
+		}
+	}
 	stageRunners := make([]StageRunner, len(filteredStages))
 	stepIDOffset := uint64(1)
 	for i, stage := range filteredStages {
-		r, err := stageRunFactory.NewStageRunner(ctx, stage, stepIDOffset)
+		stageCtx := contextWithStageID(ctx, stageID(def.Stages, stage.Name))
+		r, err := stageRunFactory.NewStageRunner(stageCtx, stage, stepIDOffset)
 		stepIDOffset += uint64(len(stage.Steps))
 		if err != nil {
 			return nil, fmt.Errorf("stage %s: %w", stage.Name, err)
@@ -39,6 +66,17 @@ func New(ctx context.Context, stageRunFactory StageRunnerFactory, def wharfyml.D
 	}, nil
 }
 
+// stageID returns the 1-based index of the stage in the build definition,
+// which stays the same regardless of which stages are filtered out.
+func stageID(stages []wharfyml.Stage, name string) uint64 {
+	for i, stage := range stages {
+		if stage.Name == name {
+			return uint64(i + 1)
+		}
+	}
+	return 0
+}
+
 func (b builder) BuildOptions() BuildOptions {
 	return b.opts
 }
@@ -59,6 +97,11 @@ func (b builder) Build(ctx context.Context) (Result, error) {
 		result.Status = workermodel.StatusNone
 		return result, nil
 	}
+	ctx, span := tracer.Start(ctx, "run stages")
+	defer func() {
+		span.SetAttributes(attribute.Stringer("wharf.status", result.Status))
+		span.End()
+	}()
 	anyPreviousStageHasFailed := false
 	for _, stageRunner := range b.stageRunners {
 		stagesDone++
@@ -92,6 +135,7 @@ func (b builder) Build(ctx context.Context) (Result, error) {
 		result.Status = workermodel.StatusCancelled
 	}
 	result.Duration = time.Since(start)
+	buildDuration.WithLabelValues(result.Status.String()).Observe(result.Duration.Seconds())
 	return result, nil
 }
 
@@ -110,6 +154,95 @@ func filterStages(stages []wharfyml.Stage, nameFilter string) []wharfyml.Stage {
 	return result
 }
 
+func filterStagesSteps(stages []wharfyml.Stage, include, exclude []string) ([]wharfyml.Stage, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return stages, nil
+	}
+	if err := validateStepPatterns(include); err != nil {
+		return nil, err
+	}
+	if err := validateStepPatterns(exclude); err != nil {
+		return nil, err
+	}
+	var result []wharfyml.Stage
+	for _, stage := range stages {
+		var steps []wharfyml.Step
+		for _, step := range stage.Steps {
+			if stepShouldBeIncluded(stage.Name, step.Name, include, exclude) {
+				steps = append(steps, step)
+			} else {
+				log.Debug().
+					WithString("stage", stage.Name).
+					WithString("step", step.Name).
+					Message("Skipping step because of filter.")
+			}
+		}
+		if len(steps) == 0 {
+			log.Debug().
+				WithString("stage", stage.Name).
+				Message("Skipping stage because all its steps were filtered out.")
+			continue
+		}
+		stage.Steps = steps
+		result = append(result, stage)
+	}
+	if len(stages) > 0 && len(result) == 0 {
+		return nil, fmt.Errorf("%w: include %q, exclude %q",
+			ErrNoStepsMatchFilter, include, exclude)
+	}
+	return result, nil
+}
+
+func filterStagesForRerun(stages []wharfyml.Stage, succeeded map[string]bool) ([]wharfyml.Stage, error) {
+	for i, stage := range stages {
+		var notSucceeded []wharfyml.Step
+		for _, step := range stage.Steps {
+			if !succeeded[stage.Name+"/"+step.Name] {
+				notSucceeded = append(notSucceeded, step)
+			}
+		}
+		if len(notSucceeded) == 0 {
+			log.Debug().
+				WithString("stage", stage.Name).
+				Message("Skipping stage because all its steps succeeded in the previous build.")
+			continue
+		}
+		stage.Steps = notSucceeded
+		return append([]wharfyml.Stage{stage}, stages[i+1:]...), nil
+	}
+	return nil, ErrNothingToRerun
+}
+
+func validateStepPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid step filter %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func stepShouldBeIncluded(stageName, stepName string, include, exclude []string) bool {
+	if len(include) > 0 && !anyStepPatternMatches(stageName, stepName, include) {
+		return false
+	}
+	return !anyStepPatternMatches(stageName, stepName, exclude)
+}
+
+func anyStepPatternMatches(stageName, stepName string, patterns []string) bool {
+	fullName := stageName + "/" + stepName
+	for _, pattern := range patterns {
+		// Errors are ignored as patterns have already been validated.
+		if ok, _ := path.Match(pattern, stepName); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, fullName); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func logSkippedStage(stage wharfyml.Stage, stagesDone, stagesCount int) {
 	ev := log.Info().
 		WithStringf("stages", "%d/%d", stagesDone, stagesCount).
@@ -143,6 +276,21 @@ func logFailedStage(res StageResult, stagesDone, stagesCount int) {
 }
 
 func logSuccessfulStage(res StageResult, stagesDone, stagesCount int) {
+	var warnings []string
+	for _, stepRes := range res.Steps {
+		if stepRes.Status == workermodel.StatusWarning {
+			warnings = append(warnings, stepRes.Name)
+		}
+	}
+	if len(warnings) > 0 {
+		log.Warn().
+			WithStringf("stages", "%d/%d", stagesDone, stagesCount).
+			WithString("stage", res.Name).
+			WithDuration("dur", res.Duration.Truncate(time.Second)).
+			WithString("allowedFailures", strings.Join(warnings, ",")).
+			Message("Done with stage, but some steps that are allowed to fail failed.")
+		return
+	}
 	log.Info().
 		WithStringf("stages", "%d/%d", stagesDone, stagesCount).
 		WithString("stage", res.Name).
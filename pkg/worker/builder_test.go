@@ -87,6 +87,115 @@ func TestBuilder_runsMiddleFails(t *testing.T) {
 	assert.Equal(t, workermodel.StatusFailed, result.Stages[1].Status)
 }
 
+func TestFilterStagesSteps(t *testing.T) {
+	stages := []wharfyml.Stage{
+		{Name: "build", Steps: []wharfyml.Step{{Name: "docker-app"}, {Name: "docker-db"}, {Name: "lint"}}},
+		{Name: "deploy", Steps: []wharfyml.Step{{Name: "helm"}}},
+	}
+	testCases := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    map[string][]string
+	}{
+		{
+			name: "no filters",
+			want: map[string][]string{
+				"build":  {"docker-app", "docker-db", "lint"},
+				"deploy": {"helm"},
+			},
+		},
+		{
+			name:    "include glob",
+			include: []string{"docker-*"},
+			want: map[string][]string{
+				"build": {"docker-app", "docker-db"},
+			},
+		},
+		{
+			name:    "include stage-qualified",
+			include: []string{"deploy/*"},
+			want: map[string][]string{
+				"deploy": {"helm"},
+			},
+		},
+		{
+			name:    "exclude",
+			exclude: []string{"helm", "lint"},
+			want: map[string][]string{
+				"build": {"docker-app", "docker-db"},
+			},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"docker-*"},
+			exclude: []string{"*-db"},
+			want: map[string][]string{
+				"build": {"docker-app"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filterStagesSteps(stages, tc.include, tc.exclude)
+			require.NoError(t, err)
+			gotNames := make(map[string][]string)
+			for _, stage := range got {
+				for _, step := range stage.Steps {
+					gotNames[stage.Name] = append(gotNames[stage.Name], step.Name)
+				}
+			}
+			assert.Equal(t, tc.want, gotNames)
+		})
+	}
+}
+
+func TestFilterStagesSteps_ErrIfInvalidPattern(t *testing.T) {
+	stages := []wharfyml.Stage{{Name: "build", Steps: []wharfyml.Step{{Name: "lint"}}}}
+	_, err := filterStagesSteps(stages, []string{"["}, nil)
+	assert.Error(t, err)
+}
+
+func TestFilterStagesSteps_ErrIfNoneMatch(t *testing.T) {
+	stages := []wharfyml.Stage{{Name: "build", Steps: []wharfyml.Step{{Name: "lint"}}}}
+	_, err := filterStagesSteps(stages, []string{"lnit"}, nil)
+	assert.ErrorIs(t, err, ErrNoStepsMatchFilter)
+	_, err = filterStagesSteps(stages, nil, []string{"*"})
+	assert.ErrorIs(t, err, ErrNoStepsMatchFilter)
+}
+
+func TestFilterStagesForRerun(t *testing.T) {
+	stages := []wharfyml.Stage{
+		{Name: "test", Steps: []wharfyml.Step{{Name: "unit"}}},
+		{Name: "build", Steps: []wharfyml.Step{{Name: "app"}, {Name: "db"}}},
+		{Name: "deploy", Steps: []wharfyml.Step{{Name: "helm"}}},
+	}
+	succeeded := map[string]bool{
+		"test/unit":   true,
+		"build/app":   true,
+		"deploy/helm": true,
+	}
+	got, err := filterStagesForRerun(stages, succeeded)
+	require.NoError(t, err)
+	gotNames := make(map[string][]string)
+	for _, stage := range got {
+		for _, step := range stage.Steps {
+			gotNames[stage.Name] = append(gotNames[stage.Name], step.Name)
+		}
+	}
+	want := map[string][]string{
+		"build":  {"db"},
+		"deploy": {"helm"},
+	}
+	assert.Equal(t, want, gotNames)
+}
+
+func TestFilterStagesForRerun_ErrIfAllSucceeded(t *testing.T) {
+	stages := []wharfyml.Stage{{Name: "build", Steps: []wharfyml.Step{{Name: "app"}}}}
+	_, err := filterStagesForRerun(stages, map[string]bool{"build/app": true})
+	assert.ErrorIs(t, err, ErrNothingToRerun)
+}
+
 func getNamesFromStageResults(stages []StageResult) []string {
 	var names []string
 	for _, stage := range stages {
@@ -10,6 +10,7 @@ type contextKey int
 const (
 	contextKeyStageName contextKey = iota
 	contextKeyStepName
+	contextKeyStageID
 )
 
 func contextWithStageName(ctx context.Context, stage string) context.Context {
@@ -23,6 +24,17 @@ func contextStageName(ctx context.Context) (string, bool) {
 	return "", false
 }
 
+func contextWithStageID(ctx context.Context, stageID uint64) context.Context {
+	return context.WithValue(ctx, contextKeyStageID, stageID)
+}
+
+func contextStageID(ctx context.Context) (uint64, bool) {
+	if v := ctx.Value(contextKeyStageID); v != nil {
+		return v.(uint64), true
+	}
+	return 0, false
+}
+
 func contextWithStepName(ctx context.Context, stage string) context.Context {
 	return context.WithValue(ctx, contextKeyStepName, stage)
 }
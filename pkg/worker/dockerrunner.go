@@ -0,0 +1,460 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/steps"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/varsub"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DockerRunnerOptions is a struct of options for a Docker Engine step runner.
+type DockerRunnerOptions struct {
+	BuildOptions
+	Config        *config.Config
+	Client        *dockerengine.Client
+	ResultStore   resultstore.Store
+	TarStore      tarstore.Store
+	VarSource     varsub.Source
+	SkipGitIgnore bool
+	CurrentDir    string
+	// BuildID and ProjectID are added as labels on each step container and
+	// volume, together with the stage and step IDs, so they can be traced
+	// back to their build.
+	BuildID   uint
+	ProjectID uint
+}
+
+// NewDocker is a helper function that creates a new builder using the
+// NewDockerStepRunnerFactory.
+func NewDocker(ctx context.Context, def wharfyml.Definition, opts DockerRunnerOptions) (Builder, error) {
+	stageFactory, err := NewDockerStageRunnerFactory(opts)
+	if err != nil {
+		return nil, err
+	}
+	return New(ctx, stageFactory, def, opts.BuildOptions)
+}
+
+// NewDockerStageRunnerFactory is a helper function that creates a new stage
+// runner factory using the NewDockerStepRunnerFactory.
+func NewDockerStageRunnerFactory(opts DockerRunnerOptions) (StageRunnerFactory, error) {
+	stepFactory, err := NewDockerStepRunnerFactory(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewStageRunnerFactory(stepFactory, opts.Config.Worker.MaxParallelSteps)
+}
+
+// NewDockerStepRunnerFactory returns a new step runner factory that creates
+// step runners that run each step as a container via the Docker Engine API,
+// which is also supported by Podman.
+//
+// The Kubernetes Pod specification of each step is translated into a
+// container, where emptyDir volumes are created as volumes in the engine.
+// Instead of using the init container that waits for the repository, the
+// repository is copied into the first container before it is started. Any
+// other init containers are run in order before the app container. Steps with
+// more than one app container are not supported. Features that rely on Kubernetes, such as
+// secrets and config maps, are not supported and are skipped with a warning.
+func NewDockerStepRunnerFactory(opts DockerRunnerOptions) (StepRunnerFactory, error) {
+	if opts.Client == nil {
+		return nil, errors.New("missing Docker Engine API client")
+	}
+	return dockerStepRunnerFactory{DockerRunnerOptions: opts}, nil
+}
+
+type dockerStepRunnerFactory struct {
+	DockerRunnerOptions
+}
+
+func (f dockerStepRunnerFactory) NewStepRunner(
+	ctx context.Context, step wharfyml.Step, stepID uint64) (StepRunner, error) {
+	ctx = contextWithStepName(ctx, step.Name)
+	podSpecer, ok := step.Type.(steps.PodSpecer)
+	if !ok {
+		return nil, errors.New("step type cannot produce a Kubernetes Pod specification")
+	}
+	podSpec := podSpecer.PodSpec()
+	if len(podSpec.Containers) == 0 {
+		return nil, errors.New("step type did not add an app container")
+	}
+	if len(podSpec.Containers) > 1 {
+		log.Warn().
+			WithString("step", step.Name).
+			WithInt("containers", len(podSpec.Containers)).
+			Message("Only a single app container is supported when running via Docker Engine.")
+		return nil, fmt.Errorf("step has %d app containers, but only 1 is supported when running via Docker Engine", len(podSpec.Containers))
+	}
+
+	tarball, err := f.stepRepoPreparer().prepareStepRepo(ctx, step, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setStepMeta(ctx, f.ResultStore, step, stepID); err != nil {
+		return nil, err
+	}
+
+	stageName, _ := contextStageName(ctx)
+	stageID, _ := contextStageID(ctx)
+	r := dockerStepRunner{
+		DockerRunnerOptions: f.DockerRunnerOptions,
+		logScope:            contextStageStepName(ctx),
+		step:                step,
+		stageName:           stageName,
+		stageID:             stageID,
+		podSpec:             podSpec,
+		stepID:              stepID,
+		repoTar:             tarball,
+	}
+	return r, nil
+}
+
+func (f dockerStepRunnerFactory) stepRepoPreparer() stepRepoPreparer {
+	return stepRepoPreparer{
+		tarStore:      f.TarStore,
+		varSource:     f.VarSource,
+		skipGitIgnore: f.SkipGitIgnore,
+		currentDir:    f.CurrentDir,
+	}
+}
+
+type dockerStepRunner struct {
+	DockerRunnerOptions
+	logScope  string
+	step      wharfyml.Step
+	stageName string
+	stageID   uint64
+	podSpec   v1.PodSpec
+	stepID    uint64
+	repoTar   tarstore.Tarball
+}
+
+func (r dockerStepRunner) Step() wharfyml.Step {
+	return r.step
+}
+
+func (r dockerStepRunner) RunStep(ctx context.Context) StepResult {
+	ctx = contextWithStepName(ctx, r.step.Name)
+	start := time.Now()
+	status := workermodel.StatusSuccess
+	termination, err := r.runStep(ctx)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		status = workermodel.StatusCancelled
+	} else if err != nil {
+		status = failedStepStatus(r.step)
+	}
+	if err := addFinalStatusUpdate(r.ResultStore, r.stepID, status, termination); err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("step", r.step.Name).
+			WithStringer("status", status).
+			Message("Failed to add status update.")
+	}
+	return StepResult{
+		Name:        r.step.Name,
+		Status:      status,
+		Type:        r.step.Type.StepTypeName(),
+		Error:       err,
+		Duration:    time.Since(start),
+		Termination: termination,
+	}
+}
+
+func (r dockerStepRunner) ReportStepStatus(status workermodel.Status) {
+	r.addStatusUpdate(status)
+}
+
+func (r dockerStepRunner) runStep(ctx context.Context) (*workermodel.Termination, error) {
+	r.addStatusUpdate(workermodel.StatusInitializing)
+	volumeNames, err := r.createVolumes(ctx)
+	defer r.removeVolumes(volumeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	repoCopied := false
+	for _, init := range r.podSpec.InitContainers {
+		if isRepoWaitInitContainer(init) {
+			continue
+		}
+		termination, err := r.runContainer(ctx, init, volumeNames, !repoCopied)
+		repoCopied = true
+		if err != nil {
+			return termination, fmt.Errorf("init container %q: %w", init.Name, err)
+		}
+	}
+
+	app := r.podSpec.Containers[0]
+	return r.runContainer(ctx, app, volumeNames, !repoCopied)
+}
+
+// runContainer runs a container to completion. The repo is only copied into
+// the first container of the step, as any later containers share the same
+// repo volume and would otherwise lose changes done by earlier containers.
+func (r dockerStepRunner) runContainer(ctx context.Context, cont v1.Container, volumeNames map[string]string, copyRepo bool) (*workermodel.Termination, error) {
+	if err := r.ensureImage(ctx, cont); err != nil {
+		return nil, err
+	}
+	config := r.newContainerConfig(cont, volumeNames)
+	name := newDockerContainerName(r.step)
+	log.Debug().
+		WithString("step", r.step.Name).
+		WithString("container", name).
+		WithString("image", config.Image).
+		Message("Creating container.")
+	containerID, err := r.Client.CreateContainer(ctx, name, config)
+	if err != nil {
+		return nil, fmt.Errorf("create container: %w", err)
+	}
+	defer r.removeContainer(containerID, name)
+
+	if copyRepo {
+		log.Debug().WithString("step", r.step.Name).WithString("container", name).
+			Message("Transferring repo to container.")
+		if err := r.copyRepoToContainer(ctx, containerID); err != nil {
+			return nil, fmt.Errorf("transfer repo: %w", err)
+		}
+	}
+
+	if err := r.Client.StartContainer(ctx, containerID); err != nil {
+		return nil, fmt.Errorf("start container: %w", err)
+	}
+	startedAt := time.Now()
+	if r.isAppContainer(cont) {
+		r.addStatusUpdate(workermodel.StatusRunning)
+	}
+	log.Debug().WithString("step", r.step.Name).WithString("container", name).
+		Message("Container started. Streaming logs.")
+	if err := r.readLogs(ctx, containerID, cont.Name); err != nil {
+		return nil, fmt.Errorf("stream logs: %w", err)
+	}
+	exitCode, err := r.Client.WaitContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("wait for container: %w", err)
+	}
+	termination := r.newTermination(containerID, name, exitCode, startedAt)
+	if exitCode != 0 {
+		return termination, fmt.Errorf("non-zero exit code: %d", exitCode)
+	}
+	return termination, nil
+}
+
+// newTermination returns the termination of a stopped container, where the
+// reason and timestamps are taken from the container's state if possible.
+func (r dockerStepRunner) newTermination(containerID, name string, exitCode int, startedAt time.Time) *workermodel.Termination {
+	termination := newExitTermination(int32(exitCode), startedAt, time.Now())
+	state, err := r.Client.InspectContainer(context.Background(), containerID)
+	if err != nil {
+		log.Warn().WithError(err).
+			WithString("step", r.step.Name).
+			WithString("container", name).
+			Message("Failed to inspect container. Termination reason is based on exit code only.")
+		return termination
+	}
+	if state.OOMKilled {
+		termination.Reason = terminationReasonOOMKilled
+	}
+	termination.Message = state.Error
+	if !state.StartedAt.IsZero() {
+		termination.StartedAt = state.StartedAt
+	}
+	if !state.FinishedAt.IsZero() {
+		termination.FinishedAt = state.FinishedAt
+	}
+	return termination
+}
+
+// isAppContainer relies on that Kubernetes requires container names to be
+// unique among both the init and app containers of a pod.
+func (r dockerStepRunner) isAppContainer(cont v1.Container) bool {
+	return cont.Name == r.podSpec.Containers[0].Name
+}
+
+// isRepoWaitInitContainer returns true if the container is the init container
+// that only waits for the repo to be transferred into the pod. The Docker
+// Engine runner copies the repo directly into the container instead, and
+// running it would block forever.
+func isRepoWaitInitContainer(cont v1.Container) bool {
+	return reflect.DeepEqual(cont.Command, steps.PodInitWaitArgs) && len(cont.Args) == 0
+}
+
+// createVolumes creates an engine volume for each emptyDir volume in the pod
+// spec, and returns a map of the pod volume names to the engine volume names.
+func (r dockerStepRunner) createVolumes(ctx context.Context) (map[string]string, error) {
+	volumeNames := make(map[string]string, len(r.podSpec.Volumes))
+	for _, vol := range r.podSpec.Volumes {
+		if vol.EmptyDir == nil {
+			log.Warn().
+				WithString("step", r.step.Name).
+				WithString("volume", vol.Name).
+				Message("Only emptyDir volumes are supported when running via Docker Engine. Skipping volume.")
+			continue
+		}
+		created, err := r.Client.CreateVolume(ctx, dockerengine.VolumeCreateRequest{
+			Labels: r.labels(),
+		})
+		if err != nil {
+			return volumeNames, fmt.Errorf("create volume %q: %w", vol.Name, err)
+		}
+		volumeNames[vol.Name] = created.Name
+	}
+	return volumeNames, nil
+}
+
+func (r dockerStepRunner) removeVolumes(volumeNames map[string]string) {
+	for _, name := range volumeNames {
+		if err := r.Client.RemoveVolume(context.Background(), name); err != nil {
+			log.Warn().WithError(err).
+				WithString("step", r.step.Name).
+				WithString("volume", name).
+				Message("Failed to remove volume.")
+		}
+	}
+}
+
+func (r dockerStepRunner) removeContainer(containerID, name string) {
+	if err := r.Client.RemoveContainer(context.Background(), containerID); err != nil {
+		log.Warn().WithError(err).
+			WithString("step", r.step.Name).
+			WithString("container", name).
+			Message("Failed to remove container.")
+		return
+	}
+	log.Debug().
+		WithString("step", r.step.Name).
+		WithString("container", name).
+		Message("Removed container.")
+}
+
+func (r dockerStepRunner) ensureImage(ctx context.Context, app v1.Container) error {
+	if app.ImagePullPolicy != v1.PullAlways {
+		exists, err := r.Client.ImageExists(ctx, app.Image)
+		if err != nil {
+			return fmt.Errorf("check image %q: %w", app.Image, err)
+		}
+		if exists {
+			return nil
+		}
+	}
+	log.Debug().
+		WithString("step", r.step.Name).
+		WithString("image", app.Image).
+		Message("Pulling image.")
+	return r.Client.PullImage(ctx, app.Image)
+}
+
+func (r dockerStepRunner) newContainerConfig(app v1.Container, volumeNames map[string]string) dockerengine.ContainerConfig {
+	config := dockerengine.ContainerConfig{
+		Image:        app.Image,
+		Entrypoint:   app.Command,
+		Cmd:          app.Args,
+		WorkingDir:   app.WorkingDir,
+		Labels:       r.labels(),
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	for _, env := range app.Env {
+		if env.ValueFrom != nil {
+			log.Warn().
+				WithString("step", r.step.Name).
+				WithString("env", env.Name).
+				Message("Environment variables from Kubernetes resources are not supported when running via Docker Engine. Skipping.")
+			continue
+		}
+		config.Env = append(config.Env, env.Name+"="+env.Value)
+	}
+	if len(app.EnvFrom) > 0 {
+		log.Warn().
+			WithString("step", r.step.Name).
+			Message("Environment variables from Kubernetes secrets are not supported when running via Docker Engine. Skipping.")
+	}
+	for _, mount := range app.VolumeMounts {
+		volumeName, ok := volumeNames[mount.Name]
+		if !ok {
+			continue
+		}
+		config.HostConfig.Mounts = append(config.HostConfig.Mounts, dockerengine.Mount{
+			Type:     dockerengine.MountTypeVolume,
+			Source:   volumeName,
+			Target:   mount.MountPath,
+			ReadOnly: mount.ReadOnly,
+		})
+	}
+	if sc := app.SecurityContext; sc != nil && sc.Privileged != nil {
+		config.HostConfig.Privileged = *sc.Privileged
+	}
+	return config
+}
+
+// labels returns the same labels as set on step pods by the Kubernetes step
+// runner. As the Docker Engine API has no annotations, the stage and step
+// names are set as labels instead.
+func (r dockerStepRunner) labels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "wharf-cmd-worker-step",
+		"app.kubernetes.io/managed-by": "wharf-cmd-worker",
+
+		LabelInstance:       r.Config.InstanceID,
+		LabelBuildRef:       strconv.FormatUint(uint64(r.BuildID), 10),
+		LabelProjectID:      strconv.FormatUint(uint64(r.ProjectID), 10),
+		LabelStageID:        strconv.FormatUint(r.stageID, 10),
+		LabelStepID:         strconv.FormatUint(r.stepID, 10),
+		AnnotationStageName: r.stageName,
+		AnnotationStepName:  r.step.Name,
+	}
+}
+
+func (r dockerStepRunner) copyRepoToContainer(ctx context.Context, containerID string) error {
+	tarReader, err := r.repoTar.Open()
+	if err != nil {
+		return err
+	}
+	defer tarReader.Close()
+	return r.Client.PutArchive(ctx, containerID, steps.PodRepoVolumeMountPath, tarReader)
+}
+
+func (r dockerStepRunner) readLogs(ctx context.Context, containerID, containerName string) error {
+	logs, err := r.Client.ContainerLogs(ctx, containerID, true)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	logWriter := openStepLogWriter(r.ResultStore, r.stepID, r.logScope, containerName)
+	defer logWriter.Close()
+	stdout := logWriter.LineWriter(containerName, resultstore.LogStreamStdout)
+	defer stdout.Close()
+	stderr := logWriter.LineWriter(containerName, resultstore.LogStreamStderr)
+	defer stderr.Close()
+	return dockerengine.DemuxLogs(logs, stdout, stderr)
+}
+
+func (r dockerStepRunner) addStatusUpdate(status workermodel.Status) {
+	if err := r.ResultStore.AddStatusUpdate(r.stepID, time.Now(), status); err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("step", r.step.Name).
+			WithStringer("status", status).
+			Message("Failed to add status update.")
+	}
+}
+
+func newDockerContainerName(step wharfyml.Step) string {
+	var suffix [3]byte
+	rand.Read(suffix[:])
+	return getPodGenerateName(step) + hex.EncodeToString(suffix[:])
+}
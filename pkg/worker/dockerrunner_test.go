@@ -0,0 +1,260 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/dockerengine/dockerenginetest"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/steps"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+type testPodStep struct {
+	podSpec v1.PodSpec
+}
+
+func (testPodStep) StepTypeName() string  { return "test" }
+func (s testPodStep) PodSpec() v1.PodSpec { return s.podSpec }
+
+func newTestPodStep(name string) wharfyml.Step {
+	repoMount := v1.VolumeMount{Name: "repo", MountPath: steps.PodRepoVolumeMountPath}
+	return wharfyml.Step{
+		Name: name,
+		Type: testPodStep{podSpec: v1.PodSpec{
+			InitContainers: []v1.Container{{
+				Name:    "init",
+				Image:   "alpine:3",
+				Command: steps.PodInitWaitArgs,
+			}},
+			Containers: []v1.Container{{
+				Name:            "step",
+				Image:           "alpine:3",
+				ImagePullPolicy: v1.PullIfNotPresent,
+				Command:         []string{"/bin/sh", "-c"},
+				Args:            []string{"echo hello"},
+				WorkingDir:      steps.PodRepoVolumeMountPath,
+				Env: []v1.EnvVar{
+					{Name: "FOO", Value: "bar"},
+					{Name: "SECRET", ValueFrom: &v1.EnvVarSource{}},
+				},
+				VolumeMounts: []v1.VolumeMount{repoMount},
+			}},
+			Volumes: []v1.Volume{
+				{Name: "repo", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+				{Name: "certs", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}},
+			},
+		}},
+	}
+}
+
+func newTestDockerStepRunnerFactory(t *testing.T, engine *dockerenginetest.Engine) (StepRunnerFactory, resultstore.Store) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "hello.txt"), []byte("hello from repo\n"), 0644))
+	tarStore, err := tarstore.New(repoDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { tarStore.Close() })
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	factory, err := NewDockerStepRunnerFactory(DockerRunnerOptions{
+		Config:        &config.Config{},
+		Client:        engine.Client(),
+		ResultStore:   store,
+		TarStore:      tarStore,
+		SkipGitIgnore: true,
+		CurrentDir:    repoDir,
+	})
+	require.NoError(t, err)
+	return factory, store
+}
+
+func TestDockerStepRunner_success(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		return "hello\n", "world\n", 0
+	}
+	factory, store := newTestDockerStepRunnerFactory(t, engine)
+	r, err := factory.NewStepRunner(context.Background(), newTestPodStep("my-step"), 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	require.NoError(t, res.Error)
+	assert.Equal(t, workermodel.StatusSuccess, res.Status)
+
+	assert.Equal(t, []string{"alpine:3"}, engine.PulledImages())
+	assert.Empty(t, engine.Volumes(), "volumes should be removed")
+	containers := engine.Containers()
+	require.Len(t, containers, 1, "repo wait init container should be skipped")
+	c := containers[0]
+	assert.True(t, c.Removed, "container should be removed")
+	assert.Equal(t, []string{"/bin/sh", "-c"}, c.Config.Entrypoint)
+	assert.Equal(t, []string{"echo hello"}, c.Config.Cmd)
+	assert.Equal(t, []string{"FOO=bar"}, c.Config.Env)
+	assert.Equal(t, steps.PodRepoVolumeMountPath, c.Config.WorkingDir)
+	require.Len(t, c.Config.HostConfig.Mounts, 1)
+	assert.Equal(t, steps.PodRepoVolumeMountPath, c.Config.HostConfig.Mounts[0].Target)
+	assert.NotEmpty(t, c.Archives[steps.PodRepoVolumeMountPath], "repo should be uploaded")
+	assert.Equal(t, "1", c.Config.Labels[LabelStepID])
+	assert.Equal(t, "my-step", c.Config.Labels[AnnotationStepName])
+
+	reader, err := store.OpenLogReader(1)
+	require.NoError(t, err)
+	defer reader.Close()
+	var messages, streams []string
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "step", line.Container)
+		messages = append(messages, line.Message)
+		streams = append(streams, string(line.Stream))
+	}
+	assert.Equal(t, []string{"hello", "world"}, messages)
+	assert.Equal(t, []string{"stdout", "stderr"}, streams)
+}
+
+func TestDockerStepRunner_nonZeroExitCode(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.AddImage("alpine:3")
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		return "", "boom\n", 2
+	}
+	factory, store := newTestDockerStepRunnerFactory(t, engine)
+	r, err := factory.NewStepRunner(context.Background(), newTestPodStep("my-step"), 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	assert.EqualError(t, res.Error, "non-zero exit code: 2")
+	assert.Equal(t, workermodel.StatusFailed, res.Status)
+	require.NotNil(t, res.Termination)
+	assert.Equal(t, int32(2), res.Termination.ExitCode)
+	assert.Equal(t, "Error", res.Termination.Reason)
+	assert.Empty(t, engine.PulledImages(), "existing image should not be pulled")
+
+	updates, err := store.ListStatusUpdates(1)
+	require.NoError(t, err)
+	var statuses []workermodel.Status
+	for _, u := range updates {
+		statuses = append(statuses, u.Status)
+	}
+	want := []workermodel.Status{
+		workermodel.StatusInitializing,
+		workermodel.StatusRunning,
+		workermodel.StatusFailed,
+	}
+	assert.Equal(t, want, statuses)
+	require.NotNil(t, updates[2].Termination, "final status should have termination")
+	assert.Equal(t, int32(2), updates[2].Termination.ExitCode)
+}
+
+func TestDockerStepRunner_initContainers(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.AddImage("alpine:3")
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		return c.Config.Cmd[0] + "\n", "", 0
+	}
+	step := newTestPodStep("my-step")
+	podSpec := step.Type.(testPodStep).podSpec
+	podSpec.InitContainers = append(podSpec.InitContainers,
+		v1.Container{Name: "first", Image: "alpine:3", Args: []string{"first"}},
+		v1.Container{Name: "second", Image: "alpine:3", Args: []string{"second"}},
+	)
+	step.Type = testPodStep{podSpec: podSpec}
+	factory, store := newTestDockerStepRunnerFactory(t, engine)
+	r, err := factory.NewStepRunner(context.Background(), step, 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	require.NoError(t, res.Error)
+
+	containers := engine.Containers()
+	require.Len(t, containers, 3)
+	var cmds []string
+	for _, c := range containers {
+		cmds = append(cmds, c.Config.Cmd[0])
+	}
+	assert.Equal(t, []string{"first", "second", "echo hello"}, cmds)
+	assert.NotEmpty(t, containers[0].Archives[steps.PodRepoVolumeMountPath], "repo should be uploaded to first container")
+	assert.Empty(t, containers[2].Archives, "repo should only be uploaded once")
+
+	reader, err := store.OpenLogReader(1)
+	require.NoError(t, err)
+	defer reader.Close()
+	var containerNames []string
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		containerNames = append(containerNames, line.Container)
+	}
+	assert.Equal(t, []string{"first", "second", "step"}, containerNames)
+}
+
+func TestDockerStepRunner_failedInitContainer(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.AddImage("alpine:3")
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		return "", "", 1
+	}
+	step := newTestPodStep("my-step")
+	podSpec := step.Type.(testPodStep).podSpec
+	podSpec.InitContainers = append(podSpec.InitContainers,
+		v1.Container{Name: "first", Image: "alpine:3"})
+	step.Type = testPodStep{podSpec: podSpec}
+	factory, _ := newTestDockerStepRunnerFactory(t, engine)
+	r, err := factory.NewStepRunner(context.Background(), step, 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	assert.EqualError(t, res.Error, `init container "first": non-zero exit code: 1`)
+	assert.Equal(t, workermodel.StatusFailed, res.Status)
+	assert.Len(t, engine.Containers(), 1, "app container should not be created")
+}
+
+func TestDockerStepRunnerFactory_multipleAppContainers(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	step := newTestPodStep("my-step")
+	podSpec := step.Type.(testPodStep).podSpec
+	podSpec.Containers = append(podSpec.Containers, v1.Container{Name: "sidecar", Image: "alpine:3"})
+	step.Type = testPodStep{podSpec: podSpec}
+	factory, _ := newTestDockerStepRunnerFactory(t, engine)
+	_, err := factory.NewStepRunner(context.Background(), step, 1)
+	assert.EqualError(t, err, "step has 2 app containers, but only 1 is supported when running via Docker Engine")
+}
+
+func TestDockerStepRunner_oomKilled(t *testing.T) {
+	engine := dockerenginetest.NewEngine()
+	defer engine.Close()
+	engine.AddImage("alpine:3")
+	engine.RunFunc = func(c *dockerenginetest.Container) (string, string, int) {
+		c.OOMKilled = true
+		return "", "", 137
+	}
+	factory, _ := newTestDockerStepRunnerFactory(t, engine)
+	r, err := factory.NewStepRunner(context.Background(), newTestPodStep("my-step"), 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	assert.Equal(t, workermodel.StatusFailed, res.Status)
+	require.NotNil(t, res.Termination)
+	assert.Equal(t, int32(137), res.Termination.ExitCode)
+	assert.Equal(t, "OOMKilled", res.Termination.Reason)
+}
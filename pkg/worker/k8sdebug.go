@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/steps"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// debugSessionMutex makes sure only one interactive debug session is open at
+// a time, as they all share the same stdin and stdout.
+var debugSessionMutex sync.Mutex
+
+func (r k8sStepRunner) shouldKeepFailedPod() bool {
+	return r.KeepFailedPods || r.DebugOnFailure
+}
+
+// keepFailedPod leaves the failed pod as-is, and starts a new debug pod based
+// on the same pod spec, but where the app container only sleeps instead of
+// running the step. The repository is transferred to the debug pod the same
+// way as for the step pod.
+//
+// If DebugOnFailure is enabled, then an interactive shell is opened in the
+// debug pod, and both pods are deleted when the shell exits.
+func (r k8sStepRunner) keepFailedPod(ctx context.Context) {
+	failedPodName := r.target.name
+	debugRunner, err := r.startDebugPod(ctx)
+	if err != nil {
+		log.Warn().
+			WithError(err).
+			WithFunc(r.logFunc).
+			Message("Failed to start debug pod. Keeping failed pod only.")
+		r.logKeptPods(failedPodName, "")
+		return
+	}
+	debugPodName := debugRunner.target.name
+	if !r.DebugOnFailure {
+		r.logKeptPods(failedPodName, debugPodName)
+		return
+	}
+
+	debugSessionMutex.Lock()
+	defer debugSessionMutex.Unlock()
+	log.Info().
+		WithFunc(r.logFunc).
+		WithString("debugPod", debugPodName).
+		Message("Opening interactive shell in debug pod. Exit the shell to continue.")
+	if err := debugRunner.openInteractiveShell(); err != nil {
+		log.Warn().
+			WithError(err).
+			WithFunc(r.logFunc).
+			WithString("debugPod", debugPodName).
+			Message("Interactive shell in debug pod failed.")
+	}
+	debugRunner.stopPodNow(ctx)
+	r.stopPodNow(ctx)
+}
+
+func (r k8sStepRunner) startDebugPod(ctx context.Context) (k8sStepRunner, error) {
+	debugPod := r.pod.DeepCopy()
+	debugPod.GenerateName = strings.TrimSuffix(r.pod.GenerateName, "-") + "-debug-"
+	debugPod.Labels["wharf.iver.com/debug"] = "true"
+	debugPod.Annotations["wharf.iver.com/debug-of-pod"] = r.target.name
+	app := &debugPod.Spec.Containers[0]
+	app.Command = steps.PodInitWaitArgs
+	app.Args = nil
+	app.Stdin = true
+	app.TTY = true
+
+	newPod, err := r.pods.Create(ctx, debugPod, metav1.CreateOptions{})
+	if err != nil {
+		return k8sStepRunner{}, fmt.Errorf("create debug pod: %w", err)
+	}
+	// The debug pod gets its own pod phase tracer, so its phases are not mixed
+	// up with the failed pod's, and is left out of the scheduling latency
+	// metric, as it is not a step pod.
+	debugRunner := r
+	debugRunner.pod = newPod
+	debugRunner.podPhases = newPodPhaseTracer(ctx, newPod.Name)
+	defer debugRunner.podPhases.end()
+	debugRunner.podSched = nil
+	debugRunner.target = &target{
+		namespace: r.target.namespace,
+		name:      newPod.Name,
+		container: "init",
+	}
+	debugRunner.logFunc = func(ev logger.Event) logger.Event {
+		return ev.
+			WithString("step", r.step.Name).
+			WithString("pod", newPod.Name)
+	}
+	log.Debug().WithFunc(debugRunner.logFunc).Message("Created debug pod.")
+
+	if err := debugRunner.waitForInitContainerRunning(ctx, newPod.ObjectMeta); err != nil {
+		debugRunner.stopPodNow(ctx)
+		return k8sStepRunner{}, fmt.Errorf("wait for debug init container: %w", err)
+	}
+	if err := debugRunner.transferDataToPod(ctx); err != nil {
+		debugRunner.stopPodNow(ctx)
+		return k8sStepRunner{}, fmt.Errorf("debug pod: %w", err)
+	}
+	if err := debugRunner.continueInitContainer(); err != nil {
+		debugRunner.stopPodNow(ctx)
+		return k8sStepRunner{}, fmt.Errorf("continue debug init container: %w", err)
+	}
+	if err := debugRunner.waitForAppContainerRunningOrDone(ctx, newPod.ObjectMeta); err != nil {
+		debugRunner.stopPodNow(ctx)
+		return k8sStepRunner{}, fmt.Errorf("wait for debug app container: %w", err)
+	}
+	debugRunner.target.container = newPod.Spec.Containers[0].Name
+	return debugRunner, nil
+}
+
+func (r k8sStepRunner) logKeptPods(failedPodName, debugPodName string) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Kept failed pod of step %q.\n\n", r.step.Name)
+	fmt.Fprintf(&sb, "Inspect the failed pod:\n  kubectl describe pod --namespace %s %s\n  kubectl logs --namespace %s %s --all-containers\n",
+		r.target.namespace, failedPodName, r.target.namespace, failedPodName)
+	podNames := failedPodName
+	if debugPodName != "" {
+		fmt.Fprintf(&sb, "\nOpen a shell in the debug pod, with the repository mounted at %s:\n  kubectl exec --namespace %s -it %s --container %s -- %s\n",
+			steps.PodRepoVolumeMountPath, r.target.namespace, debugPodName, r.pod.Spec.Containers[0].Name, r.debugShell())
+		podNames += " " + debugPodName
+	}
+	fmt.Fprintf(&sb, "\nClean up when done:\n  kubectl delete pod --namespace %s %s\n", r.target.namespace, podNames)
+	log.Info().WithFunc(r.logFunc).Message(sb.String())
+}
+
+func (r k8sStepRunner) debugShell() string {
+	if s, ok := r.step.Type.(steps.Container); ok && s.OS != "windows" && s.Shell != "" {
+		return s.Shell
+	}
+	return "/bin/sh"
+}
+
+func (r k8sStepRunner) openInteractiveShell() error {
+	exec, err := execInPod(r.RestConfig, r.target.namespace, r.target.name, &v1.PodExecOptions{
+		Container: r.target.container,
+		Command:   []string{r.debugShell()},
+		Stdin:     true,
+		Stdout:    true,
+		TTY:       true,
+	})
+	if err != nil {
+		return err
+	}
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) {
+		return fmt.Errorf("stdin is not a terminal")
+	}
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return fmt.Errorf("set terminal to raw mode: %w", err)
+	}
+	defer term.Restore(stdinFd, oldState)
+	return exec.Stream(remotecommand.StreamOptions{
+		Stdin:             os.Stdin,
+		Stdout:            os.Stdout,
+		Tty:               true,
+		TerminalSizeQueue: newSingleTerminalSizeQueue(int(os.Stdout.Fd())),
+	})
+}
+
+// singleTerminalSizeQueue only reports the terminal size once, when the
+// session starts.
+type singleTerminalSizeQueue struct {
+	size *remotecommand.TerminalSize
+}
+
+func newSingleTerminalSizeQueue(fd int) *singleTerminalSizeQueue {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return &singleTerminalSizeQueue{}
+	}
+	return &singleTerminalSizeQueue{
+		size: &remotecommand.TerminalSize{
+			Width:  uint16(width),
+			Height: uint16(height),
+		},
+	}
+}
+
+func (q *singleTerminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size := q.size
+	q.size = nil
+	return size
+}
@@ -0,0 +1,282 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	eventReasonFailedScheduling = "FailedScheduling"
+	eventReasonScheduled        = "Scheduled"
+	eventReasonTriggeredScaleUp = "TriggeredScaleUp"
+
+	containerReasonOOMKilled = "OOMKilled"
+)
+
+// podEventWatcher watches the Kubernetes events of a step pod, writes all
+// warning events to the step's logs, and cancels the step if the pod stays
+// unschedulable for longer than the timeout.
+type podEventWatcher struct {
+	events  corev1.EventInterface
+	podName string
+	writer  *stepLogWriter
+	// unschedulableTimeout is how long the pod may be unschedulable before
+	// onUnschedulable is called. Zero disables the check.
+	unschedulableTimeout time.Duration
+	onUnschedulable      func()
+	logFunc              func(ev logger.Event) logger.Event
+
+	mutex              sync.Mutex
+	unschedulableTimer *time.Timer
+	unschedulableMsg   string
+	unschedulableErr   error
+	done               chan struct{}
+}
+
+func (r k8sStepRunner) startPodEventWatcher(ctx context.Context, podName string, onUnschedulable func()) *podEventWatcher {
+	w := &podEventWatcher{
+		events:               r.events,
+		podName:              podName,
+		writer:               r.logWriter,
+		unschedulableTimeout: r.Config.Worker.UnschedulableTimeout,
+		onUnschedulable:      onUnschedulable,
+		logFunc:              r.logFunc,
+		done:                 make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		w.watch(ctx)
+	}()
+	return w
+}
+
+// Wait waits for the watcher to stop, which happens when its context is
+// cancelled, and returns an error if the pod was unschedulable.
+func (w *podEventWatcher) Wait() error {
+	<-w.done
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.unschedulableTimer != nil {
+		w.unschedulableTimer.Stop()
+	}
+	return w.unschedulableErr
+}
+
+func (w *podEventWatcher) watch(ctx context.Context) {
+	opts := metav1.ListOptions{
+		FieldSelector: fields.Set{
+			"involvedObject.kind": "Pod",
+			"involvedObject.name": w.podName,
+		}.String(),
+	}
+	// seen holds the last seen resource version of each event, so that events
+	// are not written twice when re-listing them.
+	seen := make(map[types.UID]string)
+	for ctx.Err() == nil {
+		watcher, err := w.events.Watch(ctx, opts)
+		if err == nil {
+			err = w.readEvents(ctx, watcher, &opts, seen)
+			watcher.Stop()
+			if err != nil && !isResourceVersionExpired(err) {
+				log.Debug().WithError(err).WithFunc(w.logFunc).
+					Message("Pod events watch sent an error. Restarting watch.")
+				err = nil
+			}
+		}
+		if isResourceVersionExpired(err) {
+			// The last seen event is too old to continue watching from, so we
+			// list the events to get a fresh resource version instead.
+			log.Debug().WithError(err).WithFunc(w.logFunc).
+				Message("Pod events watch expired. Listing events to resume watch.")
+			err = w.listEvents(ctx, &opts, seen)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn().WithError(err).WithFunc(w.logFunc).
+					Message("Failed to watch pod events. No events will be written to the logs.")
+			}
+			return
+		}
+		// The API server closes watches after a timeout, so we start a new
+		// one from the last seen event, but wait a bit to not spam the API.
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// readEvents reads events from the watcher until it is closed, and returns
+// an error if the watch sent an error, such as when the resource version it
+// watches from has expired.
+func (w *podEventWatcher) readEvents(ctx context.Context, watcher watch.Interface, opts *metav1.ListOptions, seen map[types.UID]string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if ev.Type == watch.Error {
+				return apierrors.FromObject(ev.Object)
+			}
+			event, ok := ev.Object.(*v1.Event)
+			if !ok {
+				continue
+			}
+			opts.ResourceVersion = event.ResourceVersion
+			if ev.Type == watch.Added || ev.Type == watch.Modified {
+				seen[event.UID] = event.ResourceVersion
+				w.handleEvent(event)
+			}
+		}
+	}
+}
+
+// listEvents lists the pod's events to get a fresh resource version to watch
+// from, and handles any events that were not seen by the previous watch.
+func (w *podEventWatcher) listEvents(ctx context.Context, opts *metav1.ListOptions, seen map[types.UID]string) error {
+	listOpts := *opts
+	listOpts.ResourceVersion = ""
+	list, err := w.events.List(ctx, listOpts)
+	if err != nil {
+		return err
+	}
+	for i := range list.Items {
+		event := &list.Items[i]
+		if seen[event.UID] == event.ResourceVersion {
+			continue
+		}
+		seen[event.UID] = event.ResourceVersion
+		w.handleEvent(event)
+	}
+	opts.ResourceVersion = list.ResourceVersion
+	return nil
+}
+
+func isResourceVersionExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
+
+func (w *podEventWatcher) handleEvent(event *v1.Event) {
+	switch event.Reason {
+	case eventReasonFailedScheduling:
+		w.startUnschedulableTimer(event.Message)
+	case eventReasonScheduled, eventReasonTriggeredScaleUp:
+		w.stopUnschedulableTimer()
+	}
+	if event.Type != v1.EventTypeWarning {
+		return
+	}
+	msg := strings.TrimSpace(event.Message)
+	if event.Count > 1 {
+		msg = fmt.Sprintf("%s (x%d)", msg, event.Count)
+	}
+	w.writer.WriteAnnotatedLogLine(eventTime(event), "event: "+event.Reason, msg)
+}
+
+func (w *podEventWatcher) startUnschedulableTimer(message string) {
+	if w.unschedulableTimeout <= 0 {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.unschedulableMsg = strings.TrimSpace(message)
+	if w.unschedulableTimer != nil {
+		return
+	}
+	w.unschedulableTimer = time.AfterFunc(w.unschedulableTimeout, func() {
+		w.mutex.Lock()
+		w.unschedulableErr = fmt.Errorf("pod unschedulable for over %s: %s",
+			w.unschedulableTimeout, w.unschedulableMsg)
+		w.mutex.Unlock()
+		log.Warn().WithFunc(w.logFunc).
+			WithDuration("timeout", w.unschedulableTimeout).
+			Message("Pod is unschedulable. Failing step.")
+		w.onUnschedulable()
+	})
+}
+
+func (w *podEventWatcher) stopUnschedulableTimer() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.unschedulableTimer != nil {
+		w.unschedulableTimer.Stop()
+		w.unschedulableTimer = nil
+	}
+}
+
+func eventTime(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
+// podFailedError returns an error if the pod or one of its containers has
+// failed, with a human-readable reason when Kubernetes provides one.
+func podFailedError(pod *v1.Pod) error {
+	for _, c := range pod.Status.ContainerStatuses {
+		if t := c.State.Terminated; t != nil && t.ExitCode != 0 {
+			return terminatedError(t)
+		}
+	}
+	if pod.Status.Phase == v1.PodFailed && pod.Status.Reason != "" {
+		return fmt.Errorf("pod failed: %s: %s", pod.Status.Reason, pod.Status.Message)
+	}
+	return nil
+}
+
+func terminatedError(t *v1.ContainerStateTerminated) error {
+	if t.Reason == containerReasonOOMKilled {
+		return fmt.Errorf("container ran out of memory (%s): non-zero exit code: %d", t.Reason, t.ExitCode)
+	}
+	return fmt.Errorf("non-zero exit code: %d", t.ExitCode)
+}
+
+// podTermination returns how the pod's app container terminated, or how the
+// pod itself failed, such as on DeadlineExceeded. Returns nil if the pod has
+// not terminated.
+func podTermination(pod *v1.Pod) *workermodel.Termination {
+	for _, c := range pod.Status.ContainerStatuses {
+		if t := c.State.Terminated; t != nil {
+			termination := &workermodel.Termination{
+				ExitCode:   t.ExitCode,
+				Reason:     t.Reason,
+				Message:    strings.TrimSpace(t.Message),
+				StartedAt:  t.StartedAt.Time,
+				FinishedAt: t.FinishedAt.Time,
+			}
+			if pod.Status.Phase == v1.PodFailed && pod.Status.Reason != "" {
+				termination.Reason = pod.Status.Reason
+				termination.Message = pod.Status.Message
+			}
+			return termination
+		}
+	}
+	if pod.Status.Phase == v1.PodFailed && pod.Status.Reason != "" {
+		return &workermodel.Termination{
+			Reason:  pod.Status.Reason,
+			Message: pod.Status.Message,
+		}
+	}
+	return nil
+}
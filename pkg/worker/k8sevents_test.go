@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestPodEventWatcher(t *testing.T, timeout time.Duration) (*podEventWatcher, *watch.FakeWatcher, resultstore.Store, context.CancelFunc) {
+	fakeWatch := watch.NewFake()
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependWatchReactor("events", k8stesting.DefaultWatchReactor(fakeWatch, nil))
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	writer := openStepLogWriter(store, 1, "test", "step")
+	t.Cleanup(writer.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := k8sStepRunner{
+		events:    clientset.CoreV1().Events("default"),
+		logWriter: writer,
+		logFunc:   func(ev logger.Event) logger.Event { return ev },
+	}
+	r.Config = &config.Config{Worker: config.WorkerConfig{UnschedulableTimeout: timeout}}
+	return r.startPodEventWatcher(ctx, "my-pod", cancel), fakeWatch, store, cancel
+}
+
+func readTestLogMessages(t *testing.T, store resultstore.Store, stepID uint64) []string {
+	reader, err := store.OpenLogReader(stepID)
+	require.NoError(t, err)
+	defer reader.Close()
+	var messages []string
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		require.NoError(t, err)
+		messages = append(messages, line.Message)
+	}
+}
+
+func TestPodEventWatcher_writesWarnings(t *testing.T) {
+	w, fakeWatch, store, cancel := newTestPodEventWatcher(t, 0)
+	fakeWatch.Add(&v1.Event{Type: v1.EventTypeNormal, Reason: "Pulling", Message: "Pulling image"})
+	fakeWatch.Add(&v1.Event{Type: v1.EventTypeWarning, Reason: "Failed", Message: "Failed to pull image \"nope\""})
+	fakeWatch.Modify(&v1.Event{Type: v1.EventTypeWarning, Reason: "BackOff", Message: "Back-off pulling image \"nope\"", Count: 3})
+	cancel()
+	require.NoError(t, w.Wait())
+
+	want := []string{
+		`[event: Failed] Failed to pull image "nope"`,
+		`[event: BackOff] Back-off pulling image "nope" (x3)`,
+	}
+	assert.Equal(t, want, readTestLogMessages(t, store, 1))
+}
+
+func TestPodEventWatcher_unschedulable(t *testing.T) {
+	w, fakeWatch, _, _ := newTestPodEventWatcher(t, time.Millisecond)
+	fakeWatch.Add(&v1.Event{
+		Type:    v1.EventTypeWarning,
+		Reason:  eventReasonFailedScheduling,
+		Message: "0/3 nodes are available: 3 Insufficient memory.",
+	})
+	err := w.Wait()
+	assert.EqualError(t, err, "pod unschedulable for over 1ms: 0/3 nodes are available: 3 Insufficient memory.")
+}
+
+func TestPodEventWatcher_scheduledStopsTimer(t *testing.T) {
+	w, fakeWatch, _, cancel := newTestPodEventWatcher(t, 50*time.Millisecond)
+	fakeWatch.Add(&v1.Event{Type: v1.EventTypeWarning, Reason: eventReasonFailedScheduling})
+	fakeWatch.Add(&v1.Event{Type: v1.EventTypeNormal, Reason: eventReasonScheduled})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	assert.NoError(t, w.Wait())
+}
+
+func TestPodEventWatcher_resourceVersionExpired(t *testing.T) {
+	expiredWatch := watch.NewFake()
+	freshWatch := watch.NewFake()
+	watches := []*watch.FakeWatcher{expiredWatch, freshWatch}
+	var watchedVersions []string
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependWatchReactor("events", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watchedVersions = append(watchedVersions, action.(k8stesting.WatchActionImpl).WatchRestrictions.ResourceVersion)
+		next := watches[0]
+		if len(watches) > 1 {
+			watches = watches[1:]
+		}
+		return true, next, nil
+	})
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &v1.EventList{
+			ListMeta: metav1.ListMeta{ResourceVersion: "20"},
+			Items: []v1.Event{
+				{ObjectMeta: metav1.ObjectMeta{UID: "a", ResourceVersion: "10"}, Type: v1.EventTypeWarning, Reason: "Failed", Message: "seen"},
+				{ObjectMeta: metav1.ObjectMeta{UID: "b", ResourceVersion: "15"}, Type: v1.EventTypeWarning, Reason: "Failed", Message: "missed"},
+			},
+		}, nil
+	})
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	writer := openStepLogWriter(store, 1, "test", "step")
+	t.Cleanup(writer.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := k8sStepRunner{
+		events:    clientset.CoreV1().Events("default"),
+		logWriter: writer,
+		logFunc:   func(ev logger.Event) logger.Event { return ev },
+	}
+	r.Config = &config.Config{}
+	w := r.startPodEventWatcher(ctx, "my-pod", cancel)
+
+	expiredWatch.Add(&v1.Event{ObjectMeta: metav1.ObjectMeta{UID: "a", ResourceVersion: "10"}, Type: v1.EventTypeWarning, Reason: "Failed", Message: "seen"})
+	expiredWatch.Error(&metav1.Status{Status: metav1.StatusFailure, Code: 410, Reason: metav1.StatusReasonExpired})
+	freshWatch.Add(&v1.Event{ObjectMeta: metav1.ObjectMeta{UID: "c", ResourceVersion: "21"}, Type: v1.EventTypeWarning, Reason: "Failed", Message: "fresh"})
+	cancel()
+	require.NoError(t, w.Wait())
+
+	assert.Equal(t, []string{"", "20"}, watchedVersions)
+	want := []string{
+		"[event: Failed] seen",
+		"[event: Failed] missed",
+		"[event: Failed] fresh",
+	}
+	assert.Equal(t, want, readTestLogMessages(t, store, 1))
+}
+
+func TestPodFailedError(t *testing.T) {
+	oomPod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+		State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+			ExitCode: 137,
+			Reason:   containerReasonOOMKilled,
+		}},
+	}}}}
+	assert.EqualError(t, podFailedError(oomPod), "container ran out of memory (OOMKilled): non-zero exit code: 137")
+
+	evictedPod := &v1.Pod{Status: v1.PodStatus{
+		Phase:   v1.PodFailed,
+		Reason:  "Evicted",
+		Message: "The node was low on resource: memory.",
+	}}
+	assert.EqualError(t, podFailedError(evictedPod), "pod failed: Evicted: The node was low on resource: memory.")
+
+	runningPod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ok"}, Status: v1.PodStatus{Phase: v1.PodRunning}}
+	assert.NoError(t, podFailedError(runningPod))
+}
+
+func TestPodTermination(t *testing.T) {
+	started := metav1.NewTime(time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(started.Add(time.Minute))
+	oomPod := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+		State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+			ExitCode:   137,
+			Reason:     containerReasonOOMKilled,
+			StartedAt:  started,
+			FinishedAt: finished,
+		}},
+	}}}}
+	assert.Equal(t, &workermodel.Termination{
+		ExitCode:   137,
+		Reason:     containerReasonOOMKilled,
+		StartedAt:  started.Time,
+		FinishedAt: finished.Time,
+	}, podTermination(oomPod))
+
+	deadlinePod := &v1.Pod{Status: v1.PodStatus{
+		Phase:   v1.PodFailed,
+		Reason:  "DeadlineExceeded",
+		Message: "Pod was active on the node longer than the specified deadline",
+	}}
+	assert.Equal(t, &workermodel.Termination{
+		Reason:  "DeadlineExceeded",
+		Message: "Pod was active on the node longer than the specified deadline",
+	}, podTermination(deadlinePod))
+
+	runningPod := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodRunning}}
+	assert.Nil(t, podTermination(runningPod))
+}
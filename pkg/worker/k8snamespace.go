@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// K8sBuildNamespaceOptions is a struct of options for creating a dedicated
+// Kubernetes namespace for a build.
+type K8sBuildNamespaceOptions struct {
+	Config     config.K8sBuildNamespaceConfig
+	RestConfig *rest.Config
+	InstanceID string
+	BuildID    uint
+	ProjectID  uint
+}
+
+// K8sBuildNamespace is a Kubernetes namespace that is dedicated to the step
+// pods of a single build. It is deleted, together with everything in it,
+// when closed.
+type K8sBuildNamespace struct {
+	// Name is the name of the namespace.
+	Name string
+
+	clientset kubernetes.Interface
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewK8sBuildNamespace creates a new Kubernetes namespace for a build,
+// together with the ResourceQuota, LimitRange, and NetworkPolicy from the
+// config. The namespace is deleted if any of them fails to be created.
+func NewK8sBuildNamespace(ctx context.Context, opts K8sBuildNamespaceOptions) (*K8sBuildNamespace, error) {
+	clientset, err := kubernetes.NewForConfig(opts.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newK8sBuildNamespace(ctx, clientset, opts)
+}
+
+func newK8sBuildNamespace(ctx context.Context, clientset kubernetes.Interface, opts K8sBuildNamespaceOptions) (*K8sBuildNamespace, error) {
+	labels, err := opts.Config.LabelsMap()
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{
+		"app.kubernetes.io/part-of":    "wharf",
+		"app.kubernetes.io/managed-by": "wharf-cmd-worker",
+		"app.kubernetes.io/created-by": "wharf-cmd-worker",
+
+		LabelInstance:  opts.InstanceID,
+		LabelBuildRef:  strconv.FormatUint(uint64(opts.BuildID), 10),
+		LabelProjectID: strconv.FormatUint(uint64(opts.ProjectID), 10),
+	}
+	for k, v := range ids {
+		labels[k] = v
+	}
+	created, err := clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   getBuildNamespaceName(opts.Config.NamePrefix, opts.BuildID),
+			Labels: labels,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create namespace: %w", err)
+	}
+	ns := &K8sBuildNamespace{Name: created.Name, clientset: clientset}
+	log.Info().
+		WithString("namespace", ns.Name).
+		Message("Created build namespace.")
+	if err := ns.createPolicies(ctx, opts.Config, ids); err != nil {
+		if deleteErr := ns.Close(); deleteErr != nil {
+			log.Warn().
+				WithError(deleteErr).
+				WithString("namespace", ns.Name).
+				Message("Failed to delete build namespace after failing to set it up.")
+		}
+		return nil, err
+	}
+	return ns, nil
+}
+
+func (ns *K8sBuildNamespace) createPolicies(ctx context.Context, conf config.K8sBuildNamespaceConfig, labels map[string]string) error {
+	meta := metav1.ObjectMeta{
+		Name:      "wharf-build",
+		Namespace: ns.Name,
+		Labels:    labels,
+	}
+	quota, err := conf.ResourceQuotaSpec()
+	if err != nil {
+		return err
+	}
+	if quota != nil {
+		if _, err := ns.clientset.CoreV1().ResourceQuotas(ns.Name).Create(ctx, &v1.ResourceQuota{
+			ObjectMeta: meta,
+			Spec:       *quota,
+		}, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("create resource quota: %w", err)
+		}
+	}
+	limitRange, err := conf.LimitRangeSpec()
+	if err != nil {
+		return err
+	}
+	if limitRange != nil {
+		if _, err := ns.clientset.CoreV1().LimitRanges(ns.Name).Create(ctx, &v1.LimitRange{
+			ObjectMeta: meta,
+			Spec:       *limitRange,
+		}, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("create limit range: %w", err)
+		}
+	}
+	netPol, err := conf.NetworkPolicySpec()
+	if err != nil {
+		return err
+	}
+	if netPol != nil {
+		if _, err := ns.clientset.NetworkingV1().NetworkPolicies(ns.Name).Create(ctx, &networkingv1.NetworkPolicy{
+			ObjectMeta: meta,
+			Spec:       *netPol,
+		}, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("create network policy: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close deletes the namespace, which in turn makes Kubernetes delete all
+// pods and other resources inside it. It uses its own timeout instead of a
+// context, so the namespace is deleted even if the build was cancelled.
+// Calling Close more than once has no further effect.
+func (ns *K8sBuildNamespace) Close() error {
+	ns.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		ns.closeErr = ns.clientset.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if ns.closeErr != nil {
+			ns.closeErr = fmt.Errorf("delete namespace %s: %w", ns.Name, ns.closeErr)
+			return
+		}
+		log.Info().
+			WithString("namespace", ns.Name).
+			Message("Deleted build namespace.")
+	})
+	return ns.closeErr
+}
+
+func getBuildNamespaceName(prefix string, buildID uint) string {
+	// Namespaces must be valid DNS Label names (IETF RFC-1123), which are
+	// max 63 chars long, so the prefix is trimmed to fit the suffix.
+	suffix := utilrand.String(5)
+	if buildID != 0 {
+		suffix = fmt.Sprintf("%d-%s", buildID, suffix)
+	}
+	const maxLen = 63
+	if len(prefix)+len(suffix) > maxLen {
+		prefix = prefix[:maxLen-len(suffix)]
+	}
+	return prefix + suffix
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNewK8sBuildNamespace(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+	ns, err := newK8sBuildNamespace(ctx, clientset, K8sBuildNamespaceOptions{
+		Config: config.K8sBuildNamespaceConfig{
+			NamePrefix:    "wharf-build-",
+			Labels:        []string{"example.com/team=my-team"},
+			ResourceQuota: "hard:\n  requests.cpu: \"4\"\n",
+			LimitRange:    "limits:\n  - type: Container\n    defaultRequest:\n      cpu: 100m\n",
+			NetworkPolicy: config.DefaultConfig.K8s.BuildNamespace.NetworkPolicy,
+		},
+		InstanceID: "local",
+		BuildID:    12,
+		ProjectID:  34,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ns.Name, "wharf-build-12-"), "namespace name: %s", ns.Name)
+
+	created, err := clientset.CoreV1().Namespaces().Get(ctx, ns.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "my-team", created.Labels["example.com/team"])
+	assert.Equal(t, "local", created.Labels[LabelInstance])
+	assert.Equal(t, "12", created.Labels[LabelBuildRef])
+	assert.Equal(t, "34", created.Labels[LabelProjectID])
+
+	quota, err := clientset.CoreV1().ResourceQuotas(ns.Name).Get(ctx, "wharf-build", metav1.GetOptions{})
+	require.NoError(t, err)
+	cpu := quota.Spec.Hard[v1.ResourceRequestsCPU]
+	assert.Equal(t, "4", cpu.String())
+	_, err = clientset.CoreV1().LimitRanges(ns.Name).Get(ctx, "wharf-build", metav1.GetOptions{})
+	require.NoError(t, err)
+	netPol, err := clientset.NetworkingV1().NetworkPolicies(ns.Name).Get(ctx, "wharf-build", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, netPol.Spec.Ingress, "denies all ingress")
+
+	require.NoError(t, ns.Close())
+	require.NoError(t, ns.Close(), "closing twice")
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, ns.Name, metav1.GetOptions{})
+	assert.Error(t, err, "namespace should be deleted")
+}
+
+func TestNewK8sBuildNamespace_deletesNamespaceOnFailure(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "resourcequotas", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	ctx := context.Background()
+	_, err := newK8sBuildNamespace(ctx, clientset, K8sBuildNamespaceOptions{
+		Config: config.K8sBuildNamespaceConfig{
+			NamePrefix:    "wharf-build-",
+			ResourceQuota: "hard:\n  pods: \"10\"\n",
+		},
+	})
+	require.Error(t, err)
+
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Items)
+}
+
+func TestGetBuildNamespaceName(t *testing.T) {
+	name := getBuildNamespaceName("wharf-build-", 0)
+	assert.Len(t, name, len("wharf-build-")+5)
+
+	name = getBuildNamespaceName(strings.Repeat("a", 70), 123)
+	assert.Len(t, name, 63)
+	assert.True(t, strings.HasPrefix(name, strings.Repeat("a", 63-len("123-xxxxx"))+"123-"), name)
+}
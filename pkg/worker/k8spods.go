@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Labels and annotations set on step pods by the Kubernetes step runner.
+const (
+	LabelInstance  = "wharf.iver.com/instance"
+	LabelBuildRef  = "wharf.iver.com/build-ref"
+	LabelProjectID = "wharf.iver.com/project-id"
+	LabelStageID   = "wharf.iver.com/stage-id"
+	LabelStepID    = "wharf.iver.com/step-id"
+
+	AnnotationStageName = "wharf.iver.com/stage-name"
+	AnnotationStepName  = "wharf.iver.com/step-name"
+)
+
+// K8sStepPodSelector selects step pods created by the Kubernetes step runner,
+// based on their labels. Fields with zero values are not filtered on.
+type K8sStepPodSelector struct {
+	InstanceID string
+	BuildID    uint
+	ProjectID  uint
+	StageID    uint64
+	StepID     uint64
+}
+
+// LabelSelector returns the Kubernetes label selector of this selector.
+func (s K8sStepPodSelector) LabelSelector() string {
+	set := labels.Set{
+		"app.kubernetes.io/name":       "wharf-cmd-worker-step",
+		"app.kubernetes.io/managed-by": "wharf-cmd-worker",
+	}
+	if s.InstanceID != "" {
+		set[LabelInstance] = s.InstanceID
+	}
+	if s.BuildID != 0 {
+		set[LabelBuildRef] = strconv.FormatUint(uint64(s.BuildID), 10)
+	}
+	if s.ProjectID != 0 {
+		set[LabelProjectID] = strconv.FormatUint(uint64(s.ProjectID), 10)
+	}
+	if s.StageID != 0 {
+		set[LabelStageID] = strconv.FormatUint(s.StageID, 10)
+	}
+	if s.StepID != 0 {
+		set[LabelStepID] = strconv.FormatUint(s.StepID, 10)
+	}
+	return labels.SelectorFromSet(set).String()
+}
+
+// ListK8sStepPods returns all step pods matching the selector.
+func ListK8sStepPods(ctx context.Context, pods corev1.PodInterface, sel K8sStepPodSelector) ([]v1.Pod, error) {
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: sel.LabelSelector()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// DeleteK8sStepPods deletes all step pods matching the selector, and returns
+// the names of the deleted pods.
+func DeleteK8sStepPods(ctx context.Context, pods corev1.PodInterface, sel K8sStepPodSelector) ([]string, error) {
+	list, err := ListK8sStepPods(ctx, pods, sel)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, pod := range list {
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, pod.Name)
+	}
+	return deleted, nil
+}
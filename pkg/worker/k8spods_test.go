@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sStepPodSelector_LabelSelector(t *testing.T) {
+	sel := K8sStepPodSelector{InstanceID: "local", BuildID: 12, StepID: 3}
+	want := "app.kubernetes.io/managed-by=wharf-cmd-worker," +
+		"app.kubernetes.io/name=wharf-cmd-worker-step," +
+		"wharf.iver.com/build-ref=12," +
+		"wharf.iver.com/instance=local," +
+		"wharf.iver.com/step-id=3"
+	assert.Equal(t, want, sel.LabelSelector())
+}
+
+func TestDeleteK8sStepPods(t *testing.T) {
+	newPod := func(name, buildRef string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "wharf-cmd-worker-step",
+				"app.kubernetes.io/managed-by": "wharf-cmd-worker",
+				LabelInstance:                  "local",
+				LabelBuildRef:                  buildRef,
+			},
+		}}
+	}
+	clientset := fake.NewSimpleClientset(
+		newPod("build-1-a", "1"),
+		newPod("build-1-b", "1"),
+		newPod("build-2-a", "2"),
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}},
+	)
+	pods := clientset.CoreV1().Pods("default")
+	ctx := context.Background()
+
+	deleted, err := DeleteK8sStepPods(ctx, pods, K8sStepPodSelector{InstanceID: "local", BuildID: 1})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"build-1-a", "build-1-b"}, deleted)
+
+	remaining, err := pods.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, pod := range remaining.Items {
+		names = append(names, pod.Name)
+	}
+	assert.ElementsMatch(t, []string{"build-2-a", "unrelated"}, names)
+}
@@ -2,9 +2,12 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/iver-wharf/wharf-cmd/pkg/steps"
@@ -13,44 +16,54 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
 )
 
-func (f k8sStepRunnerFactory) getStepPodSpec(ctx context.Context, step wharfyml.Step) (v1.Pod, error) {
+func (f k8sStepRunnerFactory) getStepPodSpec(ctx context.Context, step wharfyml.Step, stepID uint64) (v1.Pod, error) {
 	podSpecer, ok := step.Type.(steps.PodSpecer)
 	if !ok {
 		return v1.Pod{}, errors.New("step type cannot produce a Kubernetes Pod specification")
 	}
 
+	stageID, _ := contextStageID(ctx)
+	ids := map[string]string{
+		LabelBuildRef:  strconv.FormatUint(uint64(f.BuildID), 10),
+		LabelProjectID: strconv.FormatUint(uint64(f.ProjectID), 10),
+		LabelStageID:   strconv.FormatUint(stageID, 10),
+		LabelStepID:    strconv.FormatUint(stepID, 10),
+	}
 	annotations := map[string]string{
-		"wharf.iver.com/project-id": "456", // TODO: Use real numbers
-		"wharf.iver.com/stage-id":   "789",
-		"wharf.iver.com/step-id":    "789",
-		"wharf.iver.com/step-name":  step.Name,
+		AnnotationStepName: step.Name,
 	}
 	if stage, ok := contextStageName(ctx); ok {
-		annotations["wharf.iver.com/stage-name"] = stage
-	}
-	pod := v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: getPodGenerateName(step),
-			Annotations:  annotations,
-			Labels: map[string]string{
-				"app":                          "wharf-cmd-worker-step",
-				"app.kubernetes.io/name":       "wharf-cmd-worker-step",
-				"app.kubernetes.io/part-of":    "wharf",
-				"app.kubernetes.io/managed-by": "wharf-cmd-worker",
-				"app.kubernetes.io/created-by": "wharf-cmd-worker",
-
-				"wharf.iver.com/instance":   f.Config.InstanceID,
-				"wharf.iver.com/build-ref":  "123", // TODO: Use real numbers
-				"wharf.iver.com/project-id": "456",
-				"wharf.iver.com/stage-id":   "789",
-				"wharf.iver.com/step-id":    "789",
-			},
-			OwnerReferences: getOwnerReferences(),
-		},
-		Spec: podSpecer.PodSpec(),
+		annotations[AnnotationStageName] = stage
+	}
+	labels := map[string]string{
+		"app":                          "wharf-cmd-worker-step",
+		"app.kubernetes.io/name":       "wharf-cmd-worker-step",
+		"app.kubernetes.io/part-of":    "wharf",
+		"app.kubernetes.io/managed-by": "wharf-cmd-worker",
+		"app.kubernetes.io/created-by": "wharf-cmd-worker",
+
+		LabelInstance: f.Config.InstanceID,
+	}
+	for k, v := range ids {
+		annotations[k] = v
+		labels[k] = v
 	}
+	pod := v1.Pod{Spec: podSpecer.PodSpec()}
+	stepsConf := f.Config.Worker.Steps
+	if err := applyPodTemplates(&pod,
+		stepsConf.PodTemplate,
+		stepsConf.PodTemplateForStepType(step.Type.StepTypeName()),
+	); err != nil {
+		return v1.Pod{}, err
+	}
+	pod.GenerateName = getPodGenerateName(step)
+	pod.OwnerReferences = getOwnerReferences(f.Config.K8s.Namespace)
+	pod.Annotations = mergeStringMaps(pod.Annotations, annotations)
+	pod.Labels = mergeStringMaps(pod.Labels, labels)
 
 	if len(pod.Spec.Containers) == 0 {
 		return v1.Pod{}, errors.New("step type did not add an app container")
@@ -59,6 +72,47 @@ func (f k8sStepRunnerFactory) getStepPodSpec(ctx context.Context, step wharfyml.
 	return pod, nil
 }
 
+// applyPodTemplates applies the Kubernetes Pod templates from the config, in
+// order, as strategic merge patches onto the pod. Empty templates are skipped.
+func applyPodTemplates(pod *v1.Pod, templates ...string) error {
+	for _, tmpl := range templates {
+		if strings.TrimSpace(tmpl) == "" {
+			continue
+		}
+		patch, err := yaml.YAMLToJSON([]byte(tmpl))
+		if err != nil {
+			return fmt.Errorf("parse pod template: %w", err)
+		}
+		original, err := json.Marshal(pod)
+		if err != nil {
+			return fmt.Errorf("marshal pod: %w", err)
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, patch, v1.Pod{})
+		if err != nil {
+			return fmt.Errorf("apply pod template: %w", err)
+		}
+		var patchedPod v1.Pod
+		if err := json.Unmarshal(patched, &patchedPod); err != nil {
+			return fmt.Errorf("unmarshal patched pod: %w", err)
+		}
+		*pod = patchedPod
+	}
+	return nil
+}
+
+// mergeStringMaps returns a new map with the values from the second map
+// taking precedence over the first.
+func mergeStringMaps(a, b map[string]string) map[string]string {
+	merged := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
 func getPodGenerateName(step wharfyml.Step) string {
 	name := fmt.Sprintf("wharf-build-%s-%s-",
 		sanitizePodName(step.Type.StepTypeName()),
@@ -90,7 +144,11 @@ func sanitizePodName(name string) string {
 	return name
 }
 
-func getOwnerReferences() []metav1.OwnerReference {
+// getOwnerReferences returns an owner reference to the worker pod, if running
+// inside Kubernetes. Returns nil if the step pod is created in a different
+// namespace than the worker pod, such as when using dedicated build
+// namespaces, as Kubernetes does not allow cross-namespace owner references.
+func getOwnerReferences(podNamespace string) []metav1.OwnerReference {
 	var enabled bool
 	if err := env.Bind(&enabled, "WHARF_KUBERNETES_OWNER_ENABLE"); err != nil {
 		log.Warn().WithError(err).Message("Failed binding WHARF_KUBERNETES_OWNER_ENABLE environment variable.")
@@ -112,6 +170,14 @@ func getOwnerReferences() []metav1.OwnerReference {
 		return nil
 	}
 
+	if ownerNamespace, ok := getOwnerNamespace(); ok && ownerNamespace != podNamespace {
+		log.Debug().
+			WithString("ownerNamespace", ownerNamespace).
+			WithString("podNamespace", podNamespace).
+			Message("Skipping Kubernetes OwnerReference because the pod is in a different namespace than its owner.")
+		return nil
+	}
+
 	log.Info().
 		WithString("name", name).
 		WithString("uid", uid).
@@ -130,6 +196,25 @@ func getOwnerReferences() []metav1.OwnerReference {
 	}
 }
 
+// serviceAccountNamespaceFile is mounted into all pods that use a service
+// account, and contains the namespace of the pod.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// getOwnerNamespace returns the namespace of the worker pod, as set by the
+// provisioner. Falls back to the namespace of the pod's service account, for
+// worker pods created by older provisioners. Returns false if not known.
+func getOwnerNamespace() (string, bool) {
+	if ns, ok := env.LookupNoEmpty("WHARF_KUBERNETES_OWNER_NAMESPACE"); ok {
+		return ns, true
+	}
+	b, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", false
+	}
+	ns := strings.TrimSpace(string(b))
+	return ns, ns != ""
+}
+
 func getOnlyFilesToTransfer(step wharfyml.Step) ([]string, bool) {
 	switch s := step.Type.(type) {
 	case steps.Helm:
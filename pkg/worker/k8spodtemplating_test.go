@@ -1,9 +1,13 @@
 package worker
 
 import (
+	"context"
 	"testing"
 
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
 )
 
 func TestSanitizePodName(t *testing.T) {
@@ -50,3 +54,91 @@ func TestSanitizePodName(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyPodTemplates(t *testing.T) {
+	pod := v1.Pod{Spec: newTestPodStep("test").Type.(testPodStep).podSpec}
+	pod.Spec.ServiceAccountName = "wharf-cmd"
+	err := applyPodTemplates(&pod, `
+metadata:
+  labels:
+    example.com/team: my-team
+spec:
+  priorityClassName: ci
+  imagePullSecrets:
+    - name: my-mirror
+  initContainers:
+    - name: init
+      image: my-mirror.example.com/alpine:3
+  volumes:
+    - name: extra
+      emptyDir: {}
+`, "", `
+spec:
+  securityContext:
+    runAsNonRoot: true
+  serviceAccountName: my-sa
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "my-team", pod.Labels["example.com/team"])
+	assert.Equal(t, "ci", pod.Spec.PriorityClassName)
+	assert.Equal(t, "my-sa", pod.Spec.ServiceAccountName)
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "my-mirror"}}, pod.Spec.ImagePullSecrets)
+	require.NotNil(t, pod.Spec.SecurityContext)
+	require.NotNil(t, pod.Spec.SecurityContext.RunAsNonRoot)
+	assert.True(t, *pod.Spec.SecurityContext.RunAsNonRoot)
+
+	require.Len(t, pod.Spec.InitContainers, 1, "init containers are merged by name")
+	assert.Equal(t, "my-mirror.example.com/alpine:3", pod.Spec.InitContainers[0].Image)
+	require.Len(t, pod.Spec.Containers, 1)
+	assert.Equal(t, "alpine:3", pod.Spec.Containers[0].Image)
+
+	var volumeNames []string
+	for _, vol := range pod.Spec.Volumes {
+		volumeNames = append(volumeNames, vol.Name)
+	}
+	assert.ElementsMatch(t, []string{"repo", "certs", "extra"}, volumeNames)
+}
+
+func TestApplyPodTemplates_invalid(t *testing.T) {
+	pod := v1.Pod{Spec: newTestPodStep("test").Type.(testPodStep).podSpec}
+	err := applyPodTemplates(&pod, "spec: [")
+	assert.Error(t, err)
+}
+
+func TestGetStepPodSpec_podTemplateCannotOverrideWharfLabels(t *testing.T) {
+	f := k8sStepRunnerFactory{K8sRunnerOptions: K8sRunnerOptions{
+		BuildID: 12,
+		Config: &config.Config{
+			InstanceID: "local",
+			Worker: config.WorkerConfig{Steps: config.StepsConfig{
+				PodTemplate: `
+metadata:
+  labels:
+    example.com/team: my-team
+    wharf.iver.com/instance: other
+`,
+			}},
+		},
+	}}
+	pod, err := f.getStepPodSpec(context.Background(), newTestPodStep("compile"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "my-team", pod.Labels["example.com/team"])
+	assert.Equal(t, "local", pod.Labels[LabelInstance])
+	assert.Equal(t, "12", pod.Labels[LabelBuildRef])
+	assert.Equal(t, "wharf-build-test-compile-", pod.GenerateName)
+}
+
+func TestGetOwnerReferences(t *testing.T) {
+	t.Setenv("WHARF_KUBERNETES_OWNER_ENABLE", "true")
+	t.Setenv("WHARF_KUBERNETES_OWNER_NAME", "wharf-cmd-worker-abc")
+	t.Setenv("WHARF_KUBERNETES_OWNER_UID", "123")
+	t.Setenv("WHARF_KUBERNETES_OWNER_NAMESPACE", "wharf")
+
+	refs := getOwnerReferences("wharf")
+	require.Len(t, refs, 1)
+	assert.Equal(t, "wharf-cmd-worker-abc", refs[0].Name)
+
+	refs = getOwnerReferences("wharf-build-123")
+	assert.Empty(t, refs, "cross-namespace owner reference")
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	v1 "k8s.io/api/core/v1"
+)
+
+// RenderK8sPods returns the Kubernetes Pod manifests of all steps in the build
+// definition, as they would be created by the Kubernetes step runner, but
+// without contacting Kubernetes. Only the BuildOptions and Config fields of
+// the options are used, where the stage and step filters are respected.
+//
+// The step IDs are assigned in the same way as when running the build. The
+// pods are returned in the order the steps are declared, and only have a
+// generated name prefix set, as the full name is set by Kubernetes on
+// creation.
+func RenderK8sPods(ctx context.Context, def wharfyml.Definition, opts K8sRunnerOptions) ([]v1.Pod, error) {
+	stages := filterStages(def.Stages, opts.StageFilter)
+	stages, err := filterStagesSteps(stages, opts.StepFilter, opts.SkipStepFilter)
+	if err != nil {
+		return nil, err
+	}
+	f := k8sStepRunnerFactory{K8sRunnerOptions: opts}
+	var pods []v1.Pod
+	stepID := uint64(1)
+	for _, stage := range stages {
+		stageCtx := contextWithStageID(ctx, stageID(def.Stages, stage.Name))
+		stageCtx = contextWithStageName(stageCtx, stage.Name)
+		for _, step := range stage.Steps {
+			pod, err := f.getStepPodSpec(contextWithStepName(stageCtx, step.Name), step, stepID)
+			stepID++
+			if err != nil {
+				return nil, fmt.Errorf("stage %s: step %s: %w", stage.Name, step.Name, err)
+			}
+			pod.APIVersion = "v1"
+			pod.Kind = "Pod"
+			pod.Namespace = opts.Config.K8s.Namespace
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderK8sPods(t *testing.T) {
+	def := wharfyml.Definition{
+		Stages: []wharfyml.Stage{
+			{Name: "build", Steps: []wharfyml.Step{newTestPodStep("compile"), newTestPodStep("lint")}},
+			{Name: "deploy", Steps: []wharfyml.Step{newTestPodStep("push")}},
+		},
+	}
+	opts := K8sRunnerOptions{
+		BuildOptions: BuildOptions{SkipStepFilter: []string{"lint"}},
+		BuildID:      12,
+		ProjectID:    34,
+		Config: &config.Config{
+			InstanceID: "local",
+			K8s:        config.K8sConfig{Namespace: "my-ns"},
+		},
+	}
+	pods, err := RenderK8sPods(context.Background(), def, opts)
+	require.NoError(t, err)
+	require.Len(t, pods, 2)
+
+	assert.Equal(t, "Pod", pods[0].Kind)
+	assert.Equal(t, "v1", pods[0].APIVersion)
+	assert.Equal(t, "my-ns", pods[0].Namespace)
+	assert.Equal(t, "wharf-build-test-compile-", pods[0].GenerateName)
+	assert.Equal(t, "local", pods[0].Labels["wharf.iver.com/instance"])
+	assert.Equal(t, "build", pods[0].Annotations["wharf.iver.com/stage-name"])
+	assert.Equal(t, "compile", pods[0].Annotations["wharf.iver.com/step-name"])
+	assert.Equal(t, "12", pods[0].Labels[LabelBuildRef])
+	assert.Equal(t, "34", pods[0].Labels[LabelProjectID])
+	assert.Equal(t, "1", pods[0].Labels[LabelStageID])
+	assert.Equal(t, "1", pods[0].Labels[LabelStepID])
+	assert.Len(t, pods[0].Spec.Containers, 1)
+
+	assert.Equal(t, "deploy", pods[1].Annotations["wharf.iver.com/stage-name"])
+	assert.Equal(t, "push", pods[1].Annotations["wharf.iver.com/step-name"])
+	assert.Equal(t, "2", pods[1].Labels[LabelStageID])
+	assert.Equal(t, "2", pods[1].Labels[LabelStepID])
+}
@@ -11,19 +11,20 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/iver-wharf/wharf-cmd/internal/filecopy"
-	"github.com/iver-wharf/wharf-cmd/internal/gitutil"
-	"github.com/iver-wharf/wharf-cmd/internal/ignorer"
 	"github.com/iver-wharf/wharf-cmd/pkg/config"
 	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
 	"github.com/iver-wharf/wharf-cmd/pkg/steps"
 	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
 	"github.com/iver-wharf/wharf-cmd/pkg/varsub"
 	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
@@ -61,6 +62,18 @@ type K8sRunnerOptions struct {
 	SkipGitIgnore bool
 	CurrentDir    string
 	DryRun        DryRun
+	// BuildID and ProjectID are added as labels and annotations on each step
+	// pod, together with the stage and step IDs, so the pods can be traced
+	// back to their build.
+	BuildID   uint
+	ProjectID uint
+	// KeepFailedPods skips deleting the pods of failed steps, and starts a
+	// debug pod for each failed step with the repository transferred to it.
+	KeepFailedPods bool
+	// DebugOnFailure does the same as KeepFailedPods, but also opens an
+	// interactive shell in the debug pod. Both the failed pod and debug pod
+	// are deleted when the shell exits.
+	DebugOnFailure bool
 }
 
 // NewK8s is a helper function that creates a new builder using the
@@ -80,7 +93,7 @@ func NewK8sStageRunnerFactory(opts K8sRunnerOptions) (StageRunnerFactory, error)
 	if err != nil {
 		return nil, err
 	}
-	return NewStageRunnerFactory(stepFactory)
+	return NewStageRunnerFactory(stepFactory, opts.Config.Worker.MaxParallelSteps)
 }
 
 // NewK8sStepRunnerFactory returns a new step runner factory that creates
@@ -94,37 +107,46 @@ func NewK8sStepRunnerFactory(opts K8sRunnerOptions) (StepRunnerFactory, error) {
 	factory := k8sStepRunnerFactory{
 		K8sRunnerOptions: opts,
 		clientset:        clientset,
+		repoManifests:    &repoManifestCache{},
 	}
 	return factory, nil
 }
 
 type k8sStepRunnerFactory struct {
 	K8sRunnerOptions
-	clientset *kubernetes.Clientset
+	clientset     *kubernetes.Clientset
+	repoManifests *repoManifestCache
 }
 
 func (f k8sStepRunnerFactory) NewStepRunner(
 	ctx context.Context, step wharfyml.Step, stepID uint64) (StepRunner, error) {
 	ctx = contextWithStepName(ctx, step.Name)
-	pod, err := f.getStepPodSpec(ctx, step)
+	pod, err := f.getStepPodSpec(ctx, step, stepID)
 	if err != nil {
 		return nil, err
 	}
 
-	tarball, err := f.prepareStepRepo(step, stepID)
+	tarball, err := f.stepRepoPreparer().prepareStepRepo(ctx, step, stepID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := setStepMeta(ctx, f.ResultStore, step, stepID); err != nil {
+		return nil, err
+	}
+
 	r := k8sStepRunner{
 		K8sRunnerOptions: f.K8sRunnerOptions,
 		log:              logger.NewScoped(contextStageStepName(ctx)),
+		logScope:         contextStageStepName(ctx),
 		step:             step,
 		pod:              &pod,
 		clientset:        f.clientset,
 		pods:             f.clientset.CoreV1().Pods(f.Config.K8s.Namespace),
+		events:           f.clientset.CoreV1().Events(f.Config.K8s.Namespace),
 		stepID:           stepID,
 		repoTar:          tarball,
+		repoManifests:    f.repoManifests,
 		target: &target{
 			namespace: f.Config.K8s.Namespace,
 			name:      "",
@@ -146,71 +168,33 @@ func (f k8sStepRunnerFactory) NewStepRunner(
 	return r, nil
 }
 
-func (f k8sStepRunnerFactory) prepareStepRepo(step wharfyml.Step, stepID uint64) (tarstore.Tarball, error) {
-	onlyFiles, hasFileFilter := getOnlyFilesToTransfer(step)
-	copier := f.getStepRepoCopier(hasFileFilter)
-	ignorer, err := f.getStepRepoIgnorer(onlyFiles, hasFileFilter)
-	if err != nil {
-		return "", err
-	}
-	tarID := f.getStepTarID(stepID, hasFileFilter)
-
-	tarball, err := f.TarStore.GetPreparedTarball(copier, ignorer, tarID)
-	if err != nil {
-		return "", err
-	}
-	return tarball, nil
-}
-
-func (f k8sStepRunnerFactory) getStepTarID(stepID uint64, hasFileFilter bool) string {
-	if hasFileFilter {
-		return fmt.Sprintf("step-%d", stepID)
-	}
-	return "full"
-}
-
-func (f k8sStepRunnerFactory) getStepRepoCopier(hasFileFilter bool) filecopy.Copier {
-	if hasFileFilter {
-		return varsub.NewCopier(f.VarSource)
-	}
-	return filecopy.IOCopier
-}
-
-func (f k8sStepRunnerFactory) getStepRepoIgnorer(onlyFiles []string, hasFileFilter bool) (ignorer.Ignorer, error) {
-	var igns []ignorer.Ignorer
-	if hasFileFilter {
-		igns = append(igns, ignorer.NewFileIncluder(onlyFiles))
-	}
-
-	if !f.SkipGitIgnore {
-		repoRoot, err := gitutil.GitRepoRoot(f.CurrentDir)
-		if err != nil {
-			return nil, err
-		}
-		gitIgn, err := gitutil.NewIgnorer(f.CurrentDir, repoRoot)
-		if err != nil {
-			return nil, err
-		}
-		igns = append(igns, gitIgn)
-	}
-
-	if len(igns) == 0 {
-		return nil, nil
+func (f k8sStepRunnerFactory) stepRepoPreparer() stepRepoPreparer {
+	return stepRepoPreparer{
+		tarStore:      f.TarStore,
+		varSource:     f.VarSource,
+		skipGitIgnore: f.SkipGitIgnore,
+		currentDir:    f.CurrentDir,
 	}
-	return ignorer.Merge(igns...), nil
 }
 
 type k8sStepRunner struct {
 	K8sRunnerOptions
 	log       logger.Logger
+	logScope  string
 	step      wharfyml.Step
 	pod       *v1.Pod
 	clientset *kubernetes.Clientset
 	pods      corev1.PodInterface
+	events    corev1.EventInterface
+	logWriter *stepLogWriter
 	stepID    uint64
 	repoTar   tarstore.Tarball
 	target    *target
 	logFunc   func(ev logger.Event) logger.Event
+	podPhases *podPhaseTracer
+	podSched  *podSchedulingTimer
+
+	repoManifests *repoManifestCache
 }
 
 type target struct {
@@ -227,25 +211,36 @@ func (r k8sStepRunner) RunStep(ctx context.Context) StepResult {
 	ctx = contextWithStepName(ctx, r.step.Name)
 	start := time.Now()
 	status := workermodel.StatusSuccess
-	err := r.runStep(ctx)
+	termination, err := r.runStep(ctx)
 	if errors.Is(ctx.Err(), context.Canceled) {
 		status = workermodel.StatusCancelled
 	} else if err != nil {
-		status = workermodel.StatusFailed
+		status = failedStepStatus(r.step)
+	}
+	if err := addFinalStatusUpdate(r.ResultStore, r.stepID, status, termination); err != nil {
+		log.Warn().
+			WithError(err).
+			WithFunc(r.logFunc).
+			WithStringer("status", status).
+			Message("Failed to add status update.")
 	}
-	r.addStatusUpdate(status)
 	return StepResult{
-		Name:     r.step.Name,
-		Status:   status,
-		Type:     r.step.Type.StepTypeName(),
-		Error:    err,
-		Duration: time.Since(start),
+		Name:        r.step.Name,
+		Status:      status,
+		Type:        r.step.Type.StepTypeName(),
+		Error:       err,
+		Duration:    time.Since(start),
+		Termination: termination,
 	}
 }
 
-func (r k8sStepRunner) runStep(ctx context.Context) error {
+func (r k8sStepRunner) ReportStepStatus(status workermodel.Status) {
+	r.addStatusUpdate(status)
+}
+
+func (r k8sStepRunner) runStep(ctx context.Context) (*workermodel.Termination, error) {
 	if r.DryRun != DryRunNone {
-		return r.dryRunStep(ctx)
+		return nil, r.dryRunStep(ctx)
 	}
 	return r.liveRunStep(ctx)
 }
@@ -279,7 +274,7 @@ func (r k8sStepRunner) dryRunStep(ctx context.Context) error {
 	return nil
 }
 
-func (r k8sStepRunner) liveRunStep(ctx context.Context) error {
+func (r k8sStepRunner) liveRunStep(ctx context.Context) (termination *workermodel.Termination, runErr error) {
 	log.Debug().
 		WithString("step", r.step.Name).
 		WithString("pod", r.pod.GenerateName).
@@ -287,42 +282,124 @@ func (r k8sStepRunner) liveRunStep(ctx context.Context) error {
 	r.addStatusUpdate(workermodel.StatusInitializing)
 	newPod, err := r.pods.Create(ctx, r.pod, metav1.CreateOptions{})
 	if err != nil {
-		return fmt.Errorf("create pod: %w", err)
+		return nil, fmt.Errorf("create pod: %w", err)
 	}
 	r.target.name = newPod.Name
+	r.podPhases = newPodPhaseTracer(ctx, newPod.Name)
+	r.podPhases.observe(newPod)
+	r.podSched = newPodSchedulingTimer(r.step.Type.StepTypeName())
+	defer r.podPhases.end()
 
 	log.Debug().WithFunc(r.logFunc).Message("Created pod.")
-	defer r.stopPodNow(context.Background())
+	defer func() {
+		if runErr != nil && ctx.Err() == nil && r.shouldKeepFailedPod() {
+			r.keepFailedPod(context.Background())
+			return
+		}
+		r.stopPodNow(context.Background())
+	}()
+
+	r.logWriter = openStepLogWriter(r.ResultStore, r.stepID, r.logScope, r.appContainerName())
+	defer r.logWriter.Close()
+	podCtx, cancelPod := context.WithCancel(ctx)
+	eventWatcher := r.startPodEventWatcher(podCtx, newPod.Name, cancelPod)
+	defer func() {
+		cancelPod()
+		if err := eventWatcher.Wait(); err != nil {
+			runErr = err
+		}
+	}()
+	// Registered last so it runs before the pod is deleted.
+	defer func() {
+		termination = r.getPodTermination(context.Background())
+	}()
+
 	log.Debug().WithFunc(r.logFunc).Message("Waiting for init container to start.")
-	if err := r.waitForInitContainerRunning(ctx, newPod.ObjectMeta); err != nil {
-		return fmt.Errorf("wait for init container: %w", err)
+	if err := r.waitForInitContainerRunning(podCtx, newPod.ObjectMeta); err != nil {
+		return nil, fmt.Errorf("wait for init container: %w", err)
 	}
 
 	log.Debug().WithFunc(r.logFunc).Message("Transferring data to pod.")
-	if err := r.transferDataToPod(ctx); err != nil {
-		return err
+	if err := r.transferDataToPod(podCtx); err != nil {
+		r.readInitContainerLogs(podCtx)
+		return nil, err
 	}
 	log.Debug().WithFunc(r.logFunc).Message("Transferred data to pod.")
 
 	if err := r.continueInitContainer(); err != nil {
-		return fmt.Errorf("continue init container: %w", err)
+		r.readInitContainerLogs(podCtx)
+		return nil, fmt.Errorf("continue init container: %w", err)
 	}
 	r.addStatusUpdate(workermodel.StatusRunning)
 
 	log.Debug().WithFunc(r.logFunc).Message("Waiting for app container to start.")
-	if err := r.waitForAppContainerRunningOrDone(ctx, newPod.ObjectMeta); err != nil {
-		if err := r.readLogs(ctx, &v1.PodLogOptions{}); err != nil {
+	err = r.waitForAppContainerRunningOrDone(podCtx, newPod.ObjectMeta)
+	r.readInitContainerLogs(podCtx)
+	if err != nil {
+		if err := r.readLogs(podCtx, r.appContainerName(), &v1.PodLogOptions{Timestamps: true}); err != nil {
 			log.Debug().WithError(err).
 				Message("Failed to read logs from failed container.")
 		}
-		return fmt.Errorf("wait for app container: %w", err)
+		return nil, fmt.Errorf("wait for app container: %w", err)
 	}
+	sidecarCtx, cancelSidecars := context.WithCancel(podCtx)
+	waitForSidecars := r.followSidecarLogs(sidecarCtx)
+	defer func() {
+		cancelSidecars()
+		waitForSidecars()
+	}()
 	log.Debug().WithFunc(r.logFunc).Message("App container running. Streaming logs.")
-	if err := r.readLogs(ctx, &v1.PodLogOptions{Follow: true, Timestamps: true}); err != nil {
-		return fmt.Errorf("stream logs: %w", err)
+	if err := r.readLogs(podCtx, r.appContainerName(), &v1.PodLogOptions{Follow: true, Timestamps: true}); err != nil {
+		return nil, fmt.Errorf("stream logs: %w", err)
 	}
 	log.Debug().WithFunc(r.logFunc).Message("Logs ended. Waiting for termination.")
-	return r.waitForAppContainerDone(ctx, newPod.ObjectMeta)
+	return nil, r.waitForAppContainerDone(podCtx, newPod.ObjectMeta)
+}
+
+func (r k8sStepRunner) appContainerName() string {
+	return r.pod.Spec.Containers[0].Name
+}
+
+// readInitContainerLogs reads the logs of all init containers, such as to
+// show errors from when the repo was transferred.
+func (r k8sStepRunner) readInitContainerLogs(ctx context.Context) {
+	for _, c := range r.pod.Spec.InitContainers {
+		if err := r.readLogs(ctx, c.Name, &v1.PodLogOptions{Timestamps: true}); err != nil {
+			log.Debug().WithError(err).WithFunc(r.logFunc).
+				WithString("container", c.Name).
+				Message("Failed to read logs from init container.")
+		}
+	}
+}
+
+// followSidecarLogs streams the logs of all containers other than the app
+// container, until the context is cancelled. The returned function waits for
+// all streams to end.
+func (r k8sStepRunner) followSidecarLogs(ctx context.Context) func() {
+	var wg sync.WaitGroup
+	for _, c := range r.pod.Spec.Containers[1:] {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := r.readLogs(ctx, name, &v1.PodLogOptions{Follow: true, Timestamps: true})
+			if err != nil && ctx.Err() == nil {
+				log.Debug().WithError(err).WithFunc(r.logFunc).
+					WithString("container", name).
+					Message("Failed to stream logs from sidecar container.")
+			}
+		}(c.Name)
+	}
+	return wg.Wait
+}
+
+func (r k8sStepRunner) getPodTermination(ctx context.Context) *workermodel.Termination {
+	pod, err := r.pods.Get(ctx, r.target.name, metav1.GetOptions{})
+	if err != nil {
+		log.Warn().WithError(err).WithFunc(r.logFunc).
+			Message("Failed to get pod to read its termination.")
+		return nil
+	}
+	return podTermination(pod)
 }
 
 func (r k8sStepRunner) waitForInitContainerRunning(ctx context.Context, podMeta metav1.ObjectMeta) error {
@@ -338,11 +415,11 @@ func (r k8sStepRunner) waitForInitContainerRunning(ctx context.Context, podMeta
 
 func (r k8sStepRunner) waitForAppContainerRunningOrDone(ctx context.Context, podMeta metav1.ObjectMeta) error {
 	return r.waitForPodModifiedFunc(ctx, podMeta, func(pod *v1.Pod) (bool, error) {
+		if err := r.checkPodFailed(pod); err != nil {
+			return false, err
+		}
 		for _, c := range pod.Status.ContainerStatuses {
 			if c.State.Terminated != nil {
-				if c.State.Terminated.ExitCode != 0 {
-					return false, fmt.Errorf("non-zero exit code: %d", c.State.Terminated.ExitCode)
-				}
 				return true, nil
 			}
 			if c.State.Waiting != nil &&
@@ -359,11 +436,11 @@ func (r k8sStepRunner) waitForAppContainerRunningOrDone(ctx context.Context, pod
 
 func (r k8sStepRunner) waitForAppContainerDone(ctx context.Context, podMeta metav1.ObjectMeta) error {
 	return r.waitForPodModifiedFunc(ctx, podMeta, func(pod *v1.Pod) (bool, error) {
+		if err := r.checkPodFailed(pod); err != nil {
+			return false, err
+		}
 		for _, c := range pod.Status.ContainerStatuses {
 			if c.State.Terminated != nil {
-				if c.State.Terminated.ExitCode != 0 {
-					return false, fmt.Errorf("non-zero exit code: %d", c.State.Terminated.ExitCode)
-				}
 				return true, nil
 			}
 		}
@@ -371,6 +448,23 @@ func (r k8sStepRunner) waitForAppContainerDone(ctx context.Context, podMeta meta
 	})
 }
 
+// checkPodFailed returns an error if the pod has failed, and writes the
+// reason to the step's logs if it is not already covered by an event, such as
+// when the app container was OOM killed.
+func (r k8sStepRunner) checkPodFailed(pod *v1.Pod) error {
+	err := podFailedError(pod)
+	if err == nil {
+		return nil
+	}
+	for _, c := range pod.Status.ContainerStatuses {
+		if t := c.State.Terminated; t != nil && t.Reason == containerReasonOOMKilled && r.logWriter != nil {
+			r.logWriter.WriteAnnotatedLogLine(t.FinishedAt.Time, "container: "+t.Reason,
+				fmt.Sprintf("Container %q was killed as it ran out of memory.", c.Name))
+		}
+	}
+	return err
+}
+
 func (r k8sStepRunner) waitForPodModifiedFunc(ctx context.Context, podMeta metav1.ObjectMeta, f func(pod *v1.Pod) (bool, error)) error {
 	w, err := r.pods.Watch(ctx, metav1.SingleObject(podMeta))
 	if err != nil {
@@ -380,6 +474,8 @@ func (r k8sStepRunner) waitForPodModifiedFunc(ctx context.Context, podMeta metav
 	for ev := range w.ResultChan() {
 		switch obj := ev.Object.(type) {
 		case *v1.Pod:
+			r.podPhases.observe(obj)
+			r.podSched.observe(obj)
 			switch ev.Type {
 			case watch.Modified:
 				ok, err := f(obj)
@@ -402,7 +498,8 @@ func (r k8sStepRunner) waitForPodModifiedFunc(ctx context.Context, podMeta metav
 	return fmt.Errorf("got no more events when watching pod: %v", podMeta.Name)
 }
 
-func (r k8sStepRunner) readLogs(ctx context.Context, opts *v1.PodLogOptions) error {
+func (r k8sStepRunner) readLogs(ctx context.Context, container string, opts *v1.PodLogOptions) error {
+	opts.Container = container
 	req := r.pods.GetLogs(r.target.name, opts)
 	readCloser, err := req.Stream(ctx)
 	if err != nil {
@@ -410,29 +507,14 @@ func (r k8sStepRunner) readLogs(ctx context.Context, opts *v1.PodLogOptions) err
 	}
 	defer readCloser.Close()
 	scanner := bufio.NewScanner(readCloser)
-	writer, err := r.ResultStore.OpenLogWriter(uint64(r.stepID))
-	if err != nil {
-		r.log.Error().WithError(err).Message("Failed to open log writer. No logs will be written.")
-	} else {
-		defer func() {
-			if err := writer.Close(); err != nil {
-				r.log.Error().WithError(err).Message("Failed to close log writer.")
-			}
-		}()
-	}
 	for scanner.Scan() {
 		txt := scanner.Text()
 		idx := strings.LastIndexByte(txt, '\r')
 		if idx != -1 {
 			txt = txt[idx+1:]
 		}
-		if writer != nil {
-			line, err := writer.WriteLogLine(txt)
-			if err != nil {
-				r.log.Error().WithError(err).Message("Failed to write log line. No further logs will be written.")
-				return err
-			}
-			r.log.Info().Message(line.Message)
+		if err := r.logWriter.WriteContainerLogLine(container, resultstore.LogStreamUnknown, txt); err != nil {
+			return err
 		}
 	}
 	return scanner.Err()
@@ -457,9 +539,19 @@ func (r k8sStepRunner) stopPodNow(ctx context.Context) {
 	}
 }
 
-func (r k8sStepRunner) transferDataToPod(ctx context.Context) error {
+func (r k8sStepRunner) transferDataToPod(ctx context.Context) (finalErr error) {
+	ctx, span := tracer.Start(ctx, "transfer data to pod", trace.WithAttributes(
+		attribute.String("k8s.pod.name", r.target.name),
+	))
+	defer func() {
+		tracing.RecordError(span, finalErr)
+		span.End()
+	}()
+	if stat, err := os.Stat(string(r.repoTar)); err == nil {
+		span.SetAttributes(attribute.Int64("wharf.tarball.bytes", stat.Size()))
+	}
 	log.Debug().WithFunc(r.logFunc).Message("Transferring repo to init container.")
-	if err := r.copyDirToPod(ctx, steps.PodRepoVolumeMountPath); err != nil {
+	if err := r.transferRepoToPod(ctx, steps.PodRepoVolumeMountPath); err != nil {
 		return fmt.Errorf("transfer repo: %w", err)
 	}
 	log.Debug().WithFunc(r.logFunc).Message("Transferred repo to init container.")
@@ -560,7 +652,12 @@ func (r k8sStepRunner) copyToPodStdin(ctx context.Context, reader io.Reader, arg
 	pipeReader, pipeWriter := io.Pipe()
 	defer pipeReader.Close()
 	defer pipeWriter.Close()
-	exec, err := execInPodPipedStdin(r.RestConfig, r.target, args)
+	var stderr io.WriteCloser
+	if r.logWriter != nil {
+		stderr = r.logWriter.LineWriter(r.target.container, resultstore.LogStreamStderr)
+		defer stderr.Close()
+	}
+	exec, err := execInPodPipedStdin(r.RestConfig, r.target, args, stderr != nil)
 	if err != nil {
 		return err
 	}
@@ -570,9 +667,13 @@ func (r k8sStepRunner) copyToPodStdin(ctx context.Context, reader io.Reader, arg
 		_, err := io.Copy(pipeWriter, reader)
 		writeErrCh <- err
 	}()
-	err = exec.Stream(remotecommand.StreamOptions{
+	streamOpts := remotecommand.StreamOptions{
 		Stdin: pipeReader,
-	})
+	}
+	if stderr != nil {
+		streamOpts.Stderr = stderr
+	}
+	err = exec.Stream(streamOpts)
 	if err != nil {
 		return err
 	}
@@ -584,11 +685,12 @@ func (r k8sStepRunner) copyToPodStdin(ctx context.Context, reader io.Reader, arg
 	}
 }
 
-func execInPodPipedStdin(c *rest.Config, t *target, args []string) (remotecommand.Executor, error) {
+func execInPodPipedStdin(c *rest.Config, t *target, args []string, pipeStderr bool) (remotecommand.Executor, error) {
 	return execInPod(c, t.namespace, t.name, &v1.PodExecOptions{
 		Container: t.container,
 		Command:   args,
 		Stdin:     true,
+		Stderr:    pipeStderr,
 	})
 }
 
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/api/core/v1"
+)
+
+// podPhaseTracer records a span for each phase of a step's pod, such as how
+// long the pod was Pending before it started Running, and adds an event to
+// the step's span on each phase transition.
+type podPhaseTracer struct {
+	ctx     context.Context
+	podName string
+
+	mutex sync.Mutex
+	phase v1.PodPhase
+	span  trace.Span
+}
+
+func newPodPhaseTracer(ctx context.Context, podName string) *podPhaseTracer {
+	return &podPhaseTracer{ctx: ctx, podName: podName}
+}
+
+// observe ends the span of the previous phase and starts a span for the new
+// phase, if the pod's phase has changed. No span is started for the
+// Succeeded and Failed phases, as the pod does not leave them.
+func (t *podPhaseTracer) observe(pod *v1.Pod) {
+	if t == nil || pod.Status.Phase == "" {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if pod.Status.Phase == t.phase {
+		return
+	}
+	now := time.Now()
+	if t.span != nil {
+		t.span.End(trace.WithTimestamp(now))
+		t.span = nil
+	}
+	trace.SpanFromContext(t.ctx).AddEvent("pod phase changed",
+		trace.WithTimestamp(now),
+		trace.WithAttributes(
+			attribute.String("k8s.pod.name", t.podName),
+			attribute.String("k8s.pod.phase", string(pod.Status.Phase)),
+			attribute.String("k8s.pod.previousPhase", string(t.phase)),
+		))
+	t.phase = pod.Status.Phase
+	if t.phase == v1.PodSucceeded || t.phase == v1.PodFailed {
+		return
+	}
+	_, t.span = tracer.Start(t.ctx, "pod "+string(t.phase),
+		trace.WithTimestamp(now),
+		trace.WithAttributes(attribute.String("k8s.pod.name", t.podName)))
+}
+
+// end ends the span of the current phase, if any.
+func (t *podPhaseTracer) end() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.span != nil {
+		t.span.End()
+		t.span = nil
+	}
+}
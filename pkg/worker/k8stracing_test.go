@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	v1 "k8s.io/api/core/v1"
+)
+
+var (
+	testSpanRecorder     = tracetest.NewSpanRecorder()
+	testSpanRecorderOnce sync.Once
+)
+
+// startTestTrace starts a root span and returns a func that ends it and
+// returns all ended spans of its trace. The global tracer provider can only be
+// set once for the package-level tracer, so the recorder is shared between
+// tests and the spans are filtered by trace ID.
+func startTestTrace(t *testing.T) (context.Context, func() []sdktrace.ReadOnlySpan) {
+	testSpanRecorderOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(testSpanRecorder)))
+	})
+	ctx, root := tracer.Start(context.Background(), t.Name())
+	traceID := root.SpanContext().TraceID()
+	return ctx, func() []sdktrace.ReadOnlySpan {
+		root.End()
+		var spans []sdktrace.ReadOnlySpan
+		for _, span := range testSpanRecorder.Ended() {
+			if span.SpanContext().TraceID() == traceID && span.Name() != t.Name() {
+				spans = append(spans, span)
+			}
+		}
+		return spans
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
+
+func TestPodPhaseTracer(t *testing.T) {
+	ctx, endTrace := startTestTrace(t)
+	ctx, stepSpan := tracer.Start(ctx, "step")
+	phases := newPodPhaseTracer(ctx, "my-pod")
+
+	pod := &v1.Pod{}
+	for _, phase := range []v1.PodPhase{v1.PodPending, v1.PodPending, v1.PodRunning, v1.PodSucceeded} {
+		pod.Status.Phase = phase
+		phases.observe(pod)
+	}
+	phases.end()
+	stepSpan.End()
+
+	spans := endTrace()
+	assert.Equal(t, []string{"pod Pending", "pod Running", "step"}, spanNames(spans))
+	for _, span := range spans[:2] {
+		assert.Equal(t, stepSpan.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.False(t, spans[0].EndTime().After(spans[1].StartTime()), "Pending must end before Running starts")
+
+	events := spans[2].Events()
+	require.Len(t, events, 3)
+	var gotPhases []string
+	for _, ev := range events {
+		for _, attr := range ev.Attributes {
+			if attr.Key == "k8s.pod.phase" {
+				gotPhases = append(gotPhases, attr.Value.AsString())
+			}
+		}
+	}
+	assert.Equal(t, []string{"Pending", "Running", "Succeeded"}, gotPhases)
+}
+
+func TestPodPhaseTracer_nil(t *testing.T) {
+	var phases *podPhaseTracer
+	phases.observe(&v1.Pod{Status: v1.PodStatus{Phase: v1.PodRunning}})
+	phases.end()
+}
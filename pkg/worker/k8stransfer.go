@@ -0,0 +1,327 @@
+package worker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/typ.v4/sync2"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// minDedupFileSize is the smallest file that is copied from the cache or from
+// an identical file in the pod, instead of being transferred. Smaller files
+// are cheaper to send than to copy, as each copy spawns processes in the
+// init container.
+const minDedupFileSize = 32 * 1024
+
+// transferProbeScript prints the compression commands that are available in
+// the init container, and the content hashes of the files in the cache
+// directory given as the first argument, if any.
+const transferProbeScript = `for c in zstd gzip; do
+  command -v "$c" >/dev/null 2>&1 && echo "compression $c"
+done
+if [ -n "$1" ] && [ -d "$1" ]; then
+  ls -1 "$1" | sed 's/^/cached /'
+fi
+true`
+
+var regexSHA256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// repoFile is a regular file inside a repository tarball.
+type repoFile struct {
+	name string
+	hash string
+	size int64
+	mode int64
+}
+
+// repoManifestCache computes the manifest of file hashes of each tarball only
+// once, as many steps often share the same tarball.
+type repoManifestCache struct {
+	onceMap sync2.Map[tarstore.Tarball, *sync2.Once2[[]repoFile, error]]
+}
+
+func (c *repoManifestCache) get(tarball tarstore.Tarball) ([]repoFile, error) {
+	if c == nil {
+		return readRepoManifest(tarball)
+	}
+	once, _ := c.onceMap.LoadOrStore(tarball, new(sync2.Once2[[]repoFile, error]))
+	return once.Do(func() ([]repoFile, error) {
+		return readRepoManifest(tarball)
+	})
+}
+
+func readRepoManifest(tarball tarstore.Tarball) ([]repoFile, error) {
+	file, err := tarball.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var files []repoFile
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, tr); err != nil {
+			return nil, err
+		}
+		files = append(files, repoFile{
+			name: header.Name,
+			hash: hex.EncodeToString(hash.Sum(nil)),
+			size: header.Size,
+			mode: header.Mode,
+		})
+	}
+}
+
+type transferProbe struct {
+	compressions map[config.RepoTransferCompression]bool
+	cached       map[string]bool
+}
+
+func parseTransferProbe(r io.Reader) (transferProbe, error) {
+	probe := transferProbe{
+		compressions: map[config.RepoTransferCompression]bool{
+			config.RepoTransferCompressionNone: true,
+		},
+		cached: map[string]bool{},
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "compression":
+			probe.compressions[config.RepoTransferCompression(value)] = true
+		case "cached":
+			if regexSHA256Hex.MatchString(value) {
+				probe.cached[value] = true
+			}
+		}
+	}
+	return probe, scanner.Err()
+}
+
+// selectCompression returns the first compression in order of preference
+// that is available in the init container.
+func (p transferProbe) selectCompression(preferred []config.RepoTransferCompression) config.RepoTransferCompression {
+	for _, c := range preferred {
+		if p.compressions[c] {
+			return c
+		}
+	}
+	return config.RepoTransferCompressionNone
+}
+
+// repoTransferPlan is the result of comparing the files in a tarball with
+// the files that already exist in the init container.
+type repoTransferPlan struct {
+	// send is the set of file names to include in the transferred tarball.
+	// Directories are always included.
+	send map[string]bool
+	// script is a shell script run in the init container after the tarball
+	// has been extracted, that copies the files that were not sent.
+	script  string
+	sent    int
+	cached  int
+	deduped int
+}
+
+func planRepoTransfer(files []repoFile, cached map[string]bool, destDir, cacheDir string) repoTransferPlan {
+	plan := repoTransferPlan{send: make(map[string]bool, len(files))}
+	var script strings.Builder
+	if cacheDir != "" {
+		fmt.Fprintf(&script, "c=%s\n", shellQuote(cacheDir))
+	}
+	sentByHash := map[string]string{}
+	for _, f := range files {
+		dest := shellQuote(path.Join(destDir, f.name))
+		if f.size < minDedupFileSize {
+			plan.send[f.name] = true
+			plan.sent++
+			continue
+		}
+		if cacheDir != "" && cached[f.hash] {
+			fmt.Fprintf(&script, "cp \"$c/%s\" %s\nchmod %o %s\n", f.hash, dest, f.mode&0777, dest)
+			plan.cached++
+			continue
+		}
+		if first, ok := sentByHash[f.hash]; ok {
+			fmt.Fprintf(&script, "cp %s %s\nchmod %o %s\n", shellQuote(path.Join(destDir, first)), dest, f.mode&0777, dest)
+			plan.deduped++
+			continue
+		}
+		sentByHash[f.hash] = f.name
+		plan.send[f.name] = true
+		plan.sent++
+		if cacheDir != "" {
+			// Copied via a temporary file, so other pods on the same node
+			// never see partially written files in the cache.
+			fmt.Fprintf(&script, "cp %s \"$c/.$$.%[2]s\" && mv -f \"$c/.$$.%[2]s\" \"$c/%[2]s\" || true\n", dest, f.hash)
+		}
+	}
+	if script.Len() > 0 {
+		plan.script = "set -e\n" + script.String()
+	}
+	return plan
+}
+
+// writeFilteredTarball copies the tarball, excluding the regular files that
+// are not in the send set.
+func writeFilteredTarball(w io.Writer, tarball tarstore.Tarball, send map[string]bool) error {
+	file, err := tarball.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	tr := tar.NewReader(file)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && !send[header.Name] {
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func newCompressWriter(w io.Writer, compression config.RepoTransferCompression) (io.WriteCloser, error) {
+	switch compression {
+	case config.RepoTransferCompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	case config.RepoTransferCompressionGzip:
+		// Speed matters more than size here, as the transfer is the
+		// bottleneck only as long as compressing is faster than sending.
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+func extractTarballArgs(destDir string, compression config.RepoTransferCompression) []string {
+	switch compression {
+	case config.RepoTransferCompressionZstd:
+		return []string{"sh", "-c", `zstd -dc | tar -xf - -C "$1"`, "sh", destDir}
+	case config.RepoTransferCompressionGzip:
+		return []string{"tar", "-xzf", "-", "-C", destDir}
+	default:
+		return []string{"tar", "-xf", "-", "-C", destDir}
+	}
+}
+
+// shellQuote quotes a string as a single argument for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// transferRepoToPod transfers only the files of the repository that do not
+// already exist in the init container, compressed if possible. Falls back to
+// transferring the whole tarball uncompressed if the init container could not
+// be probed, such as when it has no shell.
+func (r k8sStepRunner) transferRepoToPod(ctx context.Context, destDir string) error {
+	conf := r.Config.Worker.RepoTransfer
+	probe, err := r.probeRepoTransfer(conf.CacheDir)
+	if err != nil {
+		log.Debug().
+			WithError(err).
+			WithFunc(r.logFunc).
+			Message("Failed to probe init container. Transferring whole repo uncompressed.")
+		return r.copyDirToPod(ctx, destDir)
+	}
+	files, err := r.repoManifests.get(r.repoTar)
+	if err != nil {
+		return fmt.Errorf("read repo manifest: %w", err)
+	}
+	plan := planRepoTransfer(files, probe.cached, destDir, conf.CacheDir)
+	compression := probe.selectCompression(conf.Compression)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("wharf.transfer.compression", string(compression)),
+		attribute.Int("wharf.transfer.files.sent", plan.sent),
+		attribute.Int("wharf.transfer.files.cached", plan.cached),
+		attribute.Int("wharf.transfer.files.deduped", plan.deduped),
+	)
+	log.Debug().
+		WithFunc(r.logFunc).
+		WithString("compression", string(compression)).
+		WithInt("sent", plan.sent).
+		WithInt("cached", plan.cached).
+		WithInt("deduped", plan.deduped).
+		Message("Transferring missing repo files to init container.")
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(writeCompressedTarball(pipeWriter, r.repoTar, plan.send, compression))
+	}()
+	err = r.copyToPodStdin(ctx, pipeReader, extractTarballArgs(destDir, compression))
+	pipeReader.Close()
+	if err != nil {
+		return err
+	}
+	if plan.script == "" {
+		return nil
+	}
+	if err := r.copyToPodStdin(ctx, strings.NewReader(plan.script), []string{"sh"}); err != nil {
+		return fmt.Errorf("copy cached files: %w", err)
+	}
+	return nil
+}
+
+func writeCompressedTarball(w io.Writer, tarball tarstore.Tarball, send map[string]bool, compression config.RepoTransferCompression) error {
+	cw, err := newCompressWriter(w, compression)
+	if err != nil {
+		return err
+	}
+	if err := writeFilteredTarball(cw, tarball, send); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
+
+func (r k8sStepRunner) probeRepoTransfer(cacheDir string) (transferProbe, error) {
+	exec, err := execInPodPipeStdout(r.RestConfig, r.target,
+		[]string{"sh", "-c", transferProbeScript, "sh", cacheDir})
+	if err != nil {
+		return transferProbe{}, err
+	}
+	var stdout bytes.Buffer
+	if err := exec.Stream(remotecommand.StreamOptions{Stdout: &stdout}); err != nil {
+		return transferProbe{}, err
+	}
+	return parseTransferProbe(&stdout)
+}
@@ -0,0 +1,176 @@
+package worker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/internal/tarutil"
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransferProbe(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	probe, err := parseTransferProbe(strings.NewReader(
+		"compression gzip\ncached " + hash + "\ncached .123.tmp\nunknown line\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{hash: true}, probe.cached)
+
+	assert.Equal(t, config.RepoTransferCompressionGzip, probe.selectCompression(
+		[]config.RepoTransferCompression{config.RepoTransferCompressionZstd, config.RepoTransferCompressionGzip}))
+	assert.Equal(t, config.RepoTransferCompressionNone, probe.selectCompression(
+		[]config.RepoTransferCompression{config.RepoTransferCompressionZstd}))
+	assert.Equal(t, config.RepoTransferCompressionNone, probe.selectCompression(nil))
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'/mnt/repo/it'\''s a file'`, shellQuote("/mnt/repo/it's a file"))
+}
+
+func TestPlanRepoTransfer(t *testing.T) {
+	big := int64(minDedupFileSize)
+	files := []repoFile{
+		{name: "small.txt", hash: "h1", size: 10, mode: 0644},
+		{name: "small-copy.txt", hash: "h1", size: 10, mode: 0644},
+		{name: "big.bin", hash: "h2", size: big, mode: 0644},
+		{name: "big-copy.bin", hash: "h2", size: big, mode: 0755},
+		{name: "cached.bin", hash: "h3", size: big, mode: 0644},
+	}
+	cached := map[string]bool{"h3": true}
+
+	plan := planRepoTransfer(files, cached, "/mnt/repo", "")
+	assert.Equal(t, map[string]bool{"small.txt": true, "small-copy.txt": true, "big.bin": true, "cached.bin": true}, plan.send,
+		"cache is ignored when no cache dir is set")
+	assert.Equal(t, 4, plan.sent)
+	assert.Equal(t, 1, plan.deduped)
+	assert.Equal(t, 0, plan.cached)
+	assert.Contains(t, plan.script, "cp '/mnt/repo/big.bin' '/mnt/repo/big-copy.bin'\nchmod 755 '/mnt/repo/big-copy.bin'\n")
+
+	plan = planRepoTransfer(files, cached, "/mnt/repo", "/mnt/cache")
+	assert.Equal(t, map[string]bool{"small.txt": true, "small-copy.txt": true, "big.bin": true}, plan.send)
+	assert.Equal(t, 1, plan.cached)
+	assert.Contains(t, plan.script, "cp \"$c/h3\" '/mnt/repo/cached.bin'\n")
+}
+
+func TestTransferRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("requires sh:", err)
+	}
+	bigA := bytes.Repeat([]byte("a"), minDedupFileSize)
+	bigB := bytes.Repeat([]byte("b"), minDedupFileSize)
+	srcDir := t.TempDir()
+	writeTestFiles(t, srcDir, map[string][]byte{
+		"small.txt":         []byte("hello"),
+		"dir/big-a.bin":     bigA,
+		"dir/big-a-dup.bin": bigA,
+		"it's-big-b.bin":    bigB,
+		"empty/dir/.keep":   nil,
+	})
+	tarball := tarstore.Tarball(filepath.Join(t.TempDir(), "repo.tar"))
+	tarFile, err := os.Create(string(tarball))
+	require.NoError(t, err)
+	require.NoError(t, tarutil.Dir(tarFile, srcDir))
+	require.NoError(t, tarFile.Close())
+
+	files, err := readRepoManifest(tarball)
+	require.NoError(t, err)
+	require.Len(t, files, 5)
+
+	cacheDir := t.TempDir()
+	cached := map[string]bool{}
+	for _, f := range files {
+		if f.name == "it's-big-b.bin" {
+			require.NoError(t, os.WriteFile(filepath.Join(cacheDir, f.hash), bigB, 0644))
+			cached[f.hash] = true
+		}
+	}
+
+	for _, compression := range []config.RepoTransferCompression{
+		config.RepoTransferCompressionZstd,
+		config.RepoTransferCompressionGzip,
+		config.RepoTransferCompressionNone,
+	} {
+		t.Run(string(compression), func(t *testing.T) {
+			destDir := t.TempDir()
+			plan := planRepoTransfer(files, cached, destDir, cacheDir)
+			assert.Equal(t, 1, plan.cached)
+			assert.Equal(t, 1, plan.deduped)
+
+			var buf bytes.Buffer
+			require.NoError(t, writeCompressedTarball(&buf, tarball, plan.send, compression))
+			extractTestTarball(t, &buf, compression, destDir)
+
+			cmd := exec.Command(sh)
+			cmd.Stdin = strings.NewReader(plan.script)
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+
+			assertTestFile(t, filepath.Join(destDir, "small.txt"), []byte("hello"))
+			assertTestFile(t, filepath.Join(destDir, "dir/big-a.bin"), bigA)
+			assertTestFile(t, filepath.Join(destDir, "dir/big-a-dup.bin"), bigA)
+			assertTestFile(t, filepath.Join(destDir, "it's-big-b.bin"), bigB)
+			assert.DirExists(t, filepath.Join(destDir, "empty/dir"))
+		})
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "sent file was added to the cache")
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string][]byte) {
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, content, 0644))
+	}
+}
+
+func assertTestFile(t *testing.T, path string, want []byte) {
+	got, err := os.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.Equal(want, got), "content of %s", path)
+	}
+}
+
+func extractTestTarball(t *testing.T, r io.Reader, compression config.RepoTransferCompression, destDir string) {
+	switch compression {
+	case config.RepoTransferCompressionZstd:
+		zr, err := zstd.NewReader(r)
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	case config.RepoTransferCompressionGzip:
+		gr, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = gr
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err)
+		p := filepath.Join(destDir, header.Name)
+		if header.Typeflag == tar.TypeDir {
+			require.NoError(t, os.MkdirAll(p, 0755))
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(p, content, os.FileMode(header.Mode)))
+	}
+}
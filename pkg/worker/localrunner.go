@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/internal/tarutil"
+	"github.com/iver-wharf/wharf-cmd/pkg/config"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/steps"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/varsub"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// ErrLocalStepTypeNotSupported is returned by the local step runner factory
+// for steps that cannot be run directly on the host.
+var ErrLocalStepTypeNotSupported = errors.New("step type not supported by local runner")
+
+// LocalRunnerOptions is a struct of options for a local step runner.
+type LocalRunnerOptions struct {
+	BuildOptions
+	Config        *config.Config
+	ResultStore   resultstore.Store
+	TarStore      tarstore.Store
+	VarSource     varsub.Source
+	SkipGitIgnore bool
+	CurrentDir    string
+}
+
+// NewLocal is a helper function that creates a new builder using the
+// NewLocalStepRunnerFactory.
+func NewLocal(ctx context.Context, def wharfyml.Definition, opts LocalRunnerOptions) (Builder, error) {
+	stageFactory, err := NewLocalStageRunnerFactory(opts)
+	if err != nil {
+		return nil, err
+	}
+	return New(ctx, stageFactory, def, opts.BuildOptions)
+}
+
+// NewLocalStageRunnerFactory is a helper function that creates a new stage
+// runner factory using the NewLocalStepRunnerFactory.
+func NewLocalStageRunnerFactory(opts LocalRunnerOptions) (StageRunnerFactory, error) {
+	stepFactory, err := NewLocalStepRunnerFactory(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewStageRunnerFactory(stepFactory, opts.Config.Worker.MaxParallelSteps)
+}
+
+// NewLocalStepRunnerFactory returns a new step runner factory that creates
+// step runners that execute the commands of container steps directly on the
+// host, inside a temporary copy of the repository. The container image of the
+// steps are ignored.
+//
+// Only the "container" step type is supported.
+func NewLocalStepRunnerFactory(opts LocalRunnerOptions) (StepRunnerFactory, error) {
+	return localStepRunnerFactory{LocalRunnerOptions: opts}, nil
+}
+
+type localStepRunnerFactory struct {
+	LocalRunnerOptions
+}
+
+func (f localStepRunnerFactory) NewStepRunner(
+	ctx context.Context, step wharfyml.Step, stepID uint64) (StepRunner, error) {
+	ctx = contextWithStepName(ctx, step.Name)
+	container, ok := step.Type.(steps.Container)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrLocalStepTypeNotSupported, step.Type.StepTypeName())
+	}
+
+	tarball, err := f.stepRepoPreparer().prepareStepRepo(ctx, step, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setStepMeta(ctx, f.ResultStore, step, stepID); err != nil {
+		return nil, err
+	}
+
+	r := localStepRunner{
+		LocalRunnerOptions: f.LocalRunnerOptions,
+		logScope:           contextStageStepName(ctx),
+		step:               step,
+		container:          container,
+		stepID:             stepID,
+		repoTar:            tarball,
+	}
+	return r, nil
+}
+
+func (f localStepRunnerFactory) stepRepoPreparer() stepRepoPreparer {
+	return stepRepoPreparer{
+		tarStore:      f.TarStore,
+		varSource:     f.VarSource,
+		skipGitIgnore: f.SkipGitIgnore,
+		currentDir:    f.CurrentDir,
+	}
+}
+
+type localStepRunner struct {
+	LocalRunnerOptions
+	logScope  string
+	step      wharfyml.Step
+	container steps.Container
+	stepID    uint64
+	repoTar   tarstore.Tarball
+}
+
+func (r localStepRunner) Step() wharfyml.Step {
+	return r.step
+}
+
+func (r localStepRunner) RunStep(ctx context.Context) StepResult {
+	ctx = contextWithStepName(ctx, r.step.Name)
+	start := time.Now()
+	status := workermodel.StatusSuccess
+	termination, err := r.runStep(ctx)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		status = workermodel.StatusCancelled
+	} else if err != nil {
+		status = failedStepStatus(r.step)
+	}
+	if err := addFinalStatusUpdate(r.ResultStore, r.stepID, status, termination); err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("step", r.step.Name).
+			WithStringer("status", status).
+			Message("Failed to add status update.")
+	}
+	return StepResult{
+		Name:        r.step.Name,
+		Status:      status,
+		Type:        r.step.Type.StepTypeName(),
+		Error:       err,
+		Duration:    time.Since(start),
+		Termination: termination,
+	}
+}
+
+func (r localStepRunner) ReportStepStatus(status workermodel.Status) {
+	r.addStatusUpdate(status)
+}
+
+func (r localStepRunner) runStep(ctx context.Context) (*workermodel.Termination, error) {
+	r.addStatusUpdate(workermodel.StatusInitializing)
+	repoDir, err := os.MkdirTemp("", fmt.Sprintf("wharf-cmd-step-%d-", r.stepID))
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(repoDir); err != nil {
+			log.Warn().WithError(err).
+				WithString("step", r.step.Name).
+				WithString("dir", repoDir).
+				Message("Failed to remove temporary repository copy.")
+		}
+	}()
+	log.Debug().
+		WithString("step", r.step.Name).
+		WithString("dir", repoDir).
+		Message("Extracting repository to temporary directory.")
+	if err := r.extractRepo(repoDir); err != nil {
+		return nil, fmt.Errorf("extract repo: %w", err)
+	}
+
+	log.Debug().
+		WithString("step", r.step.Name).
+		WithString("image", r.container.Image).
+		Message("Running step commands on host. Ignoring container image.")
+	r.addStatusUpdate(workermodel.StatusRunning)
+	return r.runCommands(ctx, repoDir)
+}
+
+func (r localStepRunner) extractRepo(destDir string) error {
+	tarReader, err := r.repoTar.Open()
+	if err != nil {
+		return err
+	}
+	defer tarReader.Close()
+	return tarutil.Extract(tarReader, destDir)
+}
+
+func (r localStepRunner) runCommands(ctx context.Context, repoDir string) (*workermodel.Termination, error) {
+	var cmd *exec.Cmd
+	script := strings.Join(r.container.Cmds, "\n")
+	if r.container.OS == "windows" && r.container.Shell == "/bin/sh" {
+		cmd = exec.CommandContext(ctx, "powershell.exe", "-C", script)
+	} else {
+		cmd = exec.CommandContext(ctx, r.container.Shell, "-c", script)
+	}
+	cmd.Dir = repoDir
+	cmd.Env = os.Environ()
+
+	logWriter := openStepLogWriter(r.ResultStore, r.stepID, r.logScope, "")
+	defer logWriter.Close()
+	stdout := logWriter.LineWriter("", resultstore.LogStreamStdout)
+	stderr := logWriter.LineWriter("", resultstore.LogStreamStderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start command: %w", err)
+	}
+	startedAt := time.Now()
+	waitErr := cmd.Wait()
+	stdout.Close()
+	stderr.Close()
+	var termination *workermodel.Termination
+	if cmd.ProcessState != nil {
+		termination = newExitTermination(int32(cmd.ProcessState.ExitCode()), startedAt, time.Now())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		return termination, fmt.Errorf("non-zero exit code: %d", exitErr.ExitCode())
+	}
+	return termination, waitErr
+}
+
+func (r localStepRunner) addStatusUpdate(status workermodel.Status) {
+	if err := r.ResultStore.AddStatusUpdate(r.stepID, time.Now(), status); err != nil {
+		log.Warn().
+			WithError(err).
+			WithString("step", r.step.Name).
+			WithStringer("status", status).
+			Message("Failed to add status update.")
+	}
+}
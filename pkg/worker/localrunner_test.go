@@ -0,0 +1,101 @@
+//go:build !windows
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/steps"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalStepRunnerFactory(t *testing.T) (StepRunnerFactory, resultstore.Store) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "hello.txt"), []byte("hello from repo\n"), 0644))
+	tarStore, err := tarstore.New(repoDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { tarStore.Close() })
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	factory, err := NewLocalStepRunnerFactory(LocalRunnerOptions{
+		ResultStore:   store,
+		TarStore:      tarStore,
+		SkipGitIgnore: true,
+		CurrentDir:    repoDir,
+	})
+	require.NoError(t, err)
+	return factory, store
+}
+
+func newTestContainerStep(name string, cmds ...string) wharfyml.Step {
+	return wharfyml.Step{
+		Name: name,
+		Type: steps.Container{Image: "alpine", Shell: "/bin/sh", Cmds: cmds},
+	}
+}
+
+func TestLocalStepRunner_success(t *testing.T) {
+	factory, store := newTestLocalStepRunnerFactory(t)
+	r, err := factory.NewStepRunner(context.Background(), newTestContainerStep("cat", "cat hello.txt", "echo done"), 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	require.NoError(t, res.Error)
+	assert.Equal(t, workermodel.StatusSuccess, res.Status)
+
+	reader, err := store.OpenLogReader(1)
+	require.NoError(t, err)
+	defer reader.Close()
+	var messages []string
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		messages = append(messages, line.Message)
+	}
+	assert.Equal(t, []string{"hello from repo", "done"}, messages)
+
+	updates, err := store.ListStatusUpdates(1)
+	require.NoError(t, err)
+	var statuses []workermodel.Status
+	for _, u := range updates {
+		statuses = append(statuses, u.Status)
+	}
+	want := []workermodel.Status{
+		workermodel.StatusInitializing,
+		workermodel.StatusRunning,
+		workermodel.StatusSuccess,
+	}
+	assert.Equal(t, want, statuses)
+}
+
+func TestLocalStepRunner_failure(t *testing.T) {
+	factory, _ := newTestLocalStepRunnerFactory(t)
+	r, err := factory.NewStepRunner(context.Background(), newTestContainerStep("fail", "exit 3"), 1)
+	require.NoError(t, err)
+
+	res := r.RunStep(context.Background())
+	assert.Equal(t, workermodel.StatusFailed, res.Status)
+	assert.EqualError(t, res.Error, "non-zero exit code: 3")
+	require.NotNil(t, res.Termination)
+	assert.Equal(t, int32(3), res.Termination.ExitCode)
+	assert.Equal(t, "Error", res.Termination.Reason)
+}
+
+func TestLocalStepRunnerFactory_unsupportedStepType(t *testing.T) {
+	factory, _ := newTestLocalStepRunnerFactory(t)
+	step := wharfyml.Step{Name: "image", Type: steps.Docker{}}
+	_, err := factory.NewStepRunner(context.Background(), step, 1)
+	assert.ErrorIs(t, err, ErrLocalStepTypeNotSupported)
+}
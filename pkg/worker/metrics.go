@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	v1 "k8s.io/api/core/v1"
+)
+
+const metricsSubsystem = "worker"
+
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+var (
+	buildDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "build_duration_seconds",
+		Help:      "Duration of builds, by final status.",
+		Buckets:   durationBuckets,
+	}, []string{"status"})
+
+	stepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "step_duration_seconds",
+		Help:      "Duration of steps, by step type and final status.",
+		Buckets:   durationBuckets,
+	}, []string{"step_type", "status"})
+
+	podSchedulingLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: metricsSubsystem,
+		Name:      "pod_scheduling_latency_seconds",
+		Help:      "Time from when a step's pod was created until it was scheduled onto a node, by step type.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"step_type"})
+)
+
+// podSchedulingTimer observes the scheduling latency of a step's pod once,
+// when the pod's PodScheduled condition is first seen as true.
+type podSchedulingTimer struct {
+	stepType string
+	once     sync.Once
+}
+
+func newPodSchedulingTimer(stepType string) *podSchedulingTimer {
+	return &podSchedulingTimer{stepType: stepType}
+}
+
+func (t *podSchedulingTimer) observe(pod *v1.Pod) {
+	if t == nil {
+		return
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != v1.PodScheduled || cond.Status != v1.ConditionTrue {
+			continue
+		}
+		t.once.Do(func() {
+			latency := cond.LastTransitionTime.Sub(pod.CreationTimestamp.Time)
+			podSchedulingLatency.WithLabelValues(t.stepType).Observe(latency.Seconds())
+		})
+		return
+	}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodSchedulingTimer(t *testing.T) {
+	const stepType = "test-scheduling-timer"
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+	timer := newPodSchedulingTimer(stepType)
+
+	timer.observe(pod)
+	assert.Equal(t, 0, testutil.CollectAndCount(podSchedulingLatency, "wharf_cmd_worker_pod_scheduling_latency_seconds"),
+		"no observation before the pod is scheduled")
+
+	pod.Status.Conditions = []v1.PodCondition{{
+		Type:               v1.PodScheduled,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(created.Add(3 * time.Second)),
+	}}
+	timer.observe(pod)
+	timer.observe(pod)
+
+	want := `
+# HELP wharf_cmd_worker_pod_scheduling_latency_seconds Time from when a step's pod was created until it was scheduled onto a node, by step type.
+# TYPE wharf_cmd_worker_pod_scheduling_latency_seconds histogram
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="0.5"} 0
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="1"} 0
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="2"} 0
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="5"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="10"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="30"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="60"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="120"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="300"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="600"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_bucket{step_type="test-scheduling-timer",le="+Inf"} 1
+wharf_cmd_worker_pod_scheduling_latency_seconds_sum{step_type="test-scheduling-timer"} 3
+wharf_cmd_worker_pod_scheduling_latency_seconds_count{step_type="test-scheduling-timer"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(podSchedulingLatency, strings.NewReader(want)))
+}
@@ -1,7 +1,17 @@
 package worker
 
+import "io"
+
 type nopWriter struct{}
 
 func (nopWriter) Write(bytes []byte) (int, error) {
 	return len(bytes), nil
 }
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"fmt"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// ReadRerunSucceededSteps reads the result store of a previous build, and
+// returns the "stage/step" names of its steps that do not need to be run
+// again, as used in BuildOptions.RerunSucceededSteps.
+//
+// Steps that failed but were allowed to fail, and got the Warning status, are
+// counted as succeeded, as they did not fail the previous build. Re-running
+// them would most likely only produce the same warnings again.
+func ReadRerunSucceededSteps(store resultstore.Store) (map[string]bool, error) {
+	stepMetas, err := store.ListStepMetas()
+	if err != nil {
+		return nil, err
+	}
+	if len(stepMetas) == 0 {
+		return nil, fmt.Errorf("no step metadata found in %s", store.Path())
+	}
+	succeeded := make(map[string]bool)
+	for _, meta := range stepMetas {
+		updates, err := store.ListStatusUpdates(meta.StepID)
+		if err != nil {
+			return nil, fmt.Errorf("step %s/%s: %w", meta.StageName, meta.StepName, err)
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		switch updates[len(updates)-1].Status {
+		case workermodel.StatusSuccess, workermodel.StatusWarning:
+			succeeded[meta.StageName+"/"+meta.StepName] = true
+		}
+	}
+	return succeeded, nil
+}
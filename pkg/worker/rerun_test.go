@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRerunSucceededSteps(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	now := time.Now()
+	steps := []struct {
+		stage, step string
+		status      workermodel.Status
+	}{
+		{"build", "app", workermodel.StatusSuccess},
+		{"build", "lint", workermodel.StatusWarning},
+		{"build", "db", workermodel.StatusFailed},
+		{"deploy", "helm", workermodel.StatusCancelled},
+	}
+	for i, s := range steps {
+		stepID := uint64(i + 1)
+		require.NoError(t, store.SetStepMeta(stepID, resultstore.StepMeta{StageName: s.stage, StepName: s.step}))
+		require.NoError(t, store.AddStatusUpdate(stepID, now, workermodel.StatusRunning))
+		require.NoError(t, store.AddStatusUpdate(stepID, now.Add(time.Second), s.status))
+	}
+
+	got, err := ReadRerunSucceededSteps(store)
+	require.NoError(t, err)
+	want := map[string]bool{
+		"build/app":  true,
+		"build/lint": true,
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestReadRerunSucceededSteps_ErrIfNoSteps(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	_, err := ReadRerunSucceededSteps(store)
+	assert.Error(t, err)
+}
@@ -7,43 +7,72 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/iver-wharf/wharf-cmd/pkg/tracing"
 	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // NewStageRunnerFactory returns a new StageRunner that uses the provided
 // StepRunner to run the steps in parallel.
-func NewStageRunnerFactory(stepRunFactory StepRunnerFactory) (StageRunnerFactory, error) {
-	return stageRunnerFactory{stepRunFactory}, nil
+//
+// The maxParallelSteps argument limits how many steps may run at the same
+// time, on top of the stage's own max-parallel setting. Zero means no limit.
+func NewStageRunnerFactory(stepRunFactory StepRunnerFactory, maxParallelSteps int) (StageRunnerFactory, error) {
+	if maxParallelSteps < 0 {
+		return nil, fmt.Errorf("max parallel steps must not be negative: %d", maxParallelSteps)
+	}
+	return stageRunnerFactory{stepRunFactory, maxParallelSteps}, nil
 }
 
 type stageRunnerFactory struct {
-	stepRunFactory StepRunnerFactory
+	stepRunFactory   StepRunnerFactory
+	maxParallelSteps int
 }
 
 // NewStageRunner returns a new StageRunner that uses the provided StepRunner to
 // run the steps in parallel.
 func (f stageRunnerFactory) NewStageRunner(ctx context.Context, stage wharfyml.Stage, stepIDOffset uint64) (StageRunner, error) {
-	return newStageRunner(ctx, f.stepRunFactory, stage, stepIDOffset)
+	r, err := newStageRunner(ctx, f.stepRunFactory, stage, stepIDOffset)
+	if err != nil {
+		return nil, err
+	}
+	r.maxParallel = minParallelLimit(stage.MaxParallel, f.maxParallelSteps)
+	return r, nil
 }
 
-func newStageRunner(ctx context.Context, stepRunFactory StepRunnerFactory, stage wharfyml.Stage, stepIDOffset uint64) (StageRunner, error) {
+func newStageRunner(ctx context.Context, stepRunFactory StepRunnerFactory, stage wharfyml.Stage, stepIDOffset uint64) (stageRunner, error) {
 	ctx = contextWithStageName(ctx, stage.Name)
 	stepRunners := make([]StepRunner, len(stage.Steps))
 	for i, step := range stage.Steps {
 		r, err := stepRunFactory.NewStepRunner(ctx, step, stepIDOffset+uint64(i))
 		if err != nil {
-			return nil, fmt.Errorf("step %s: %w", step.Name, err)
+			return stageRunner{}, fmt.Errorf("step %s: %w", step.Name, err)
 		}
 		stepRunners[i] = r
 	}
-	return stageRunner{stage, stepRunners}, nil
+	return stageRunner{
+		stage:       stage,
+		stepRunners: stepRunners,
+		maxParallel: stage.MaxParallel,
+	}, nil
+}
+
+// minParallelLimit returns the smallest of two limits, where zero means
+// no limit.
+func minParallelLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
 }
 
 type stageRunner struct {
 	stage       wharfyml.Stage
 	stepRunners []StepRunner
+	maxParallel int
 }
 
 func (r stageRunner) Stage() wharfyml.Stage {
@@ -52,15 +81,25 @@ func (r stageRunner) Stage() wharfyml.Stage {
 
 func (r stageRunner) RunStage(ctx context.Context) StageResult {
 	ctx = contextWithStageName(ctx, r.stage.Name)
+	ctx, span := tracer.Start(ctx, "stage", trace.WithAttributes(
+		attribute.String("wharf.stage", r.stage.Name),
+	))
+	defer span.End()
 	stageRun := stageRun{
 		stepCount: len(r.stepRunners),
 		stage:     &r.stage,
 		start:     time.Now(),
 	}
+	if r.maxParallel > 0 && r.maxParallel < len(r.stepRunners) {
+		stageRun.slots = make(chan struct{}, r.maxParallel)
+		stageRun.maxParallel = r.maxParallel
+	}
 	for _, stepRunner := range r.stepRunners {
 		stageRun.startRunStepGoroutine(ctx, stepRunner)
 	}
-	return stageRun.waitForResult()
+	res := stageRun.waitForResult()
+	span.SetAttributes(attribute.Stringer("wharf.status", res.Status))
+	return res
 }
 
 type stageRun struct {
@@ -68,10 +107,12 @@ type stageRun struct {
 	cancelFuncs []func()
 	stepCount   int
 	stepsDone   int32
+	maxParallel int
+	slots       chan struct{}
 
-	status      workermodel.Status
-	stepResults []StepResult
-	start       time.Time
+	anyStepFailed bool
+	stepResults   []StepResult
+	start         time.Time
 
 	wg sync.WaitGroup
 
@@ -89,9 +130,7 @@ func (r *stageRun) startRunStepGoroutine(ctx context.Context, stepRunner StepRun
 func (r *stageRun) waitForResult() StageResult {
 	r.wg.Wait()
 	status := workermodel.StatusSuccess
-	if r.status == workermodel.StatusCancelled {
-		status = workermodel.StatusFailed
-	} else if r.status != workermodel.StatusSuccess {
+	if r.anyStepFailed {
 		status = workermodel.StatusFailed
 	}
 	return StageResult{
@@ -111,25 +150,70 @@ func (r *stageRun) addStepResult(res StepResult) {
 
 func (r *stageRun) runStep(ctx context.Context, stepRunner StepRunner) {
 	defer r.wg.Done()
+	ctx, span := tracer.Start(ctx, "step", trace.WithAttributes(
+		attribute.String("wharf.stage", r.stage.Name),
+		attribute.String("wharf.step", stepRunner.Step().Name),
+		attribute.String("wharf.step.type", stepTypeName(stepRunner.Step())),
+	))
+	defer span.End()
 	logFunc := func(ev logger.Event) logger.Event {
 		return ev.
 			WithStringf("steps", "%d/%d", atomic.LoadInt32(&r.stepsDone), r.stepCount).
 			WithString("stage", r.stage.Name).
 			WithString("step", stepRunner.Step().Name)
 	}
+	if !r.waitForSlot(ctx, stepRunner, logFunc) {
+		r.addStepResult(StepResult{
+			Name:   stepRunner.Step().Name,
+			Status: workermodel.StatusCancelled,
+			Type:   stepTypeName(stepRunner.Step()),
+			Error:  ctx.Err(),
+		})
+		log.Info().WithFunc(logFunc).Message("Cancelled queued step.")
+		return
+	}
+	defer r.releaseSlot()
+	span.AddEvent("started")
 	log.Info().WithFunc(logFunc).Message("Starting step.")
 	res := stepRunner.RunStep(ctx)
+	if res.Status == workermodel.StatusFailed && stepRunner.Step().AllowFailure {
+		res.Status = workermodel.StatusWarning
+	}
+	span.SetAttributes(attribute.Stringer("wharf.status", res.Status))
+	stepDuration.WithLabelValues(res.Type, res.Status.String()).Observe(res.Duration.Seconds())
+	if res.Termination != nil {
+		span.SetAttributes(attribute.Int("wharf.step.exitCode", int(res.Termination.ExitCode)))
+	}
+	if res.Status == workermodel.StatusFailed {
+		tracing.RecordError(span, res.Error)
+	}
 	r.addStepResult(res)
 	dur := res.Duration.Truncate(time.Second)
-	r.statusMutex.Lock()
-	r.status = res.Status
-	r.statusMutex.Unlock()
+	if res.Status != workermodel.StatusSuccess && res.Status != workermodel.StatusWarning {
+		r.statusMutex.Lock()
+		r.anyStepFailed = true
+		r.statusMutex.Unlock()
+	}
 	if res.Status == workermodel.StatusCancelled {
 		log.Info().
 			WithFunc(logFunc).
 			WithDuration("dur", dur).
 			Message("Cancelled pod.")
+	} else if res.Status == workermodel.StatusWarning {
+		log.Warn().
+			WithError(res.Error).
+			WithFunc(logFunc).
+			WithDuration("dur", dur).
+			Message("Failed step, but it is allowed to fail. Continuing.")
 	} else if res.Status != workermodel.StatusSuccess {
+		if !r.stage.ShouldFailFast() {
+			log.Warn().
+				WithError(res.Error).
+				WithFunc(logFunc).
+				WithDuration("dur", dur).
+				Message("Failed step. Letting other steps in stage finish, as fail-fast is disabled.")
+			return
+		}
 		log.Warn().
 			WithError(res.Error).
 			WithFunc(logFunc).
@@ -145,3 +229,48 @@ func (r *stageRun) runStep(ctx context.Context, stepRunner StepRunner) {
 			Message("Done with step.")
 	}
 }
+
+// waitForSlot blocks until there is room for another step to run, based on
+// the max parallel steps limit. Returns false if the context was cancelled
+// while waiting.
+func (r *stageRun) waitForSlot(ctx context.Context, stepRunner StepRunner, logFunc func(logger.Event) logger.Event) bool {
+	if r.slots == nil {
+		return true
+	}
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	default:
+	}
+	log.Info().
+		WithFunc(logFunc).
+		WithInt("maxParallel", r.maxParallel).
+		Message("Queued step. Waiting for other steps in stage to finish.")
+	reportStepStatus(stepRunner, workermodel.StatusScheduling)
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		reportStepStatus(stepRunner, workermodel.StatusCancelled)
+		return false
+	}
+}
+
+func (r *stageRun) releaseSlot() {
+	if r.slots != nil {
+		<-r.slots
+	}
+}
+
+func reportStepStatus(stepRunner StepRunner, status workermodel.Status) {
+	if reporter, ok := stepRunner.(StepStatusReporter); ok {
+		reporter.ReportStepStatus(status)
+	}
+}
+
+func stepTypeName(step wharfyml.Step) string {
+	if step.Type == nil {
+		return ""
+	}
+	return step.Type.StepTypeName()
+}
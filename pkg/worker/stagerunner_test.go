@@ -2,7 +2,9 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type mockStepRunFactory struct {
@@ -22,7 +25,7 @@ func (f mockStepRunFactory) NewStepRunner(
 	if !ok {
 		return nil, fmt.Errorf("no step runner found for %q", step.Name)
 	}
-	runner.step.Name = step.Name
+	runner.step = step
 	runner.result.Name = step.Name
 	return runner, nil
 }
@@ -106,6 +109,141 @@ func TestStageRunner_runOneFailsOthersCancelled(t *testing.T) {
 	assert.Equal(t, wantStatuses, gotStatuses)
 }
 
+func TestStageRunner_runAllowedFailureNotCancellingOthers(t *testing.T) {
+	factory := mockStepRunFactory{runners: map[string]mockStepRunner{
+		"foo": {result: StepResult{Status: workermodel.StatusSuccess}},
+		"bar": {result: StepResult{Status: workermodel.StatusFailed}},
+		"moo": {result: StepResult{Status: workermodel.StatusSuccess}},
+	}}
+	stage := wharfyml.Stage{
+		Name: "doesnt-matter",
+		Steps: []wharfyml.Step{
+			{Name: "foo"},
+			{Name: "bar", AllowFailure: true},
+			{Name: "moo"},
+		},
+	}
+	b, err := newStageRunner(context.Background(), factory, stage, 1)
+	require.NoError(t, err)
+	result := b.RunStage(context.Background())
+	assert.Equal(t, workermodel.StatusSuccess, result.Status)
+
+	gotStatuses := getStatusesFromStepResults(result.Steps)
+	wantStatuses := map[string]workermodel.Status{
+		"foo": workermodel.StatusSuccess,
+		"bar": workermodel.StatusWarning,
+		"moo": workermodel.StatusSuccess,
+	}
+	assert.Equal(t, wantStatuses, gotStatuses)
+}
+
+func TestStageRunner_runOneFailsWithoutFailFast(t *testing.T) {
+	factory := mockStepRunFactory{runners: map[string]mockStepRunner{
+		"foo": {result: StepResult{Status: workermodel.StatusSuccess}, wait: true},
+		"bar": {result: StepResult{Status: workermodel.StatusFailed}},
+	}}
+	failFast := false
+	stage := wharfyml.Stage{
+		Name:     "doesnt-matter",
+		FailFast: &failFast,
+		Steps: []wharfyml.Step{
+			{Name: "foo"},
+			{Name: "bar"},
+		},
+	}
+	b, err := newStageRunner(context.Background(), factory, stage, 1)
+	require.NoError(t, err)
+	result := b.RunStage(context.Background())
+	assert.Equal(t, workermodel.StatusFailed, result.Status)
+
+	gotStatuses := getStatusesFromStepResults(result.Steps)
+	wantStatuses := map[string]workermodel.Status{
+		// The mock returns StatusUnknown when it was allowed to finish waiting.
+		"foo": workermodel.StatusUnknown,
+		"bar": workermodel.StatusFailed,
+	}
+	assert.Equal(t, wantStatuses, gotStatuses)
+}
+
+type concurrencyStepRunner struct {
+	step    wharfyml.Step
+	running *int32
+	maxSeen *int32
+}
+
+func (r concurrencyStepRunner) Step() wharfyml.Step {
+	return r.step
+}
+
+func (r concurrencyStepRunner) RunStep(context.Context) StepResult {
+	running := atomic.AddInt32(r.running, 1)
+	for {
+		maxSeen := atomic.LoadInt32(r.maxSeen)
+		if running <= maxSeen || atomic.CompareAndSwapInt32(r.maxSeen, maxSeen, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	atomic.AddInt32(r.running, -1)
+	return StepResult{Name: r.step.Name, Status: workermodel.StatusSuccess}
+}
+
+type concurrencyStepRunFactory struct {
+	running int32
+	maxSeen int32
+}
+
+func (f *concurrencyStepRunFactory) NewStepRunner(
+	_ context.Context, step wharfyml.Step, _ uint64) (StepRunner, error) {
+	return concurrencyStepRunner{step, &f.running, &f.maxSeen}, nil
+}
+
+func TestStageRunner_runMaxParallel(t *testing.T) {
+	testCases := []struct {
+		name             string
+		stageMax         int
+		maxParallelSteps int
+		wantMaxSeen      int32
+	}{
+		{
+			name:        "stage limit",
+			stageMax:    2,
+			wantMaxSeen: 2,
+		},
+		{
+			name:             "global limit",
+			maxParallelSteps: 1,
+			wantMaxSeen:      1,
+		},
+		{
+			name:             "lowest limit wins",
+			stageMax:         3,
+			maxParallelSteps: 2,
+			wantMaxSeen:      2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stepFactory := &concurrencyStepRunFactory{}
+			factory, err := NewStageRunnerFactory(stepFactory, tc.maxParallelSteps)
+			require.NoError(t, err)
+			stage := wharfyml.Stage{
+				Name:        "doesnt-matter",
+				MaxParallel: tc.stageMax,
+				Steps: []wharfyml.Step{
+					{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"},
+				},
+			}
+			b, err := factory.NewStageRunner(context.Background(), stage, 1)
+			require.NoError(t, err)
+			result := b.RunStage(context.Background())
+			assert.Equal(t, workermodel.StatusSuccess, result.Status)
+			assert.Len(t, result.Steps, 5)
+			assert.Equal(t, tc.wantMaxSeen, atomic.LoadInt32(&stepFactory.maxSeen))
+		})
+	}
+}
+
 func getNamesFromStepResults(steps []StepResult) []string {
 	var names []string
 	for _, step := range steps {
@@ -121,3 +259,34 @@ func getStatusesFromStepResults(steps []StepResult) map[string]workermodel.Statu
 	}
 	return statuses
 }
+
+func TestStageRunner_tracesStageAndSteps(t *testing.T) {
+	ctx, endTrace := startTestTrace(t)
+	factory := mockStepRunFactory{runners: map[string]mockStepRunner{
+		"foo": {result: StepResult{Status: workermodel.StatusSuccess}},
+		"bar": {result: StepResult{Status: workermodel.StatusFailed, Error: errors.New("bar failed")}},
+	}}
+	stage := wharfyml.Stage{
+		Name:  "my-stage",
+		Steps: []wharfyml.Step{{Name: "foo"}, {Name: "bar"}},
+	}
+	b, err := newStageRunner(ctx, factory, stage, 1)
+	require.NoError(t, err)
+	b.RunStage(ctx)
+
+	spans := endTrace()
+	require.Len(t, spans, 3)
+	stageSpan := spans[len(spans)-1]
+	assert.Equal(t, "stage", stageSpan.Name())
+	stepStatuses := make(map[string]codes.Code)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "step", span.Name())
+		assert.Equal(t, stageSpan.SpanContext().SpanID(), span.Parent().SpanID())
+		for _, attr := range span.Attributes() {
+			if attr.Key == "wharf.step" {
+				stepStatuses[attr.Value.AsString()] = span.Status().Code
+			}
+		}
+	}
+	assert.Equal(t, map[string]codes.Code{"foo": codes.Unset, "bar": codes.Error}, stepStatuses)
+}
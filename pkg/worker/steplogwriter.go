@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+)
+
+// stepLogWriter writes log lines to the result store and to the logger. It can
+// be shared between goroutines, and discards all lines if the result store
+// failed to open a log writer.
+//
+// Log lines from other containers than the step's app container are logged
+// with the container name added to the logger's scope, such as
+// "build/my-step/init" instead of "build/my-step".
+type stepLogWriter struct {
+	mutex        sync.Mutex
+	writer       resultstore.LogLineWriteCloser
+	log          logger.Logger
+	scope        string
+	appContainer string
+	containerLog map[string]logger.Logger
+}
+
+func openStepLogWriter(store resultstore.Store, stepID uint64, scope, appContainer string) *stepLogWriter {
+	w := &stepLogWriter{
+		log:          logger.NewScoped(scope),
+		scope:        scope,
+		appContainer: appContainer,
+		containerLog: make(map[string]logger.Logger),
+	}
+	writer, err := store.OpenLogWriter(stepID)
+	if err != nil {
+		w.log.Error().WithError(err).Message("Failed to open log writer. No logs will be written.")
+		return w
+	}
+	w.writer = writer
+	return w
+}
+
+func (w *stepLogWriter) WriteLogLine(line string) error {
+	return w.WriteContainerLogLine("", resultstore.LogStreamUnknown, line)
+}
+
+// WriteContainerLogLine writes a log line that came from a given container
+// and output stream. The line may be prefixed with a timestamp, otherwise the
+// current time is used.
+func (w *stepLogWriter) WriteContainerLogLine(container string, stream resultstore.LogStream, line string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.writer == nil {
+		return nil
+	}
+	var logLine resultstore.LogLine
+	var err error
+	if container == "" && stream == resultstore.LogStreamUnknown {
+		logLine, err = w.writer.WriteLogLine(line)
+	} else {
+		logLine, err = w.writer.WriteContainerLogLine(container, stream, line)
+	}
+	if err != nil {
+		w.log.Error().WithError(err).Message("Failed to write log line. No further logs will be written.")
+		w.writer.Close()
+		w.writer = nil
+		return err
+	}
+	w.loggerForContainer(container).Info().Message(logLine.Message)
+	return nil
+}
+
+func (w *stepLogWriter) loggerForContainer(container string) logger.Logger {
+	if container == "" || container == w.appContainer {
+		return w.log
+	}
+	log, ok := w.containerLog[container]
+	if !ok {
+		log = logger.NewScoped(w.scope + "/" + container)
+		w.containerLog[container] = log
+	}
+	return log
+}
+
+// WriteAnnotatedLogLine writes a log line that did not come from the step's
+// own output, such as Kubernetes events, prefixed with the given annotation.
+func (w *stepLogWriter) WriteAnnotatedLogLine(t time.Time, annotation, message string) error {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return w.WriteLogLine(fmt.Sprintf("%s [%s] %s",
+		t.UTC().Format(time.RFC3339Nano), annotation, message))
+}
+
+// LineWriter returns a writer that writes every line written to it as a log
+// line from the given container and output stream, timestamped with the time
+// it was written. Close must be called to write any trailing unterminated line.
+func (w *stepLogWriter) LineWriter(container string, stream resultstore.LogStream) *stepLogLineWriter {
+	return &stepLogLineWriter{
+		writer:    w,
+		container: container,
+		stream:    stream,
+	}
+}
+
+func (w *stepLogWriter) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.writer == nil {
+		return
+	}
+	if err := w.writer.Close(); err != nil {
+		w.log.Error().WithError(err).Message("Failed to close log writer.")
+	}
+	w.writer = nil
+}
+
+// stepLogLineWriter is an io.WriteCloser that splits the written data into
+// log lines. Errors from writing log lines are ignored, as they are already
+// logged by the stepLogWriter.
+type stepLogLineWriter struct {
+	writer    *stepLogWriter
+	container string
+	stream    resultstore.LogStream
+	buf       []byte
+}
+
+func (w *stepLogLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		w.writeLine(string(w.buf[:idx]))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *stepLogLineWriter) Close() error {
+	if len(w.buf) > 0 {
+		w.writeLine(string(w.buf))
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *stepLogLineWriter) writeLine(line string) {
+	// Only keep the last carriage-return separated part, as progress bars
+	// rewrite the same line over and over.
+	if idx := strings.LastIndexByte(line, '\r'); idx != -1 {
+		line = line[idx+1:]
+	}
+	timestamp := time.Now().UTC().Format(time.RFC3339Nano)
+	w.writer.WriteContainerLogLine(w.container, w.stream, timestamp+" "+line)
+}
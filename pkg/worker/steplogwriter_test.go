@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepLogLineWriter(t *testing.T) {
+	store := resultstore.NewStore(resultstore.NewFS(t.TempDir()))
+	writer := openStepLogWriter(store, 1, "test", "step")
+	stdout := writer.LineWriter("step", resultstore.LogStreamStdout)
+	stderr := writer.LineWriter("init", resultstore.LogStreamStderr)
+
+	io.WriteString(stdout, "hello\nwor")
+	io.WriteString(stderr, "tar: no space left on device\n")
+	io.WriteString(stdout, "ld\n10%\r50%\r100%")
+	stdout.Close()
+	stderr.Close()
+	writer.Close()
+
+	reader, err := store.OpenLogReader(1)
+	require.NoError(t, err)
+	defer reader.Close()
+	var got []resultstore.LogLine
+	for {
+		line, err := reader.ReadLogLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		assert.False(t, line.Timestamp.IsZero(), "timestamp")
+		got = append(got, resultstore.LogLine{
+			Message:   line.Message,
+			Container: line.Container,
+			Stream:    line.Stream,
+		})
+	}
+	want := []resultstore.LogLine{
+		{Message: "hello", Container: "step", Stream: resultstore.LogStreamStdout},
+		{Message: "tar: no space left on device", Container: "init", Stream: resultstore.LogStreamStderr},
+		{Message: "world", Container: "step", Stream: resultstore.LogStreamStdout},
+		{Message: "100%", Container: "step", Stream: resultstore.LogStreamStdout},
+	}
+	assert.Equal(t, want, got)
+}
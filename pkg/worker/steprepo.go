@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iver-wharf/wharf-cmd/internal/filecopy"
+	"github.com/iver-wharf/wharf-cmd/internal/gitutil"
+	"github.com/iver-wharf/wharf-cmd/internal/ignorer"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/varsub"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+)
+
+// StepRepoOptions is a struct of options for PrepareStepRepo.
+type StepRepoOptions struct {
+	TarStore      tarstore.Store
+	VarSource     varsub.Source
+	SkipGitIgnore bool
+	CurrentDir    string
+}
+
+// PrepareStepRepo creates the tarball of the repository that is transferred to
+// a step, with the same filters applied as when running the step. Pass a zero
+// value step to get the tarball that is shared by steps without any filters.
+func PrepareStepRepo(ctx context.Context, step wharfyml.Step, stepID uint64, opts StepRepoOptions) (tarstore.Tarball, error) {
+	return stepRepoPreparer{
+		tarStore:      opts.TarStore,
+		varSource:     opts.VarSource,
+		skipGitIgnore: opts.SkipGitIgnore,
+		currentDir:    opts.CurrentDir,
+	}.prepareStepRepo(ctx, step, stepID)
+}
+
+// stepRepoPreparer prepares tarballs of the repository to be transferred to
+// the step runners, with .gitignore, .wharfignore, and step file filters
+// applied.
+type stepRepoPreparer struct {
+	tarStore      tarstore.Store
+	varSource     varsub.Source
+	skipGitIgnore bool
+	currentDir    string
+}
+
+func (p stepRepoPreparer) prepareStepRepo(ctx context.Context, step wharfyml.Step, stepID uint64) (tarstore.Tarball, error) {
+	onlyFiles, hasFileFilter := getOnlyFilesToTransfer(step)
+	copier := p.getStepRepoCopier(hasFileFilter)
+	ignorer, err := p.getStepRepoIgnorer(onlyFiles, hasFileFilter, step.Ignore)
+	if err != nil {
+		return "", err
+	}
+	tarID := p.getStepTarID(stepID, hasFileFilter || len(step.Ignore) > 0)
+
+	tarball, err := p.tarStore.GetPreparedTarball(ctx, copier, ignorer, tarID)
+	if err != nil {
+		return "", err
+	}
+	return tarball, nil
+}
+
+func (p stepRepoPreparer) getStepTarID(stepID uint64, isStepSpecific bool) string {
+	if isStepSpecific {
+		return fmt.Sprintf("step-%d", stepID)
+	}
+	return "full"
+}
+
+func (p stepRepoPreparer) getStepRepoCopier(hasFileFilter bool) filecopy.Copier {
+	if hasFileFilter {
+		return varsub.NewCopier(p.varSource)
+	}
+	return filecopy.IOCopier
+}
+
+func (p stepRepoPreparer) getStepRepoIgnorer(onlyFiles []string, hasFileFilter bool, stepIgnore []string) (ignorer.Ignorer, error) {
+	var igns []ignorer.Ignorer
+	if hasFileFilter {
+		igns = append(igns, ignorer.NewFileIncluder(onlyFiles))
+	}
+
+	wharfIgn, err := ignorer.NewWharfIgnorer(p.currentDir)
+	if err != nil {
+		return nil, err
+	}
+	igns = append(igns, wharfIgn)
+
+	if len(stepIgnore) > 0 {
+		stepIgn, err := ignorer.NewPatternIgnorer(p.currentDir, stepIgnore)
+		if err != nil {
+			return nil, err
+		}
+		igns = append(igns, stepIgn)
+	}
+
+	if !p.skipGitIgnore {
+		repoRoot, err := gitutil.GitRepoRoot(p.currentDir)
+		if err != nil {
+			return nil, err
+		}
+		gitIgn, err := gitutil.NewIgnorer(p.currentDir, repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		igns = append(igns, gitIgn)
+	}
+
+	return ignorer.Merge(igns...), nil
+}
+
+func setStepMeta(ctx context.Context, store resultstore.Store, step wharfyml.Step, stepID uint64) error {
+	stageName, _ := contextStageName(ctx)
+	if err := store.SetStepMeta(stepID, resultstore.StepMeta{
+		StageName: stageName,
+		StepName:  step.Name,
+		StepType:  step.Type.StepTypeName(),
+	}); err != nil {
+		return fmt.Errorf("write step metadata: %w", err)
+	}
+	return nil
+}
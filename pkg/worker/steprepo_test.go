@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/tarstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareStepRepo_ignores(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string][]byte{
+		".wharfignore":     []byte("*.log\n"),
+		"sub/.wharfignore": []byte("secret.txt\n"),
+		"main.go":          nil,
+		"app.log":          nil,
+		"docs/readme.md":   nil,
+		"sub/secret.txt":   nil,
+		"sub/public.txt":   nil,
+	})
+	store, err := tarstore.New(dir)
+	require.NoError(t, err)
+	defer store.Close()
+	opts := StepRepoOptions{TarStore: store, SkipGitIgnore: true, CurrentDir: dir}
+
+	tarball, err := PrepareStepRepo(context.Background(), wharfyml.Step{}, 1, opts)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		".wharfignore", "sub/.wharfignore", "main.go", "docs/readme.md", "sub/public.txt",
+	}, readTestTarballFileNames(t, tarball))
+
+	step := wharfyml.Step{Name: "compile", Ignore: []string{"docs/"}}
+	tarball, err = PrepareStepRepo(context.Background(), step, 2, opts)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		".wharfignore", "sub/.wharfignore", "main.go", "sub/public.txt",
+	}, readTestTarballFileNames(t, tarball))
+}
+
+func readTestTarballFileNames(t *testing.T, tarball tarstore.Tarball) []string {
+	file, err := tarball.Open()
+	require.NoError(t, err)
+	defer file.Close()
+	var names []string
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeReg {
+			names = append(names, header.Name)
+		}
+	}
+}
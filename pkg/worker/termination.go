@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
+)
+
+// Termination reasons, using the same values as Kubernetes.
+const (
+	terminationReasonCompleted = "Completed"
+	terminationReasonError     = "Error"
+	terminationReasonOOMKilled = "OOMKilled"
+)
+
+// newExitTermination returns a termination with a reason based on the exit
+// code, for step runners where no other reason is given.
+func newExitTermination(exitCode int32, startedAt, finishedAt time.Time) *workermodel.Termination {
+	reason := terminationReasonCompleted
+	if exitCode != 0 {
+		reason = terminationReasonError
+	}
+	return &workermodel.Termination{
+		ExitCode:   exitCode,
+		Reason:     reason,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+	}
+}
+
+// addFinalStatusUpdate adds the last status update of a step, including how
+// the step terminated, if known.
+func addFinalStatusUpdate(store resultstore.Store, stepID uint64, status workermodel.Status, termination *workermodel.Termination) error {
+	if termination == nil {
+		return store.AddStatusUpdate(stepID, time.Now(), status)
+	}
+	return store.AddTerminatedStatusUpdate(stepID, time.Now(), status, *termination)
+}
@@ -7,14 +7,32 @@ import (
 	"github.com/iver-wharf/wharf-cmd/pkg/wharfyml"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workermodel"
 	"github.com/iver-wharf/wharf-core/v2/pkg/logger"
+	"go.opentelemetry.io/otel"
 )
 
-var log = logger.New()
+var (
+	log    = logger.New()
+	tracer = otel.Tracer("github.com/iver-wharf/wharf-cmd/pkg/worker")
+)
 
 // BuildOptions defines filtering options to control what parts of a build should
 // actually be executed.
 type BuildOptions struct {
 	StageFilter string
+	// StepFilter is a list of glob patterns, as supported by path.Match, of
+	// which steps to run. Patterns are matched against the step name as well
+	// as the "stage/step" name. All steps are run if this is empty.
+	StepFilter []string
+	// SkipStepFilter is a list of glob patterns, in the same format as
+	// StepFilter, of which steps to not run.
+	SkipStepFilter []string
+	// RerunSucceededSteps is a set of "stage/step" names of the steps that
+	// succeeded in a previous build that this build is re-running. When set,
+	// stages are skipped up until the first stage that has any steps not in
+	// this set, of which only the steps not in this set are run. All steps are
+	// run in the stages after that. It is an error if all steps are in this
+	// set. See ReadRerunSucceededSteps.
+	RerunSucceededSteps map[string]bool
 }
 
 // Builder is the interface for running a Wharf build. A single Wharf build may
@@ -46,6 +64,13 @@ type StepRunner interface {
 	RunStep(ctx context.Context) StepResult
 }
 
+// StepStatusReporter is an optional interface that a StepRunner may implement
+// to be notified of status changes that happen outside of RunStep, such as
+// when the step is queued while waiting for other steps in the stage to finish.
+type StepStatusReporter interface {
+	ReportStepStatus(status workermodel.Status)
+}
+
 // StepRunnerFactory creates a new StepRunner for a given step.
 type StepRunnerFactory interface {
 	NewStepRunner(ctx context.Context, step wharfyml.Step, stepID uint64) (StepRunner, error)
@@ -74,9 +99,19 @@ type StageResult struct {
 // StepResult is a Wharf build step result with the status of the step execution
 // as well as the duration of the Wharf build step.
 type StepResult struct {
-	Name     string             // name of the step
-	Status   workermodel.Status // execution status of the step
-	Type     string             // type of Wharf build step, eg. "container" or "docker"
-	Error    error              // error message from the execution, if any
-	Duration time.Duration      // execution duration of the step
+	Name        string                   // name of the step
+	Status      workermodel.Status       // execution status of the step
+	Type        string                   // type of Wharf build step, eg. "container" or "docker"
+	Error       error                    // error message from the execution, if any
+	Duration    time.Duration            // execution duration of the step
+	Termination *workermodel.Termination // how the step's container or process terminated, if it ran
+}
+
+// failedStepStatus returns the status a step runner should report when the
+// step fails, which depends on if the step is allowed to fail.
+func failedStepStatus(step wharfyml.Step) workermodel.Status {
+	if step.AllowFailure {
+		return workermodel.StatusWarning
+	}
+	return workermodel.StatusFailed
 }
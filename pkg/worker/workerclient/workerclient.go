@@ -4,6 +4,7 @@ package workerclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -110,6 +111,27 @@ func (c *Client) BuildID() uint {
 	return c.buildID
 }
 
+// Build is the build that a worker server is serving.
+type Build struct {
+	BuildID         uint   `json:"buildId"`
+	Project         string `json:"project"`
+	ResultStorePath string `json:"resultStorePath"`
+}
+
+// GetBuild returns the build that the worker server is serving.
+func (c *Client) GetBuild(ctx context.Context) (Build, error) {
+	res, err := c.rest.get(ctx, fmt.Sprintf("%s/api/build", c.baseURL))
+	if err := assertResponseOK(res, err); err != nil {
+		return Build{}, err
+	}
+	defer res.Body.Close()
+	var build Build
+	if err := json.NewDecoder(res.Body).Decode(&build); err != nil {
+		return Build{}, fmt.Errorf("decode build response: %w", err)
+	}
+	return build, nil
+}
+
 // Ping pongs.
 func (c *Client) Ping(ctx context.Context) error {
 	res, err := c.rest.get(ctx, c.baseURL)
@@ -1,9 +1,13 @@
 package workerclient
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -11,3 +15,22 @@ func TestNew(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 }
+
+func TestClient_GetBuild(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/build", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"buildId":12,"project":"iver-wharf/wharf-cmd","resultStorePath":"/tmp/build-00012-123"}`))
+	}))
+	defer server.Close()
+	c, err := New(server.URL, Options{InsecureSkipVerify: true})
+	require.NoError(t, err)
+
+	build, err := c.GetBuild(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Build{
+		BuildID:         12,
+		Project:         "iver-wharf/wharf-cmd",
+		ResultStorePath: "/tmp/build-00012-123",
+	}, build)
+}
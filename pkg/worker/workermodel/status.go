@@ -28,6 +28,9 @@ const (
 	StatusFailed
 	// StatusCancelled means the build, stage, or step was cancelled.
 	StatusCancelled
+	// StatusWarning means the step failed, but was allowed to fail. It does
+	// not fail the stage or build.
+	StatusWarning
 )
 
 // String implements the fmt.Stringer interface.
@@ -47,6 +50,8 @@ func (s Status) String() string {
 		return "Failed"
 	case StatusCancelled:
 		return "Cancelled"
+	case StatusWarning:
+		return "Warning"
 	default:
 		return "Unknown"
 	}
@@ -71,6 +76,8 @@ func ParseStatus(s string) Status {
 		return StatusFailed
 	case "cancelled":
 		return StatusCancelled
+	case "warning":
+		return StatusWarning
 	default:
 		return StatusUnknown
 	}
@@ -0,0 +1,19 @@
+package workermodel
+
+import "time"
+
+// Termination holds details about how a step's process or container
+// terminated.
+type Termination struct {
+	// ExitCode is the exit code of the step's process or container.
+	ExitCode int32 `json:"exitCode"`
+	// Reason is a short machine-readable reason of the termination, such as
+	// "Completed", "Error", "OOMKilled", or "DeadlineExceeded".
+	Reason string `json:"reason,omitempty"`
+	// Message is a human-readable message about the termination, if any.
+	Message string `json:"message,omitempty"`
+	// StartedAt is when the step's process or container started.
+	StartedAt time.Time `json:"startedAt"`
+	// FinishedAt is when the step's process or container terminated.
+	FinishedAt time.Time `json:"finishedAt"`
+}
@@ -0,0 +1,48 @@
+package workerserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
+	"github.com/iver-wharf/wharf-core/v2/pkg/ginutil"
+)
+
+type buildModule struct {
+	store resultstore.Store
+}
+
+// Build is the response from a GET /api/build request.
+type Build struct {
+	// BuildID is the ID of the build that is served.
+	BuildID uint `json:"buildId" example:"123"`
+	// Project is the human readable name of the project of the build.
+	Project string `json:"project,omitempty" example:"iver-wharf/wharf-cmd"`
+	// ResultStorePath is the path to the result store directory of the
+	// build, which uniquely identifies the build on the worker's machine.
+	ResultStorePath string `json:"resultStorePath" example:"/tmp/wharf-cmd-builds/wharf-cmd-1a2b3c4d/build-00123-123456"`
+}
+
+func (m buildModule) register(g *gin.RouterGroup) {
+	g.GET("/build", m.getBuildHandler)
+}
+
+// getBuildHandler godoc
+// @id getBuild
+// @summary Get the build that is served.
+// @description Added in v0.10.0.
+// @tags worker
+// @produce json
+// @success 200 {object} Build "OK"
+// @failure 502 {object} problem.Response "Cannot read build metadata"
+// @router /api/build [get]
+func (m buildModule) getBuildHandler(c *gin.Context) {
+	meta, err := m.store.ReadBuildMeta()
+	if err != nil {
+		ginutil.WriteAPIClientReadError(c, err, "Unable to read build metadata.")
+		return
+	}
+	c.JSON(200, Build{
+		BuildID:         meta.BuildID,
+		Project:         meta.Project,
+		ResultStorePath: m.store.Path(),
+	})
+}
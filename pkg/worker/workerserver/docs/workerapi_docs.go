@@ -93,6 +93,33 @@ const docTemplateworkerapi = `{
                     }
                 }
             }
+        },
+        "/api/build": {
+            "get": {
+                "description": "Added in v0.10.0.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "worker"
+                ],
+                "summary": "Get the build that is served.",
+                "operationId": "getBuild",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/workerserver.Build"
+                        }
+                    },
+                    "502": {
+                        "description": "Cannot read build metadata",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Response"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -136,6 +163,26 @@ const docTemplateworkerapi = `{
                 }
             }
         },
+        "workerserver.Build": {
+            "type": "object",
+            "properties": {
+                "buildId": {
+                    "description": "BuildID is the ID of the build that is served.",
+                    "type": "integer",
+                    "example": 123
+                },
+                "project": {
+                    "description": "Project is the human readable name of the project of the build.",
+                    "type": "string",
+                    "example": "iver-wharf/wharf-cmd"
+                },
+                "resultStorePath": {
+                    "description": "ResultStorePath is the path to the result store directory of the\nbuild, which uniquely identifies the build on the worker's machine.",
+                    "type": "string",
+                    "example": "/tmp/wharf-cmd-builds/wharf-cmd-1a2b3c4d/build-00123-123456"
+                }
+            }
+        },
         "workerserver.Ping": {
             "type": "object",
             "properties": {
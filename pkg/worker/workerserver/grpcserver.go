@@ -2,6 +2,7 @@ package workerserver
 
 import (
 	"net"
+	"time"
 
 	v1 "github.com/iver-wharf/wharf-cmd/api/workerapi/v1"
 	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
@@ -106,16 +107,50 @@ func ConvertToStreamLogsResponse(line resultstore.LogLine) *v1.StreamLogsRespons
 		StepID:    line.StepID,
 		Timestamp: timestamppb.New(line.Timestamp),
 		Message:   line.Message,
+		Container: line.Container,
+		Stream:    convertToLogStream(line.Stream),
+	}
+}
+
+func convertToLogStream(stream resultstore.LogStream) v1.LogStream {
+	switch stream {
+	case resultstore.LogStreamStdout:
+		return v1.LogStreamStdout
+	case resultstore.LogStreamStderr:
+		return v1.LogStreamStderr
+	default:
+		return v1.LogStreamUnspecified
 	}
 }
 
 // ConvertToStreamStatusEventsResponse converts a resultstore status update to the equivalent response type.
 func ConvertToStreamStatusEventsResponse(update resultstore.StatusUpdate) *v1.StreamStatusEventsResponse {
 	return &v1.StreamStatusEventsResponse{
-		EventID: update.UpdateID,
-		StepID:  update.StepID,
-		Status:  convertToStreamStatusEventsResponseStatus(update.Status),
+		EventID:     update.UpdateID,
+		StepID:      update.StepID,
+		Status:      convertToStreamStatusEventsResponseStatus(update.Status),
+		Termination: convertToTermination(update.Termination),
+	}
+}
+
+func convertToTermination(termination *workermodel.Termination) *v1.Termination {
+	if termination == nil {
+		return nil
+	}
+	return &v1.Termination{
+		ExitCode:   termination.ExitCode,
+		Reason:     termination.Reason,
+		Message:    termination.Message,
+		StartedAt:  convertToTimestamp(termination.StartedAt),
+		FinishedAt: convertToTimestamp(termination.FinishedAt),
+	}
+}
+
+func convertToTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
 	}
+	return timestamppb.New(t)
 }
 
 func convertToStreamStatusEventsResponseStatus(status workermodel.Status) v1.Status {
@@ -134,6 +169,8 @@ func convertToStreamStatusEventsResponseStatus(status workermodel.Status) v1.Sta
 		return v1.StatusFailed
 	case workermodel.StatusCancelled:
 		return v1.StatusCancelled
+	case workermodel.StatusWarning:
+		return v1.StatusWarning
 	default:
 		return v1.StatusUnspecified
 	}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/iver-wharf/wharf-cmd/pkg/metrics"
+	"github.com/iver-wharf/wharf-cmd/pkg/resultstore"
 	"github.com/iver-wharf/wharf-cmd/pkg/worker/workerserver/docs"
 	"github.com/iver-wharf/wharf-core/v2/pkg/ginutil"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -12,11 +14,13 @@ import (
 )
 
 type restServer struct {
+	store          resultstore.Store
 	artifactOpener ArtifactFileOpener
 }
 
-func newRestServer(artifactOpener ArtifactFileOpener) *restServer {
+func newRestServer(store resultstore.Store, artifactOpener ArtifactFileOpener) *restServer {
 	return &restServer{
+		store:          store,
 		artifactOpener: artifactOpener,
 	}
 }
@@ -38,12 +42,14 @@ func serveHTTP(s *restServer, listener net.Listener) error {
 	applyCORSConfig(r)
 
 	r.GET("", pingHandler)
+	metrics.Register(r)
 
 	api := r.Group("/api")
 	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, func(c *ginSwagger.Config) {
 		c.InstanceName = docs.SwaggerInfoworkerapi.InstanceName()
 	}))
 	artifactModule{s.artifactOpener}.register(api)
+	buildModule{s.store}.register(api)
 	return r.RunListener(listener)
 }
 
@@ -34,7 +34,7 @@ type server struct {
 // New creates a new server that can handle both HTTP and gRPC requests.
 func New(store resultstore.Store, artifactOpener ArtifactFileOpener) Server {
 	return &server{
-		rest: newRestServer(artifactOpener),
+		rest: newRestServer(store, artifactOpener),
 		grpc: newGRPCServer(store),
 	}
 }